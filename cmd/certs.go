@@ -0,0 +1,263 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+)
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Inspect and renew the TLS certificates traefik serves",
+	Long: `Inspect and renew the TLS certificates traefik serves for the context's sites.
+
+The hostnames come from the https URLs in the context's uriMap, or the drupal service's
+DRUPAL_DRUSH_URI, unless they are passed as arguments.
+
+Examples:
+  islectl certs status --context prod
+  islectl certs renew --context prod
+  islectl certs renew --cert fullchain.pem --key privkey.pem --context prod`,
+}
+
+var certsStatusCmd = &cobra.Command{
+	Use:   "status [HOSTNAME...]",
+	Short: "Show the certificates served for the context's hostnames and when they expire",
+	Long: fmt.Sprintf(`Connect to each hostname on port 443 and report the certificate served for it, warning when it
+isn't trusted or expires within %d days.
+
+Hostnames that can't be reached from this machine, e.g. because of a firewall or split DNS, are
+checked by connecting to traefik through the context's SSH connection instead. Pass --tunnel to
+always do that.`, int(isle.CertExpiryWarning.Hours()/24)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		viaTunnel, err := f.GetBool("tunnel")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		tunnel, err := isle.NewTunnel(c)
+		if err != nil {
+			if viaTunnel {
+				return err
+			}
+			slog.Warn("Unable to connect to docker, only checking hostnames reachable from here", "err", err)
+		} else {
+			defer tunnel.Close()
+		}
+
+		ctx := context.Background()
+		hosts := args
+		if len(hosts) == 0 {
+			var cli *isle.DockerClient
+			if tunnel != nil {
+				cli = tunnel.Client()
+			}
+			hosts = isle.SiteHostnames(ctx, c, cli)
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("no https site URLs found for %s, pass the hostnames to check", c.Name)
+		}
+
+		now := time.Now()
+		rows := [][]string{}
+		failed := 0
+		for _, host := range hosts {
+			cert, tunneled, err := isle.FetchSiteCertificate(ctx, tunnel, host, viaTunnel)
+			via := "direct"
+			if tunneled {
+				via = "tunnel"
+			}
+			if err != nil {
+				rows = append(rows, []string{host, "", "", string(isle.CheckFail), err.Error(), via})
+				failed++
+				continue
+			}
+			check := isle.CertificateCheck(cert, now)
+			if check.Status == isle.CheckFail {
+				failed++
+			}
+			rows = append(rows, []string{host, cert.Issuer, cert.NotAfter.Format(time.DateOnly), string(check.Status), check.Detail, via})
+		}
+		if err := utils.WriteRows(os.Stdout, format, []string{"HOST", "ISSUER", "EXPIRES", "STATUS", "DETAIL", "VIA"}, rows); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d certificates on %s are expired or unreachable", failed, c.Name)
+		}
+		return nil
+	},
+}
+
+var certsRenewCmd = &cobra.Command{
+	Use:   "renew [HOSTNAME...]",
+	Short: "Renew ACME certificates or install a custom certificate",
+	Long: `Without --cert and --key, force traefik to request new ACME certificates for the hostnames by
+removing them from its acme.json and restarting it. Traefik renews ACME certificates by itself
+30 days before they expire, so this is for when that failed or the certificate must be replaced.
+
+With --cert and --key, check the certificate chain and key match, haven't expired and cover the
+hostnames, then copy them into the project's certs/ directory (over SFTP for remote contexts)
+as the files of the CERT_PUBLIC_KEY and CERT_PRIVATE_KEY secrets, and restart traefik.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		certFile, err := f.GetString("cert")
+		if err != nil {
+			return err
+		}
+		keyFile, err := f.GetString("key")
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+		if (certFile == "") != (keyFile == "") {
+			return fmt.Errorf("pass both --cert and --key to install a custom certificate")
+		}
+
+		return withTraefik(c, func(ctx context.Context, tunnel *isle.Tunnel, container string) error {
+			hosts := args
+			if len(hosts) == 0 {
+				hosts = isle.SiteHostnames(ctx, c, tunnel.Client())
+			}
+
+			if certFile != "" {
+				return installCertificate(c, container, certFile, keyFile, hosts, yes)
+			}
+			if len(hosts) == 0 {
+				return fmt.Errorf("no https site URLs found for %s, pass the hostnames to renew", c.Name)
+			}
+			return renewACMECertificates(ctx, c, tunnel, container, hosts, yes)
+		})
+	},
+}
+
+func installCertificate(c *config.Context, container, certFile, keyFile string, hosts []string, yes bool) error {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	leaf, err := isle.ValidateCertificate(certPEM, keyPEM, hosts, time.Now())
+	if err != nil {
+		return err
+	}
+	files, err := isle.ProjectCertFiles(c)
+	if err != nil {
+		return err
+	}
+
+	if !yes {
+		question := fmt.Sprintf("Install the certificate for %s (expires %s) as %s and %s on %s, and restart traefik?",
+			strings.Join(leaf.DNSNames, ", "), leaf.NotAfter.Format(time.DateOnly),
+			path.Join(c.ProjectDir, files.Cert), path.Join(c.ProjectDir, files.Key), c.Name)
+		if ok, err := config.Confirm(question); err != nil || !ok {
+			return err
+		}
+	}
+
+	if err := isle.InstallCertificate(c, files, certPEM, keyPEM); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s and %s\n", files.Cert, files.Key)
+	return restartTraefik(c, container)
+}
+
+func renewACMECertificates(ctx context.Context, c *config.Context, tunnel *isle.Tunnel, container string, hosts []string, yes bool) error {
+	inspect, err := tunnel.Client().CLI.ContainerInspect(ctx, container)
+	if err != nil {
+		return fmt.Errorf("unable to inspect %s: %v", container, err)
+	}
+	storage, ok := isle.ACMEStorage(inspect.Args)
+	if !ok {
+		return fmt.Errorf("traefik has no ACME certificate resolver on %s, install a custom certificate with --cert and --key", c.Name)
+	}
+
+	command := exec.Command("docker", "exec", container, "cat", storage)
+	command.Dir = c.ProjectDir
+	data, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", storage, err)
+	}
+	updated, removed, err := isle.RemoveACMECertificates([]byte(data), hosts)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("%s has no certificates for %s", storage, strings.Join(hosts, ", "))
+	}
+
+	if !yes {
+		question := fmt.Sprintf("Remove the ACME certificates for %s on %s and restart traefik to request new ones?", strings.Join(removed, ", "), c.Name)
+		if ok, err := config.Confirm(question); err != nil || !ok {
+			return err
+		}
+	}
+
+	// overwrite in place so acme.json keeps the permissions traefik insists on
+	command = exec.Command("docker", "exec", "-i", container, "sh", "-c", "cat > "+shellquote.Join(storage))
+	command.Dir = c.ProjectDir
+	if _, err := c.CaptureCommand(command, bytes.NewReader(updated)); err != nil {
+		return fmt.Errorf("unable to write %s: %v", storage, err)
+	}
+	fmt.Printf("Removed the certificates for %s from %s\n", strings.Join(removed, ", "), storage)
+	if err := restartTraefik(c, container); err != nil {
+		return err
+	}
+	fmt.Printf("Check the new certificates in a minute with: islectl certs status --context %s\n", c.Name)
+	return nil
+}
+
+func restartTraefik(c *config.Context, container string) error {
+	command := exec.Command("docker", "restart", container)
+	command.Dir = c.ProjectDir
+	if _, err := c.CaptureCommand(command, nil); err != nil {
+		return fmt.Errorf("unable to restart traefik: %v", err)
+	}
+	fmt.Println("Restarted traefik")
+	return nil
+}
+
+func init() {
+	certsStatusCmd.Flags().Bool("tunnel", false, "Always connect to traefik through the context's SSH connection")
+	certsStatusCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	certsRenewCmd.Flags().String("cert", "", "Custom certificate chain (PEM) to install")
+	certsRenewCmd.Flags().String("key", "", "Private key (PEM) of the custom certificate")
+	certsRenewCmd.Flags().BoolP("yes", "y", false, "Renew without asking for confirmation")
+
+	certsCmd.AddCommand(certsStatusCmd)
+	certsCmd.AddCommand(certsRenewCmd)
+	rootCmd.AddCommand(certsCmd)
+}
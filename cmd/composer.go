@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var composerCmd = &cobra.Command{
+	Use:                "composer [COMMAND]",
+	DisableFlagParsing: true,
+	Args:               cobra.ArbitraryArgs,
+	Short:              "Run composer commands on ISLE contexts",
+	Long: `Run composer commands on ISLE contexts.
+
+This is a shorthand for "islectl compose exec drupal composer" ran in the Drupal project directory.
+
+Examples:
+  islectl composer require drupal/devel          # Add a module
+  islectl composer update drupal/core-* -W       # Update Drupal core
+  islectl composer outdated --direct             # List outdated dependencies
+  islectl composer --context prod audit          # Check prod for security advisories`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// since we're disabling flag parsing to make easy passing of flags to composer
+		// handle the context flag
+		filteredArgs, isleContext, err := utils.GetContextFromArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		context, err := config.GetContext(isleContext)
+		if err != nil {
+			return err
+		}
+
+		cmdArgs := []string{
+			"compose",
+			"exec",
+			"-w",
+			isle.DrupalRoot,
+			fmt.Sprintf("drupal-%s", context.Profile),
+			"composer",
+		}
+		cmdArgs = append(cmdArgs, filteredArgs...)
+		c := exec.Command("docker", cmdArgs...)
+		c.Dir = context.ProjectDir
+		_, err = context.RunCommand(c)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(composerCmd)
+}
@@ -114,6 +114,18 @@ var setContextCmd = &cobra.Command{
 		}
 		cc.Name = args[0]
 
+		uris, err := f.GetStringToString("uri")
+		if err != nil {
+			return err
+		}
+		removeUris, err := f.GetStringSlice("remove-uri")
+		if err != nil {
+			return err
+		}
+		if err := cc.UpdateUriMap(uris, removeUris); err != nil {
+			return err
+		}
+
 		defaultContext, err := f.GetBool("default")
 		if err != nil {
 			return err
@@ -211,6 +223,8 @@ func init() {
 	flags := setContextCmd.Flags()
 	config.SetCommandFlags(flags)
 	flags.Bool("default", false, "set to default context")
+	flags.StringToString("uri", map[string]string{}, "base URL drush uses for a Drupal site, as SITE=URL. Can be repeated")
+	flags.StringSlice("remove-uri", []string{}, "Drupal sites to remove from the context's URIs")
 
 	configCmd.AddCommand(viewConfigCmd)
 	configCmd.AddCommand(currentContextCmd)
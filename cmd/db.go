@@ -0,0 +1,345 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Work with your ISLE database",
+}
+
+// dbClients builds the command to launch each supported database client
+// against a local port-forward to mariadb. The password is never passed as an
+// argument, where ps would show it, so the GUI clients prompt for it.
+var dbClients = map[string]func(creds isle.DBCredentials, contextName string) *exec.Cmd{
+	"mysql": func(creds isle.DBCredentials, contextName string) *exec.Cmd {
+		return mysqlClient("mysql", creds)
+	},
+	"mariadb": func(creds isle.DBCredentials, contextName string) *exec.Cmd {
+		return mysqlClient("mariadb", creds)
+	},
+	"dbeaver": func(creds isle.DBCredentials, contextName string) *exec.Cmd {
+		con := strings.Join([]string{
+			"driver=mariadb",
+			"name=islectl-" + contextName,
+			"host=" + creds.Host,
+			"port=" + creds.Port,
+			"database=" + creds.Database,
+			"user=" + creds.User,
+			"savePassword=false",
+			"connect=true",
+		}, "|")
+		if runtime.GOOS == "darwin" {
+			return exec.Command("open", "-a", "DBeaver", "--args", "-con", con)
+		}
+		return exec.Command("dbeaver", "-con", con)
+	},
+	"tableplus": func(creds isle.DBCredentials, contextName string) *exec.Cmd {
+		creds.Password = ""
+		uri, _ := creds.DSN("uri")
+		if runtime.GOOS == "darwin" {
+			return exec.Command("open", "-a", "TablePlus", uri)
+		}
+		return exec.Command("tableplus", uri)
+	},
+	"workbench": func(creds isle.DBCredentials, contextName string) *exec.Cmd {
+		target := fmt.Sprintf("%s@%s:%s", creds.User, creds.Host, creds.Port)
+		if runtime.GOOS == "darwin" {
+			return exec.Command("open", "-a", "MySQLWorkbench", "--args", "--query", target)
+		}
+		return exec.Command("mysql-workbench", "--query", target)
+	},
+}
+
+// mysqlClient passes the password in MYSQL_PWD rather than on the command line, where ps would show it.
+func mysqlClient(name string, creds isle.DBCredentials) *exec.Cmd {
+	cmd := exec.Command(name, "-h", creds.Host, "-P", creds.Port, "-u", creds.User, creds.Database)
+	cmd.Env = append(os.Environ(), "MYSQL_PWD="+creds.Password)
+	return cmd
+}
+
+func dbClientNames() []string {
+	names := make([]string, 0, len(dbClients))
+	for name := range dbClients {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+var dbConnectCmd = &cobra.Command{
+	Use:   "connect",
+	Short: "Connect a database client to your ISLE database",
+	Long: `Connect a database client to your ISLE database.
+
+The database credentials are discovered from the mariadb service's secrets and a local port-forward
+to mariadb is opened (through SSH for remote contexts). The chosen client is then launched against it.
+
+Supported clients: ` + strings.Join(dbClientNames(), ", ") + `
+
+Instead of launching a client, --print outputs a connection string for the port-forward
+in one of these formats: ` + strings.Join(isle.DSNFormats, ", ") + `
+
+Examples:
+  islectl db connect                          # open a mysql shell
+  islectl db connect --client dbeaver         # open DBeaver
+  islectl db connect --client tableplus --context prod
+  islectl db connect --print jdbc --port 3307 # print a JDBC URL and keep the forward open`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		client, err := f.GetString("client")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("print")
+		if err != nil {
+			return err
+		}
+		port, err := f.GetInt("port")
+		if err != nil {
+			return err
+		}
+		launch, ok := dbClients[client]
+		if !ok && format == "" {
+			return fmt.Errorf("unknown database client %q. Valid clients are %s", client, strings.Join(dbClientNames(), ", "))
+		}
+
+		tunnel, err := isle.NewTunnel(c)
+		if err != nil {
+			return err
+		}
+		defer tunnel.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		creds, err := tunnel.Client().DBCredentials(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		listener, localPort, err := isle.ListenLocal(port)
+		if err != nil {
+			return err
+		}
+		remotePort := isle.Presets["mariadb"].RemotePort
+		if p, err := strconv.Atoi(creds.Port); err == nil {
+			remotePort = p
+		}
+		fwd := isle.NewForward(listener, "mariadb", remotePort)
+		defer fwd.Close()
+		go fwd.Serve(ctx, tunnel)
+
+		creds.Host = "127.0.0.1"
+		creds.Port = strconv.Itoa(localPort)
+		slog.Debug("Forwarding mariadb", "port", localPort)
+
+		done := make(chan os.Signal, 1)
+		signal.Notify(done, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+
+		if format != "" {
+			dsn, err := creds.DSN(format)
+			if err != nil {
+				return err
+			}
+			fmt.Println(dsn)
+			fmt.Fprintln(os.Stderr, "\nPress Ctrl+c to close the connection")
+			<-done
+			return nil
+		}
+
+		clientCmd := launch(creds, c.Name)
+		clientCmd.Stdin = os.Stdin
+		clientCmd.Stdout = os.Stdout
+		clientCmd.Stderr = os.Stderr
+		if client == "mysql" || client == "mariadb" {
+			// terminal clients exit when the user is done, so close the forward with them
+			if err := clientCmd.Run(); err != nil {
+				return fmt.Errorf("error running %s: %v", client, err)
+			}
+			return nil
+		}
+
+		if err := clientCmd.Start(); err != nil {
+			return fmt.Errorf("could not open %s: %v", client, err)
+		}
+		go func() {
+			if err := clientCmd.Wait(); err != nil {
+				slog.Debug("client exited", "client", client, "err", err)
+			}
+		}()
+		fmt.Printf("%s is connected to the %s database through localhost:%d\n", client, c.Name, localPort)
+		if err := utils.CopyToClipboard(creds.Password); err != nil {
+			slog.Debug("unable to copy the password", "err", err)
+			fmt.Printf("Enter the %s password when %s asks for it, islectl secrets get DB_ROOT_PASSWORD prints it\n", creds.User, client)
+		} else {
+			fmt.Printf("The %s password is on your clipboard, paste it when %s asks for it\n", creds.User, client)
+		}
+		fmt.Println("Press Ctrl+c to close the connection when you are done")
+		<-done
+
+		return nil
+	},
+}
+
+var dbShellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Open an interactive MariaDB shell on your ISLE database",
+	Long: `Open an interactive MariaDB shell on your ISLE database.
+
+The shell runs inside the mariadb container using the database root credentials,
+connected to the Drupal database for the context's site (drupal_<site>).
+
+Examples:
+  islectl db shell                         # shell on the context's site database
+  islectl db shell --site history          # shell on the drupal_history multisite database
+  islectl db shell --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		containerName, database, err := mariadbTarget(f, c)
+		if err != nil {
+			return err
+		}
+
+		shell := exec.Command("docker", isle.MariaDBExecArgs(containerName, database, true)...)
+		shell.Dir = c.ProjectDir
+		return c.RunInteractiveCommand(shell)
+	},
+}
+
+var dbQueryCmd = &cobra.Command{
+	Use:   "query [SQL]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Run SQL against your ISLE database",
+	Long: `Run a SQL statement or a SQL file against your ISLE database.
+
+SQL files are streamed from this machine to the mariadb container, so they do not need to be
+copied to remote contexts first. Query results can be rendered as a table, CSV, or JSON.
+
+Examples:
+  islectl db query "SELECT nid, title FROM node_field_data LIMIT 5"
+  islectl db query "SELECT COUNT(*) AS nodes FROM node" --format json --context prod
+  islectl db query --file ./fix-aliases.sql --site history
+  islectl db query "SELECT * FROM users_field_data" --format csv > users.csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		file, err := f.GetString("file")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		if (len(args) == 0) == (file == "") {
+			return fmt.Errorf("pass either a SQL statement or --file")
+		}
+
+		containerName, database, err := mariadbTarget(f, c)
+		if err != nil {
+			return err
+		}
+
+		var stdin io.Reader
+		clientArgs := []string{"--batch"}
+		if len(args) == 1 {
+			clientArgs = append(clientArgs, "-e", args[0])
+		} else {
+			sqlFile, err := os.Open(file)
+			if err != nil {
+				return fmt.Errorf("unable to open SQL file: %v", err)
+			}
+			defer sqlFile.Close()
+			stdin = sqlFile
+		}
+
+		query := exec.Command("docker", isle.MariaDBExecArgs(containerName, database, false, clientArgs...)...)
+		query.Dir = c.ProjectDir
+		output, err := c.CaptureCommand(query, stdin)
+		if err != nil {
+			return err
+		}
+
+		header, rows := isle.ParseBatchOutput(output)
+		if header == nil {
+			return nil
+		}
+		return utils.WriteRows(os.Stdout, format, header, rows)
+	},
+}
+
+// mariadbTarget returns the mariadb container and the Drupal database
+// for the --site flag, falling back to the context's site.
+func mariadbTarget(f *pflag.FlagSet, c *config.Context) (string, string, error) {
+	site, err := f.GetString("site")
+	if err != nil {
+		return "", "", err
+	}
+	if site == "" {
+		site = c.Site
+	}
+
+	cli, err := isle.GetDockerCli(c)
+	if err != nil {
+		return "", "", err
+	}
+	defer cli.Close()
+	containerName, err := cli.GetContainerName(c, "mariadb", false)
+	if err != nil {
+		return "", "", err
+	}
+	if containerName == "" {
+		return "", "", fmt.Errorf("no running mariadb container found for context %q", c.Name)
+	}
+
+	return containerName, isle.DatabaseName(site), nil
+}
+
+func init() {
+	dbShellCmd.Flags().String("site", "", "Drupal multisite whose database to use. Defaults to the context's site")
+
+	dbQueryCmd.Flags().String("site", "", "Drupal multisite whose database to use. Defaults to the context's site")
+	dbQueryCmd.Flags().String("file", "", "Path to a SQL file on this machine to run")
+	dbQueryCmd.Flags().String("format", "table", "Output format for query results: "+strings.Join(utils.OutputFormats, ", "))
+
+	dbConnectCmd.Flags().String("client", "mysql", "Database client to launch: "+strings.Join(dbClientNames(), ", "))
+	dbConnectCmd.Flags().String("print", "", "Print a connection string instead of launching a client: "+strings.Join(isle.DSNFormats, ", "))
+	dbConnectCmd.Flags().Int("port", 0, "Local port to forward mariadb to. Defaults to a free port")
+
+	dbCmd.AddCommand(dbConnectCmd)
+	dbCmd.AddCommand(dbShellCmd)
+	dbCmd.AddCommand(dbQueryCmd)
+	rootCmd.AddCommand(dbCmd)
+}
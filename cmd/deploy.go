@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/kballard/go-shellquote"
+	"github.com/spf13/cobra"
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy code changes to a context",
+	Long: `Deploy code changes to a context by running an ordered pipeline of steps.
+
+The pipeline is the first of
+  1. --steps
+  2. the context's deploy-steps (islectl config set-context CONTEXT --deploy-steps ...)
+  3. the steps list in ` + isle.DeployFile + ` in the project directory
+  4. the default pipeline: ` + strings.Join(isle.DefaultDeploySteps, ", ") + `
+
+Built in steps: ` + strings.Join(isle.DeployStepNames, ", ") + `
+Custom steps:
+  drush:ARGS     run drush in the drupal container, e.g. drush:search-api:index
+  exec:SCRIPT    run a shell script in the drupal container
+  host:SCRIPT    run a shell script in the project directory
+
+The snapshot step records the image of every running service and a database dump so the deploy
+can be undone with islectl rollback.
+
+The pipeline stops at the first failing step. If maintenance mode was turned on, the site is left in
+maintenance mode so visitors don't see a half deployed site.
+
+Examples:
+  islectl deploy --context prod
+  islectl deploy --context prod --dry-run
+  islectl deploy --steps git-pull,up,drush:deploy`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		override, err := f.GetStringSlice("steps")
+		if err != nil {
+			return err
+		}
+		dryRun, err := f.GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+		keep, err := f.GetInt("keep-snapshots")
+		if err != nil {
+			return err
+		}
+
+		steps, source, err := isle.DeploySteps(c, override)
+		if err != nil {
+			return err
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		// validate the whole pipeline before changing anything
+		commands := make([][]string, len(steps))
+		for i, step := range steps {
+			if step == isle.SnapshotStep {
+				continue
+			}
+			commands[i], err = isle.DeployCommand(c, site, step)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Deploying %s using the %s\n", c.Name, source)
+		if dryRun {
+			for i, step := range steps {
+				description := shellquote.Join(commands[i]...)
+				if step == isle.SnapshotStep {
+					description = fmt.Sprintf("record the service images and dump the database to %s", isle.SnapshotDir)
+				}
+				fmt.Printf("%d. %s\n   %s\n", i+1, step, description)
+			}
+			return nil
+		}
+
+		durations := []time.Duration{}
+		start := time.Now()
+		var failed error
+		for i, step := range steps {
+			fmt.Printf("\n==> [%d/%d] %s\n", i+1, len(steps), step)
+			stepStart := time.Now()
+			var err error
+			if step == isle.SnapshotStep {
+				err = takeSnapshot(c, site, keep)
+			} else {
+				command := exec.Command(commands[i][0], commands[i][1:]...)
+				command.Dir = c.ProjectDir
+				_, err = c.RunCommand(command)
+			}
+			durations = append(durations, time.Since(stepStart))
+			if err != nil {
+				failed = fmt.Errorf("deploy step %q failed: %v", step, err)
+				break
+			}
+		}
+
+		printDeploySummary(steps, durations, failed != nil, time.Since(start))
+		if failed != nil {
+			ranSteps := steps[:len(durations)]
+			if slices.Contains(ranSteps, "maintenance-on") && !slices.Contains(ranSteps, "maintenance-off") {
+				fmt.Fprintf(os.Stderr, "\n%s is still in maintenance mode. Once fixed, turn it off with\n  islectl deploy --context %s --steps maintenance-off\n", c.Name, c.Name)
+			}
+			return failed
+		}
+
+		return nil
+	},
+}
+
+// takeSnapshot records a snapshot for islectl rollback and removes the oldest ones
+func takeSnapshot(c *config.Context, site isle.Site, keep int) error {
+	cli, err := isle.GetDockerCli(c)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	s, err := cli.TakeSnapshot(context.Background(), c, site)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recorded snapshot %s of %d services\n", s.ID, len(s.Services))
+
+	snapshots, err := isle.ListSnapshots(c)
+	if err != nil {
+		return err
+	}
+	if prune := isle.PruneSnapshotsCommand(c, snapshots, keep); prune != nil {
+		command := exec.Command(prune[0], prune[1:]...)
+		command.Dir = c.ProjectDir
+		if _, err := c.CaptureCommand(command, nil); err != nil {
+			return fmt.Errorf("unable to remove old snapshots: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func printDeploySummary(steps []string, durations []time.Duration, failed bool, total time.Duration) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tDURATION")
+	for i, step := range steps {
+		status, duration := "skipped", ""
+		if i < len(durations) {
+			status, duration = "ok", durations[i].Round(time.Millisecond).String()
+			if failed && i == len(durations)-1 {
+				status = "failed"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", step, status, duration)
+	}
+	fmt.Fprintf(w, "total\t\t%s\n", total.Round(time.Millisecond))
+	w.Flush()
+}
+
+func init() {
+	deployCmd.Flags().StringSlice("steps", []string{}, "Steps to run instead of the configured pipeline")
+	deployCmd.Flags().Bool("dry-run", false, "Print the pipeline without running it")
+	deployCmd.Flags().String("site", "", "Drupal multisite to deploy. Defaults to the context's site")
+	deployCmd.Flags().Int("keep-snapshots", 5, "Number of snapshots to keep for islectl rollback")
+
+	rootCmd.AddCommand(deployCmd)
+}
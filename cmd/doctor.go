@@ -0,0 +1,92 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common problems with a context",
+	Long: `Run a battery of diagnostics for a context and print how to fix each problem found.
+
+The checks cover SSH authentication and host keys, the docker socket, the project directory and
+docker-compose.yml, the secrets the compose file needs, the health of the services, disk space and
+memory on the host, and the expiry of the sites' TLS certificates.
+Checks that depend on a failed check are skipped.
+
+Examples:
+  islectl doctor
+  islectl doctor --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		// Ctrl+c stops the check in progress and skips the rest, the results so far are still printed
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		results := isle.Doctor(ctx, c, time.Now())
+
+		header := []string{"CHECK", "STATUS", "DETAIL"}
+		if format != "table" {
+			header = append(header, "REMEDIATION")
+		}
+		rows := [][]string{}
+		failed := 0
+		for _, r := range results {
+			row := []string{r.Name, string(r.Status), r.Detail}
+			if format != "table" {
+				row = append(row, r.Remediation)
+			}
+			rows = append(rows, row)
+			if r.Status == isle.CheckFail {
+				failed++
+			}
+		}
+		if err := utils.WriteRows(os.Stdout, format, header, rows); err != nil {
+			return err
+		}
+
+		if format == "table" {
+			fixes := []string{}
+			for _, r := range results {
+				if r.Remediation != "" && (r.Status == isle.CheckFail || r.Status == isle.CheckWarn) {
+					fixes = append(fixes, fmt.Sprintf("  %s: %s", r.Name, r.Remediation))
+				}
+			}
+			if len(fixes) > 0 {
+				fmt.Printf("\nTo fix:\n%s\n", strings.Join(fixes, "\n"))
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d checks failed on %s", failed, c.Name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	rootCmd.AddCommand(doctorCmd)
+}
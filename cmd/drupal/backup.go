@@ -19,10 +19,12 @@ var backupCmd = &cobra.Command{
 
 This creates a gzipped SQL dump of the database to /tmp/db.tar.gz in the container.
 Cache tables are excluded from the dump for efficiency, but their structure is preserved.
+Multisites other than default are dumped to /tmp/db-<site>.tar.gz.
 
 Example:
   islectl drupal backup              # Backup database to /tmp/db.tar.gz
-  islectl drupal backup --context prod  # Backup production database`,
+  islectl drupal backup --context prod  # Backup production database
+  islectl drupal backup --site history  # Backup the history multisite to /tmp/db-history.tar.gz`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		f := cmd.Flags()
 		context, err := config.CurrentContext(f)
@@ -39,18 +41,20 @@ Example:
 			return err
 		}
 
+		site, err := isle.CurrentSite(f, context)
+		if err != nil {
+			return err
+		}
 		cmdArgs := []string{
 			"exec",
 			drupalContainer,
 			"drush",
-			"sql-dump",
-			"-y",
-			"--skip-tables-list=cache,cache_*,watchdog",
-			"--structure-tables-list=cache,cache_*,watchdog",
-			"--debug",
-			"--gzip",
-			"--result-file=/tmp/db.tar.gz",
 		}
+		if uri := site.URI(context); uri != "" {
+			cmdArgs = append(cmdArgs, "--uri="+uri)
+		}
+		dumpArgs, _ := isle.SQLDumpArgs(site)
+		cmdArgs = append(cmdArgs, dumpArgs...)
 
 		c := exec.Command("docker", cmdArgs...)
 		c.Dir = context.ProjectDir
@@ -66,6 +70,7 @@ Example:
 func init() {
 	backupCmd.Flags().StringSlice("file", []string{"database"}, "components to backup")
 	backupCmd.Flags().StringSlice("component", []string{"database"}, "components to backup")
+	backupCmd.Flags().String("site", "", "Drupal multisite to backup. Defaults to the context's site")
 
 	RootCmd.AddCommand(backupCmd)
 }
@@ -0,0 +1,293 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package drupal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Export, diff and import Drupal config between contexts",
+	Long: `Export, diff and import Drupal config between contexts.
+
+Config is exported with drush config:export and copied to this machine, so it can be compared
+with another context's active config or a directory like your git working tree before importing.`,
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a context's active config to a local directory",
+	Long: `Export a context's active config to a local directory.
+
+Config files in the directory that no longer exist on the context are removed, like drush config:export.
+
+Examples:
+  islectl drupal config export --context prod               # export to ./config/prod
+  islectl drupal config export --dir drupal/rootfs/var/www/drupal/config/sync`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		dir, err := f.GetString("dir")
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			dir = filepath.Join("config", c.Name)
+		}
+
+		active, err := exportConfig(f, c)
+		if err != nil {
+			return err
+		}
+		if err := isle.WriteConfigDir(dir, active); err != nil {
+			return fmt.Errorf("unable to write config to %s: %v", dir, err)
+		}
+		fmt.Printf("Exported %d config items from %s to %s\n", len(active), c.Name, dir)
+
+		return nil
+	},
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what importing config would change on a context",
+	Long: `Show what importing config from another context or a local directory would change
+on the current context's active config.
+
+Examples:
+  islectl drupal config diff --source prod                  # prod's config vs local
+  islectl drupal config diff --dir drupal/rootfs/var/www/drupal/config/sync --context prod
+  islectl drupal config diff --source prod --full           # include line by line changes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		full, err := f.GetBool("full")
+		if err != nil {
+			return err
+		}
+
+		source, sourceLabel, err := configSource(f)
+		if err != nil {
+			return err
+		}
+		active, err := exportConfig(f, c)
+		if err != nil {
+			return err
+		}
+
+		diff := isle.DiffConfig(source, active)
+		printConfigDiff(diff, sourceLabel, c.Name)
+		if full {
+			for _, name := range diff.Changed {
+				if err := printFileDiff(name, active[name], source[name], c.Name, sourceLabel); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import config from another context or a local directory",
+	Long: `Import config from another context or a local directory into the current context.
+
+A summary of the config that will be added, changed and deleted is shown before importing.
+
+Examples:
+  islectl drupal config import --dir drupal/rootfs/var/www/drupal/config/sync --context prod
+  islectl drupal config import --source stage --context prod --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		source, sourceLabel, err := configSource(f)
+		if err != nil {
+			return err
+		}
+		if len(source) == 0 {
+			return fmt.Errorf("no config found in %s", sourceLabel)
+		}
+		active, err := exportConfig(f, c)
+		if err != nil {
+			return err
+		}
+
+		diff := isle.DiffConfig(source, active)
+		printConfigDiff(diff, sourceLabel, c.Name)
+		if diff.Empty() {
+			return nil
+		}
+		if !yes {
+			if ok, err := config.Confirm(fmt.Sprintf("Import this config into %s?", c.Name)); err != nil || !ok {
+				return err
+			}
+		}
+
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+
+		return cli.ImportConfig(context.Background(), c, site, source)
+	},
+}
+
+// exportConfig returns the active config of the --site on a context
+func exportConfig(f *pflag.FlagSet, c *config.Context) (isle.ConfigSet, error) {
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := isle.GetDockerCli(c)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	return cli.ExportConfig(context.Background(), c, site)
+}
+
+// configSource reads the config from --dir or exports it from the --source context
+func configSource(f *pflag.FlagSet) (isle.ConfigSet, string, error) {
+	dir, err := f.GetString("dir")
+	if err != nil {
+		return nil, "", err
+	}
+	sourceName, err := f.GetString("source")
+	if err != nil {
+		return nil, "", err
+	}
+	if (dir == "") == (sourceName == "") {
+		return nil, "", fmt.Errorf("pass one of --source or --dir")
+	}
+
+	if dir != "" {
+		cs, err := isle.ReadConfigDir(dir)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to read config from %s: %v", dir, err)
+		}
+		return cs, dir, nil
+	}
+
+	exists, err := config.ContextExists(sourceName)
+	if err != nil {
+		return nil, "", err
+	}
+	if !exists {
+		return nil, "", fmt.Errorf("context %q does not exist", sourceName)
+	}
+	source, err := config.GetContext(sourceName)
+	if err != nil {
+		return nil, "", err
+	}
+	cs, err := exportConfig(f, &source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cs, source.Name, nil
+}
+
+func printConfigDiff(diff isle.ConfigDiff, source, target string) {
+	if diff.Empty() {
+		fmt.Printf("%s and %s have the same config\n", source, target)
+		return
+	}
+
+	fmt.Printf("Importing config from %s into %s:\n", source, target)
+	for _, name := range diff.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range diff.Changed {
+		fmt.Printf("  ~ %s\n", name)
+	}
+	for _, name := range diff.Deleted {
+		fmt.Printf("  - %s\n", name)
+	}
+	fmt.Printf("%d added, %d changed, %d deleted\n", len(diff.Added), len(diff.Changed), len(diff.Deleted))
+}
+
+// printFileDiff shows a unified diff of a config item using the local diff command
+func printFileDiff(name string, from, to []byte, fromLabel, toLabel string) error {
+	dir, err := os.MkdirTemp("", "islectl-config-diff")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	fromFile := filepath.Join(dir, "from.yml")
+	toFile := filepath.Join(dir, "to.yml")
+	if err := os.WriteFile(fromFile, from, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(toFile, to, 0600); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	diff := exec.Command("diff", "-u",
+		"--label", fmt.Sprintf("%s/%s.yml", fromLabel, name),
+		"--label", fmt.Sprintf("%s/%s.yml", toLabel, name),
+		fromFile, toFile)
+	diff.Stdout = os.Stdout
+	diff.Stderr = os.Stderr
+	// diff exits 1 when the files differ
+	if err := diff.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("error running diff: %v", err)
+	}
+
+	return nil
+}
+
+func init() {
+	configCmd.PersistentFlags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+
+	configExportCmd.Flags().String("dir", "", "Local directory to export to. Defaults to ./config/CONTEXT")
+
+	for _, c := range []*cobra.Command{configDiffCmd, configImportCmd} {
+		c.Flags().String("source", "", "Context to read the config to import from")
+		c.Flags().String("dir", "", "Local directory to read the config to import from, e.g. your git working tree")
+	}
+	configDiffCmd.Flags().Bool("full", false, "Show the line by line changes of changed config")
+	configImportCmd.Flags().BoolP("yes", "y", false, "Import without asking for confirmation")
+
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configDiffCmd)
+	configCmd.AddCommand(configImportCmd)
+	RootCmd.AddCommand(configCmd)
+}
@@ -24,12 +24,14 @@ var execCmd = &cobra.Command{
 
 If no command is provided, opens an interactive bash shell in the container.
 This is useful for debugging, running composer commands, or performing file operations.
+Pass --site before the command to have drush commands ran in the container target a multisite.
 
 Examples:
   islectl drupal exec                              # Open interactive bash shell
   islectl drupal exec ls -la /var/www/drupal/web   # List files
   islectl drupal exec composer require drupal/devel # Install a module
-  islectl drupal exec "drush cr && drush status"   # Run multiple commands`,
+  islectl drupal exec "drush cr && drush status"   # Run multiple commands
+  islectl drupal exec --site history drush status  # Run drush against the history multisite`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// since we're disabling flag parsing to make easy passing of flags to docker compose
 		// handle the context flag
@@ -37,6 +39,7 @@ Examples:
 		if err != nil {
 			return err
 		}
+		filteredArgs, siteName, _ := utils.GetFlagFromArgs(filteredArgs, "site", false)
 		context, err := config.GetContext(isleContext)
 		if err != nil {
 			return err
@@ -58,11 +61,19 @@ Examples:
 		if err != nil {
 			return err
 		}
+		site, err := isle.FindSite(&context, siteName)
+		if err != nil {
+			return err
+		}
 		cmdArgs := []string{
 			"exec",
 			"-i",
-			drupalContainer,
 		}
+		// point drush at the site for anything ran in the container
+		if uri := site.URI(&context); uri != "" {
+			cmdArgs = append(cmdArgs, "-e", "DRUSH_OPTIONS_URI="+uri)
+		}
+		cmdArgs = append(cmdArgs, drupalContainer)
 
 		if len(filteredArgs) == 0 {
 			filteredArgs = []string{"bash"}
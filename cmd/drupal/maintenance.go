@@ -0,0 +1,207 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package drupal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Turn Drupal maintenance mode on or off",
+	Long: `Turn Drupal maintenance mode on or off.
+
+Pass --stack to also serve a static maintenance page for every service at the Traefik layer,
+so work on fcrepo, solr or the database can happen without users hitting errors.
+
+Examples:
+  islectl drupal maintenance on --context prod
+  islectl drupal maintenance on --context prod --stack --page maintenance.html
+  islectl drupal maintenance status --context prod
+  islectl drupal maintenance off --context prod`,
+}
+
+var maintenanceOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Put Drupal, and optionally the whole stack, into maintenance mode",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		stack, err := f.GetBool("stack")
+		if err != nil {
+			return err
+		}
+		pageFile, err := f.GetString("page")
+		if err != nil {
+			return err
+		}
+
+		page := isle.DefaultMaintenancePage
+		if pageFile != "" {
+			data, err := os.ReadFile(pageFile)
+			if err != nil {
+				return fmt.Errorf("unable to read maintenance page: %v", err)
+			}
+			page = string(data)
+			stack = true
+		}
+
+		if err := setMaintenanceMode(f, c, true); err != nil {
+			return err
+		}
+		if !stack {
+			fmt.Printf("%s is in maintenance mode\n", c.Name)
+			return nil
+		}
+
+		// answer on the site's entrypoints with its certificates
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			return err
+		}
+		routers, err := cli.SiteRouters(context.Background(), c)
+		cli.Close()
+		if err != nil {
+			return err
+		}
+
+		pageCmd := isle.MaintenancePageCommand(c, page, routers)
+		if err := runMaintenanceCommand(c, pageCmd); err != nil {
+			return fmt.Errorf("unable to start the maintenance page: %v", err)
+		}
+		fmt.Printf("%s is in maintenance mode and every service is serving the maintenance page\n", c.Name)
+
+		return nil
+	},
+}
+
+var maintenanceOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Take Drupal and the stack out of maintenance mode",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+
+		// put the services back behind Traefik before Drupal leaves maintenance mode
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			return err
+		}
+		running, err := cli.MaintenancePageRunning(context.Background(), c)
+		cli.Close()
+		if err != nil {
+			return err
+		}
+		if running {
+			if err := runMaintenanceCommand(c, []string{"docker", "rm", "--force", isle.MaintenanceContainerName(c)}); err != nil {
+				return fmt.Errorf("unable to remove the maintenance page: %v", err)
+			}
+		}
+
+		if err := setMaintenanceMode(f, c, false); err != nil {
+			return err
+		}
+		fmt.Printf("%s is out of maintenance mode\n", c.Name)
+
+		return nil
+	},
+}
+
+var maintenanceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether Drupal and the stack are in maintenance mode",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		script := isle.DrushCommand(c, site, "state:get", "system.maintenance_mode")
+		output, err := c.CaptureCommand(maintenanceExec(c, script), nil)
+		if err != nil {
+			return err
+		}
+		drupal, err := isle.ParseMaintenanceMode(output)
+		if err != nil {
+			return err
+		}
+
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+		stack, err := cli.MaintenancePageRunning(context.Background(), c)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Drupal (%s): %s\n", site.Name, onOff(drupal))
+		fmt.Printf("Maintenance page: %s\n", onOff(stack))
+
+		return nil
+	},
+}
+
+func setMaintenanceMode(f *pflag.FlagSet, c *config.Context, on bool) error {
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return err
+	}
+	if _, err := c.RunCommand(maintenanceExec(c, isle.MaintenanceScript(c, site, on))); err != nil {
+		return fmt.Errorf("unable to set maintenance mode on %s: %v", c.Name, err)
+	}
+	return nil
+}
+
+// maintenanceExec runs a script in the drupal container
+func maintenanceExec(c *config.Context, script string) *exec.Cmd {
+	cmd := exec.Command("docker", isle.DrupalExecArgs(c, script)...)
+	cmd.Dir = c.ProjectDir
+	return cmd
+}
+
+func runMaintenanceCommand(c *config.Context, args []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = c.ProjectDir
+	_, err := c.RunCommand(cmd)
+	return err
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func init() {
+	maintenanceCmd.PersistentFlags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+	maintenanceOnCmd.Flags().Bool("stack", false, "Also serve a static maintenance page for every service at the Traefik layer")
+	maintenanceOnCmd.Flags().String("page", "", "HTML file to serve as the maintenance page. Implies --stack")
+
+	maintenanceCmd.AddCommand(maintenanceOnCmd)
+	maintenanceCmd.AddCommand(maintenanceOffCmd)
+	maintenanceCmd.AddCommand(maintenanceStatusCmd)
+	RootCmd.AddCommand(maintenanceCmd)
+}
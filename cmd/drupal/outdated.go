@@ -0,0 +1,132 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package drupal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Summarise outdated modules and security advisories across contexts",
+	Long: `Summarise outdated modules and security advisories across contexts.
+
+Runs composer outdated and composer audit in the drupal container of every context
+(or the ones passed with --contexts) and shows the results in one table.
+Contexts that can not be reached are skipped with a warning.
+
+Examples:
+  islectl drupal outdated
+  islectl drupal outdated --contexts stage,prod --all
+  islectl drupal outdated --format csv > outdated.csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		contextNames, err := f.GetStringSlice("contexts")
+		if err != nil {
+			return err
+		}
+		all, err := f.GetBool("all")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		contexts := []config.Context{}
+		for _, c := range cfg.Contexts {
+			if len(contextNames) == 0 || slices.Contains(contextNames, c.Name) {
+				contexts = append(contexts, c)
+			}
+		}
+		if len(contexts) == 0 {
+			return fmt.Errorf("no contexts found matching %s", strings.Join(contextNames, ", "))
+		}
+
+		rows := [][]string{}
+		for _, c := range contexts {
+			report, err := composerReport(&c)
+			if err != nil {
+				slog.Warn("Skipping context", "context", c.Name, "err", err)
+				continue
+			}
+			rows = append(rows, outdatedRows(c.Name, report, all)...)
+		}
+
+		header := []string{"CONTEXT", "PACKAGE", "INSTALLED", "LATEST", "UPDATE", "ADVISORIES"}
+		return utils.WriteRows(os.Stdout, format, header, rows)
+	},
+}
+
+func composerReport(c *config.Context) (isle.ComposerReport, error) {
+	cli, err := isle.GetDockerCli(c)
+	if err != nil {
+		return isle.ComposerReport{}, err
+	}
+	defer cli.Close()
+
+	return cli.ComposerReport(c)
+}
+
+// outdatedRows has a row per outdated package, and for packages with advisories that are not outdated.
+// Unless all is set, only Drupal packages are included.
+func outdatedRows(contextName string, report isle.ComposerReport, all bool) [][]string {
+	advisories := map[string][]string{}
+	for _, advisory := range report.Advisories {
+		title := advisory.Title
+		if advisory.CVE != "" {
+			title = fmt.Sprintf("%s (%s)", title, advisory.CVE)
+		}
+		advisories[advisory.Package] = append(advisories[advisory.Package], title)
+	}
+
+	include := func(name string) bool {
+		return all || strings.HasPrefix(name, "drupal/") || len(advisories[name]) > 0
+	}
+	updates := map[string]string{
+		"semver-safe-update": "minor",
+		"update-possible":    "major",
+	}
+
+	rows := [][]string{}
+	seen := map[string]bool{}
+	for _, pkg := range report.Outdated {
+		if !include(pkg.Name) {
+			continue
+		}
+		seen[pkg.Name] = true
+		rows = append(rows, []string{contextName, pkg.Name, pkg.Version, pkg.Latest, updates[pkg.Status], strings.Join(advisories[pkg.Name], "; ")})
+	}
+	for _, advisory := range report.Advisories {
+		if seen[advisory.Package] {
+			continue
+		}
+		seen[advisory.Package] = true
+		rows = append(rows, []string{contextName, advisory.Package, "", "", "", strings.Join(advisories[advisory.Package], "; ")})
+	}
+
+	return rows
+}
+
+func init() {
+	outdatedCmd.Flags().StringSlice("contexts", []string{}, "Contexts to check. Defaults to all contexts")
+	outdatedCmd.Flags().Bool("all", false, "Include non Drupal packages")
+	outdatedCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+
+	RootCmd.AddCommand(outdatedCmd)
+}
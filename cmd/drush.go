@@ -6,12 +6,13 @@ package cmd
 import (
 	"fmt"
 	"log/slog"
+	"os"
 	"os/exec"
-	"slices"
 	"strings"
 
 	"github.com/islandora-devops/islectl/internal/utils"
 	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
 	"github.com/kballard/go-shellquote"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +27,12 @@ var drushCmd = &cobra.Command{
 This is a shorthand for "islectl compose exec drupal drush" with automatic --uri handling.
 The DRUPAL_DRUSH_URI environment variable is automatically passed unless you specify --uri or -l.
 
+Multisite:
+  --site NAME   run against a multisite, setting its --uri (see "islectl sites list")
+  --all-sites   run the command against every site, one after the other
+
+These flags must come before the drush command, flags after it are passed to drush as is.
+
 Special subcommands:
   uli - Generate and auto-open a one-time login link in your browser
 
@@ -37,7 +44,9 @@ Examples:
   islectl drush uli                         # Generate login link and open in browser
   islectl drush uli --uid=2                 # Login link for user ID 2
   islectl drush sqlq "SHOW TABLES"          # Run SQL query
-  islectl drush --context prod status       # Check status on prod context`,
+  islectl drush --context prod status       # Check status on prod context
+  islectl drush --site history cr           # Clear caches on the history multisite
+  islectl drush --all-sites updb -y         # Run database updates on every site`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// since we're disabling flag parsing to make easy passing of flags to docker compose
 		// handle the context flag
@@ -45,10 +54,14 @@ Examples:
 		if err != nil {
 			return err
 		}
-
-		drush := "drush"
-		if !slices.Contains(filteredArgs, "--uri") && !slices.Contains(filteredArgs, "-l") {
-			drush = drush + " --uri $DRUPAL_DRUSH_URI"
+		filteredArgs, siteName, siteFound := utils.GetFlagFromArgs(filteredArgs, "site", false)
+		filteredArgs, allSites, _ := utils.GetFlagFromArgs(filteredArgs, "all-sites", true)
+		// a flag after --site means its value is missing, e.g. "--site --all-sites"
+		if siteFound && (siteName == "" || strings.HasPrefix(siteName, "-")) {
+			return fmt.Errorf("--site needs a site name, see islectl sites list")
+		}
+		if siteFound && allSites == "true" {
+			return fmt.Errorf("--site and --all-sites can't be used together")
 		}
 
 		context, err := config.GetContext(isleContext)
@@ -56,25 +69,74 @@ Examples:
 			return err
 		}
 
-		cmdArgs := []string{
-			"compose",
-			"exec",
-			fmt.Sprintf("drupal-%s", context.Profile),
-			"bash",
-			"-c",
-			fmt.Sprintf("%s %s", drush, shellquote.Join(filteredArgs...)),
+		if allSites != "true" {
+			site, err := isle.FindSite(&context, siteName)
+			if err != nil {
+				return err
+			}
+			return runDrush(&context, site, filteredArgs)
 		}
-		c := exec.Command("docker", cmdArgs...)
-		c.Dir = context.ProjectDir
-		_, err = context.RunCommand(c)
+
+		sites, err := isle.ListSites(&context)
 		if err != nil {
 			return err
 		}
+		failed := []string{}
+		for _, site := range sites {
+			fmt.Printf("==> %s\n", site.Name)
+			if err := runDrush(&context, site, filteredArgs); err != nil {
+				slog.Error("drush failed", "site", site.Name, "err", err)
+				failed = append(failed, site.Name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("drush failed on sites: %s", strings.Join(failed, ", "))
+		}
 
 		return nil
 	},
 }
 
+// runDrush runs drush in the drupal container against a site.
+// The site's --uri is only added if the args do not already have one.
+func runDrush(context *config.Context, site isle.Site, args []string) error {
+	drush := "drush"
+	if !hasUriArg(args) {
+		drush = drush + " --uri " + drushURIArg(context, site)
+	}
+
+	cmdArgs := []string{
+		"compose",
+		"exec",
+		fmt.Sprintf("drupal-%s", context.Profile),
+		"bash",
+		"-c",
+		fmt.Sprintf("%s %s", drush, shellquote.Join(args...)),
+	}
+	c := exec.Command("docker", cmdArgs...)
+	c.Dir = context.ProjectDir
+	_, err := context.RunCommand(c)
+
+	return err
+}
+
+func hasUriArg(args []string) bool {
+	for _, arg := range args {
+		if arg == "--uri" || arg == "-l" || strings.HasPrefix(arg, "--uri=") || strings.HasPrefix(arg, "-l=") {
+			return true
+		}
+	}
+	return false
+}
+
+// drushURIArg is the shell word for a site's drush --uri
+func drushURIArg(context *config.Context, site isle.Site) string {
+	if uri := site.URI(context); uri != "" {
+		return shellquote.Join(uri)
+	}
+	return "$DRUPAL_DRUSH_URI"
+}
+
 // login runs drush uli
 var loginCmd = &cobra.Command{
 	Use:   "uli",
@@ -82,11 +144,17 @@ var loginCmd = &cobra.Command{
 	Long: `Generate a one-time login link and automatically open it in your default browser.
 
 This runs 'drush uli' in the Drupal container and opens the resulting URL.
+When there is no desktop session to open a browser in (e.g. over SSH) the link is printed instead.
 
 Examples:
   islectl drush uli              # Login as admin (user 1)
   islectl drush uli --uid=2      # Login as user ID 2
-  islectl drush uli --uri=https://example.com  # Use specific URI`,
+  islectl drush uli --name=editor  # Login as the user named editor
+  islectl drush uli --mail=editor@example.com  # Login as the user with this email
+  islectl drush uli --uri=https://example.com  # Use specific URI
+  islectl drush uli --site history  # Login to the history multisite
+  islectl drush uli --print-only | pbcopy  # Only print the link
+  islectl drush uli --copy --qr --context prod  # Copy the link and show it as a QR code`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		f := cmd.Flags()
 		context, err := config.CurrentContext(f)
@@ -97,10 +165,49 @@ Examples:
 		if err != nil {
 			return err
 		}
+		if !f.Changed("uid") {
+			uid = 0
+		}
+		name, err := f.GetString("name")
+		if err != nil {
+			return err
+		}
+		mail, err := f.GetString("mail")
+		if err != nil {
+			return err
+		}
 		uri, err := f.GetString("uri")
 		if err != nil {
 			return err
 		}
+		siteName, err := f.GetString("site")
+		if err != nil {
+			return err
+		}
+		printOnly, err := f.GetBool("print-only")
+		if err != nil {
+			return err
+		}
+		copyLink, err := f.GetBool("copy")
+		if err != nil {
+			return err
+		}
+		showQR, err := f.GetBool("qr")
+		if err != nil {
+			return err
+		}
+
+		if uri == "" {
+			site, err := isle.FindSite(context, siteName)
+			if err != nil {
+				return err
+			}
+			uri = site.URI(context)
+		}
+		drush, err := isle.UliCommand(uri, uid, name, mail)
+		if err != nil {
+			return err
+		}
 
 		cmdArgs := []string{
 			"compose",
@@ -112,23 +219,49 @@ Examples:
 		}
 		cmdArgs = append(cmdArgs, []string{
 			"exec",
+			"-T",
 			fmt.Sprintf("drupal-%s", context.Profile),
 			"bash",
 			"-c",
-			fmt.Sprintf("drush uli --uri=%s --uid=%d", uri, uid),
+			drush,
 		}...)
 		c := exec.Command("docker", cmdArgs...)
 		c.Dir = context.ProjectDir
-		output, err := context.RunCommand(c)
+		output, err := context.CaptureCommand(c, nil)
+		if err != nil {
+			return err
+		}
+		link, err := isle.ExtractLoginURL(output)
 		if err != nil {
 			return err
 		}
 
-		if strings.HasPrefix(output, "http") {
-			err := utils.OpenURL(output)
-			if err != nil {
-				slog.Warn("Error opening URL", "err", err)
+		fmt.Println(link)
+		if printOnly {
+			return nil
+		}
+		if copyLink || showQR {
+			if showQR {
+				if err := utils.RenderQR(os.Stdout, link); err != nil {
+					return err
+				}
+			}
+			if copyLink {
+				if err := utils.CopyToClipboard(link); err != nil {
+					slog.Warn("Unable to copy the login link to the clipboard", "err", err)
+				} else {
+					fmt.Fprintln(os.Stderr, "Copied the login link to the clipboard")
+				}
 			}
+			return nil
+		}
+
+		if !utils.CanOpenBrowser() {
+			fmt.Fprintln(os.Stderr, "No browser available, use --copy or --qr to get the link to one")
+			return nil
+		}
+		if err := utils.OpenURL(link); err != nil {
+			slog.Warn("Error opening URL", "err", err)
 		}
 
 		return nil
@@ -137,7 +270,13 @@ Examples:
 
 func init() {
 	loginCmd.Flags().Uint("uid", 1, "Drupal user ID to provide a direct login link for")
-	loginCmd.Flags().String("uri", "$DRUPAL_DRUSH_URI", "--uri flag to pass to drush")
+	loginCmd.Flags().String("name", "", "Drupal username to provide a direct login link for")
+	loginCmd.Flags().String("mail", "", "Email of the Drupal user to provide a direct login link for")
+	loginCmd.Flags().String("uri", "", "--uri flag to pass to drush. Defaults to the site's URI")
+	loginCmd.Flags().String("site", "", "Drupal multisite to log in to. Defaults to the context's site")
+	loginCmd.Flags().Bool("print-only", false, "Only print the login link")
+	loginCmd.Flags().Bool("copy", false, "Copy the login link to the clipboard instead of opening it")
+	loginCmd.Flags().Bool("qr", false, "Show the login link as a QR code instead of opening it")
 
 	drushCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(drushCmd)
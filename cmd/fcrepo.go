@@ -0,0 +1,200 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var fcrepoCmd = &cobra.Command{
+	Use:   "fcrepo",
+	Short: "Check Fedora and reconcile it with Drupal",
+	Long: `Check the fcrepo service and reconcile it with Drupal.
+
+The fcrepo service is reached through the context's SSH connection, like port-forward. Requests are
+authenticated with a token signed by Drupal's JWT_PRIVATE_KEY secret, the same way Islandora writes to Fedora.
+
+Examples:
+  islectl fcrepo status --context prod
+  islectl fcrepo reconcile --context prod
+  islectl fcrepo reconcile --context prod --reindex`,
+}
+
+var fcrepoStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check fcrepo responds and compare its size with Drupal's nodes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		nodes, err := fcrepoNodes(f, c)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		err = withFcrepo(c, func(ctx context.Context, fcrepo *isle.Fcrepo, tunnel *isle.Tunnel) error {
+			latency, err := fcrepo.Ping(ctx)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, []string{"reachable", fmt.Sprintf("yes (%s)", latency.Round(time.Millisecond))})
+
+			resources := "unknown"
+			if count, err := fcrepo.ResourceCount(ctx); err == nil {
+				resources = fmt.Sprint(count)
+			} else {
+				fmt.Fprintf(os.Stderr, "unable to count fedora resources: %v\n", err)
+			}
+			rows = append(rows, []string{"fedora resources", resources})
+			rows = append(rows, []string{"drupal nodes", fmt.Sprint(len(nodes))})
+
+			size := "unknown"
+			if bytes, err := fcrepoDiskUsage(c, tunnel); err == nil {
+				size = utils.FormatBytes(bytes)
+			} else {
+				fmt.Fprintf(os.Stderr, "unable to measure the repository size: %v\n", err)
+			}
+			rows = append(rows, []string{"repository size", size})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.WriteRows(os.Stdout, format, []string{"CHECK", "VALUE"}, rows)
+	},
+}
+
+var fcrepoReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "List Drupal nodes missing from Fedora and optionally queue them for indexing",
+	Long: `Check every Drupal node of the given content types has a resource in Fedora and list the ones that don't.
+
+Pass --reindex to run Islandora's index action on the missing nodes, which queues them on ActiveMQ
+for the fcrepo indexer to write to Fedora.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		workers, err := f.GetInt("workers")
+		if err != nil {
+			return err
+		}
+		reindex, err := f.GetBool("reindex")
+		if err != nil {
+			return err
+		}
+		action, err := f.GetString("action")
+		if err != nil {
+			return err
+		}
+
+		nodes, err := fcrepoNodes(f, c)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Checking %d nodes in fedora...\n", len(nodes))
+
+		var missing []isle.DrupalNode
+		err = withFcrepo(c, func(ctx context.Context, fcrepo *isle.Fcrepo, tunnel *isle.Tunnel) error {
+			missing, err = fcrepo.MissingNodes(ctx, nodes, workers)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, node := range missing {
+			rows = append(rows, []string{fmt.Sprint(node.ID), node.Type, node.UUID, isle.FedoraPath(node.UUID)})
+		}
+		if err := utils.WriteRows(os.Stdout, format, []string{"NID", "TYPE", "UUID", "FEDORA PATH"}, rows); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d nodes are missing from fedora\n", len(missing), len(nodes))
+		if !reindex || len(missing) == 0 {
+			return nil
+		}
+
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+		ids := make([]int, len(missing))
+		for i, node := range missing {
+			ids[i] = node.ID
+		}
+		queued, err := isle.RunNodeAction(c, site, action, ids, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d nodes to be indexed in fedora\n", queued)
+		return nil
+	},
+}
+
+func fcrepoNodes(f *pflag.FlagSet, c *config.Context) ([]isle.DrupalNode, error) {
+	types, err := f.GetStringSlice("type")
+	if err != nil {
+		return nil, err
+	}
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return nil, err
+	}
+	return isle.DrupalNodes(c, site, types)
+}
+
+func fcrepoDiskUsage(c *config.Context, tunnel *isle.Tunnel) (int64, error) {
+	containerName, err := tunnel.ContainerName("fcrepo")
+	if err != nil {
+		return 0, err
+	}
+	command := exec.Command("docker", "exec", strings.TrimPrefix(containerName, "/"), "du", "-sk", isle.FcrepoDataDir)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return 0, err
+	}
+	return isle.ParseDiskUsage(output)
+}
+
+func init() {
+	fcrepoCmd.PersistentFlags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+	fcrepoCmd.PersistentFlags().StringSlice("type", []string{"islandora_object"}, "Content types Islandora writes to Fedora. Pass an empty value for every node")
+	fcrepoStatusCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	fcrepoReconcileCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	fcrepoReconcileCmd.Flags().Int("workers", 8, "Nodes to check in fedora at the same time")
+	fcrepoReconcileCmd.Flags().Bool("reindex", false, "Queue the missing nodes to be indexed in fedora")
+	fcrepoReconcileCmd.Flags().String("action", isle.FcrepoIndexAction, "Drupal action that queues a node for fedora")
+
+	fcrepoCmd.AddCommand(fcrepoStatusCmd)
+	fcrepoCmd.AddCommand(fcrepoReconcileCmd)
+	rootCmd.AddCommand(fcrepoCmd)
+}
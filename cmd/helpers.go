@@ -0,0 +1,87 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/pflag"
+)
+
+// withTunnel runs fn with a tunnel to the context's services. The context passed
+// to fn is cancelled on Ctrl+c so long-running commands stop and close the tunnel.
+func withTunnel(c *config.Context, fn func(context.Context, *isle.Tunnel) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tunnel, err := isle.NewTunnel(c)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Close()
+
+	return fn(ctx, tunnel)
+}
+
+func withActiveMQ(c *config.Context, fn func(context.Context, *isle.ActiveMQ) error) error {
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		amq, err := isle.NewActiveMQ(ctx, tunnel)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, amq)
+	})
+}
+
+func withCantaloupe(c *config.Context, fn func(context.Context, *isle.Cantaloupe, *isle.Tunnel) error) error {
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		cantaloupe, err := isle.NewCantaloupe(ctx, tunnel)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, cantaloupe, tunnel)
+	})
+}
+
+func withFcrepo(c *config.Context, fn func(context.Context, *isle.Fcrepo, *isle.Tunnel) error) error {
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		fcrepo, err := isle.NewFcrepo(ctx, tunnel)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, fcrepo, tunnel)
+	})
+}
+
+func withSolr(c *config.Context, fn func(context.Context, *isle.Solr, *isle.Tunnel) error) error {
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		return fn(ctx, isle.NewSolr(tunnel), tunnel)
+	})
+}
+
+func withTraefik(c *config.Context, fn func(context.Context, *isle.Tunnel, string) error) error {
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		containerName, err := tunnel.ContainerName("traefik")
+		if err != nil {
+			return err
+		}
+		return fn(ctx, tunnel, strings.TrimPrefix(containerName, "/"))
+	})
+}
+
+func withTriplestore(f *pflag.FlagSet, c *config.Context, fn func(context.Context, *isle.Triplestore) error) error {
+	namespace, err := f.GetString("namespace")
+	if err != nil {
+		return err
+	}
+	return withTunnel(c, func(ctx context.Context, tunnel *isle.Tunnel) error {
+		return fn(ctx, isle.NewTriplestore(tunnel, namespace))
+	})
+}
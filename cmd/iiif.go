@@ -0,0 +1,228 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var iiifCmd = &cobra.Command{
+	Use:   "iiif",
+	Short: "Manage cantaloupe's cache and check IIIF images resolve",
+	Long: `Manage cantaloupe's cache and check IIIF manifests and images resolve.
+
+The cantaloupe and drupal services are reached through the context's SSH connection, like port-forward.
+
+Examples:
+  islectl iiif cache stats --context prod
+  islectl iiif cache purge --context prod
+  islectl iiif check 42 --context prod`,
+}
+
+var iiifCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and purge cantaloupe's cache",
+}
+
+var iiifCacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of cantaloupe's cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		return withCantaloupe(c, func(ctx context.Context, cantaloupe *isle.Cantaloupe, tunnel *isle.Tunnel) error {
+			command := exec.Command("docker", "exec", cantaloupe.Container, "sh", "-c", isle.CacheStatsScript(cantaloupe.CacheDir))
+			command.Dir = c.ProjectDir
+			output, err := c.CaptureCommand(command, nil)
+			if err != nil {
+				return fmt.Errorf("unable to inspect the cache in %s: %v", cantaloupe.CacheDir, err)
+			}
+			usage, err := isle.ParseCacheStats(output)
+			if err != nil {
+				return err
+			}
+
+			rows := [][]string{}
+			var totalBytes, totalFiles int64
+			for _, u := range usage {
+				rows = append(rows, []string{u.Name, fmt.Sprint(u.Files), utils.FormatBytes(u.Bytes)})
+				totalBytes += u.Bytes
+				totalFiles += u.Files
+			}
+			rows = append(rows, []string{"total", fmt.Sprint(totalFiles), utils.FormatBytes(totalBytes)})
+			return utils.WriteRows(os.Stdout, format, []string{"CACHE", "FILES", "SIZE"}, rows)
+		})
+	},
+}
+
+var iiifCachePurgeCmd = &cobra.Command{
+	Use:   "purge [IDENTIFIER]",
+	Short: "Purge cantaloupe's cache",
+	Long: `Purge everything in cantaloupe's cache, only the invalid entries with --invalid, or the cached
+images and info of one identifier.
+
+Purging invalid entries or an identifier uses cantaloupe's API, which needs
+CANTALOUPE_ENDPOINT_API_ENABLED=true on the cantaloupe service. Without the API the whole cache
+is purged by deleting its files.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		invalid, err := f.GetBool("invalid")
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+		if invalid && len(args) > 0 {
+			return fmt.Errorf("pass either an identifier or --invalid")
+		}
+
+		verb, identifier := isle.PurgeCache, ""
+		switch {
+		case invalid:
+			verb = isle.PurgeInvalidFromCache
+		case len(args) > 0:
+			verb, identifier = isle.PurgeItemFromCache, args[0]
+		}
+		if verb == isle.PurgeCache && !yes {
+			if ok, err := config.Confirm(fmt.Sprintf("Purge cantaloupe's whole cache on %s? Images are slow until it fills again", c.Name)); err != nil || !ok {
+				return err
+			}
+		}
+
+		return withCantaloupe(c, func(ctx context.Context, cantaloupe *isle.Cantaloupe, tunnel *isle.Tunnel) error {
+			if verb == isle.PurgeCache && !cantaloupe.APIEnabled {
+				command := exec.Command("docker", "exec", cantaloupe.Container, "sh", "-c", isle.CachePurgeScript(cantaloupe.CacheDir))
+				command.Dir = c.ProjectDir
+				if _, err := c.CaptureCommand(command, nil); err != nil {
+					return fmt.Errorf("unable to delete the cache in %s: %v", cantaloupe.CacheDir, err)
+				}
+				fmt.Printf("Deleted the cache in %s\n", cantaloupe.CacheDir)
+				return nil
+			}
+			if err := cantaloupe.Purge(ctx, verb, identifier, time.Second); err != nil {
+				return err
+			}
+			fmt.Printf("Completed %s\n", verb)
+			return nil
+		})
+	},
+}
+
+var iiifCheckCmd = &cobra.Command{
+	Use:   "check NODE_ID",
+	Short: "Check a node's IIIF manifest, image info and tiles resolve",
+	Long: `Fetch a node's IIIF manifest from Drupal and request the info.json and first tile of every image in it
+from cantaloupe, reporting which ones fail.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		nid, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid node ID %q", args[0])
+		}
+		manifestPath, err := f.GetString("manifest-path")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		err = withCantaloupe(c, func(ctx context.Context, cantaloupe *isle.Cantaloupe, tunnel *isle.Tunnel) error {
+			siteURL := site.URI(c)
+			if siteURL == "" {
+				drupalContainer, err := tunnel.ContainerName("drupal")
+				if err != nil {
+					return err
+				}
+				siteURL, err = isle.GetConfigEnv(ctx, tunnel.Client().CLI, drupalContainer, "DRUPAL_DRUSH_URI")
+				if err != nil {
+					return err
+				}
+			}
+
+			manifest, err := isle.FetchManifest(ctx, tunnel.HTTPClient(), siteURL, manifestPath, nid)
+			if err != nil {
+				return fmt.Errorf("unable to fetch the IIIF manifest of node %d: %v", nid, err)
+			}
+			images, err := isle.ParseManifestImages(manifest)
+			if err != nil {
+				return err
+			}
+			if len(images) == 0 {
+				return fmt.Errorf("the IIIF manifest of node %d has no images", nid)
+			}
+
+			rows := [][]string{}
+			for _, image := range images {
+				check, err := cantaloupe.CheckImage(ctx, image.Service)
+				status := fmt.Sprintf("ok (%dx%d)", check.Width, check.Height)
+				if err != nil {
+					status = err.Error()
+					failed++
+				}
+				rows = append(rows, []string{image.Canvas, image.Service, status})
+			}
+			return utils.WriteRows(os.Stdout, format, []string{"CANVAS", "IMAGE", "STATUS"}, rows)
+		})
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d images of node %d did not resolve", failed, nid)
+		}
+		return nil
+	},
+}
+
+func init() {
+	iiifCacheStatsCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	iiifCachePurgeCmd.Flags().Bool("invalid", false, "Only purge invalid cache entries")
+	iiifCachePurgeCmd.Flags().BoolP("yes", "y", false, "Purge the whole cache without asking for confirmation")
+	iiifCheckCmd.Flags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+	iiifCheckCmd.Flags().String("manifest-path", isle.IIIFManifestPath, "Path of a node's IIIF manifest, {nid} is replaced with the node ID")
+	iiifCheckCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+
+	iiifCacheCmd.AddCommand(iiifCacheStatsCmd)
+	iiifCacheCmd.AddCommand(iiifCachePurgeCmd)
+	iiifCmd.AddCommand(iiifCacheCmd)
+	iiifCmd.AddCommand(iiifCheckCmd)
+	rootCmd.AddCommand(iiifCmd)
+}
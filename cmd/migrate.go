@@ -0,0 +1,269 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Run Drupal content migrations",
+	Long: `List, import and roll back Drupal migrations with drush, and upload their source files.
+
+Examples:
+  islectl migrate upload --context prod objects.csv
+  islectl migrate status --context prod --group islandora
+  islectl migrate import --context prod --group islandora
+  islectl migrate rollback --context prod islandora_objects`,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and how many of their items are imported",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		group, err := f.GetString("group")
+		if err != nil {
+			return err
+		}
+
+		migrations, err := migrationStatus(f, c, group)
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, m := range migrations {
+			rows = append(rows, []string{m.Group, m.ID, m.Status, string(m.Total), string(m.Imported), string(m.Unprocessed), m.LastImported})
+		}
+
+		return utils.WriteRows(os.Stdout, format, []string{"GROUP", "ID", "STATUS", "TOTAL", "IMPORTED", "UNPROCESSED", "LAST IMPORTED"}, rows)
+	},
+}
+
+var migrateImportCmd = &cobra.Command{
+	Use:   "import [MIGRATION...]",
+	Short: "Import migrations, showing their progress",
+	Long: `Import migrations with drush migrate:import, showing how many of their items have been processed.
+
+Examples:
+  islectl migrate import islandora_objects islandora_media
+  islectl migrate import --group islandora --update
+  islectl migrate import --all --limit 100`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		group, all, err := migrationSelection(f)
+		if err != nil {
+			return err
+		}
+		update, err := f.GetBool("update")
+		if err != nil {
+			return err
+		}
+		limit, err := f.GetInt("limit")
+		if err != nil {
+			return err
+		}
+		feedback, err := f.GetInt("feedback")
+		if err != nil {
+			return err
+		}
+
+		extra := []string{fmt.Sprintf("--feedback=%d", feedback)}
+		if update {
+			extra = append(extra, "--update")
+		}
+		if limit > 0 {
+			extra = append(extra, fmt.Sprintf("--limit=%d", limit))
+		}
+		drushArgs, err := isle.MigrateArgs("migrate:import", args, group, all, extra...)
+		if err != nil {
+			return err
+		}
+
+		// the totals only make the progress nicer, so carry on without them
+		migrations, err := migrationStatus(f, c, group)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to count the items to import: %v\n", err)
+		}
+		tracker := isle.NewMigrateTracker(migrations, update)
+
+		err = runMigrateDrush(f, c, drushArgs, func(line string) {
+			if p, ok := tracker.Update(line); ok {
+				fmt.Println(tracker.Format(p))
+				return
+			}
+			fmt.Println(line)
+		})
+		if err != nil {
+			return fmt.Errorf("migration import failed: %v", err)
+		}
+
+		return nil
+	},
+}
+
+var migrateRollbackCmd = &cobra.Command{
+	Use:   "rollback [MIGRATION...]",
+	Short: "Roll back migrations, deleting the content they imported",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		group, all, err := migrationSelection(f)
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		drushArgs, err := isle.MigrateArgs("migrate:rollback", args, group, all)
+		if err != nil {
+			return err
+		}
+		if !yes {
+			if ok, err := config.Confirm(fmt.Sprintf("This deletes the content imported by %s on %s. Continue?", drushArgs[1], c.Name)); err != nil || !ok {
+				return err
+			}
+		}
+
+		return runMigrateDrush(f, c, drushArgs, func(line string) {
+			fmt.Println(line)
+		})
+	},
+}
+
+var migrateUploadCmd = &cobra.Command{
+	Use:   "upload FILE...",
+	Short: "Upload CSVs and other migration source files to the drupal container",
+	Long: `Upload CSVs and other migration source files to the drupal container.
+
+Directories are uploaded with their contents. Files are copied with the Docker API, so this works the same
+for local and remote contexts.
+
+Examples:
+  islectl migrate upload --context prod objects.csv media.csv
+  islectl migrate upload --context prod ./batch-42 --dest /var/www/drupal/private/batches`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		dest, err := f.GetString("dest")
+		if err != nil {
+			return err
+		}
+
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+
+		uploaded, err := cli.UploadMigrationFiles(context.Background(), c, dest, args)
+		if err != nil {
+			return err
+		}
+		for _, file := range uploaded {
+			fmt.Println(file)
+		}
+		fmt.Printf("Uploaded %d files to %s\n", len(uploaded), c.Name)
+
+		return nil
+	},
+}
+
+func migrationSelection(f *pflag.FlagSet) (string, bool, error) {
+	group, err := f.GetString("group")
+	if err != nil {
+		return "", false, err
+	}
+	all, err := f.GetBool("all")
+	if err != nil {
+		return "", false, err
+	}
+	return group, all, nil
+}
+
+func migrationStatus(f *pflag.FlagSet, c *config.Context, group string) ([]isle.Migration, error) {
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"migrate:status", "--format=json"}
+	if group != "" {
+		args = append(args, "--group="+group)
+	}
+	script := isle.DrushCommand(c, site, args...)
+	command := exec.Command("docker", isle.DrupalExecArgs(c, script)...)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return isle.ParseMigrateStatus([]byte(output))
+}
+
+func runMigrateDrush(f *pflag.FlagSet, c *config.Context, drushArgs []string, onLine func(string)) error {
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return err
+	}
+	command := exec.Command("docker", isle.DrupalExecArgs(c, isle.DrushCommand(c, site, drushArgs...))...)
+	command.Dir = c.ProjectDir
+	return c.StreamCommand(command, onLine)
+}
+
+func init() {
+	migrateCmd.PersistentFlags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+
+	migrateStatusCmd.Flags().String("group", "", "Only list migrations in this group")
+	migrateStatusCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+
+	for _, c := range []*cobra.Command{migrateImportCmd, migrateRollbackCmd} {
+		c.Flags().String("group", "", "Run the migrations in this group")
+		c.Flags().Bool("all", false, "Run all migrations")
+	}
+	migrateImportCmd.Flags().Bool("update", false, "Update previously imported items too")
+	migrateImportCmd.Flags().Int("limit", 0, "Only import this many items of each migration")
+	migrateImportCmd.Flags().Int("feedback", 50, "Report progress every this many items")
+	migrateRollbackCmd.Flags().BoolP("yes", "y", false, "Roll back without asking for confirmation")
+
+	migrateUploadCmd.Flags().String("dest", isle.MigrateUploadDir, "Directory in the drupal container to upload to")
+
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateImportCmd)
+	migrateCmd.AddCommand(migrateRollbackCmd)
+	migrateCmd.AddCommand(migrateUploadCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -3,23 +3,22 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"io"
-	"net"
 	"os"
 	"os/signal"
 	"runtime"
-	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/islandora-devops/islectl/pkg/config"
 	"github.com/islandora-devops/islectl/pkg/isle"
 	"github.com/spf13/cobra"
-	"golang.org/x/crypto/ssh"
+	"github.com/spf13/pflag"
 )
 
 var portForwardCmd = &cobra.Command{
-	Use:   "port-forward [LOCAL-PORT:SERVICE:REMOTE-PORT...]",
+	Use:   "port-forward [[LOCAL-PORT:]SERVICE[:REMOTE-PORT]...]",
 	Args:  cobra.ArbitraryArgs,
 	Short: "Forward one or more local ports to a service",
 	Long: `
@@ -43,107 +42,272 @@ http://localhost:8983/solr to see the solr admin UI
 http://localhost:8080/dashboard to see the traefik dashboard (assuming it's enabled in your config)
 http://localhost:8161/admin/queues.jsp to see ActiveMQ queues
 
+Each forward can be written in a few shorter forms:
+
+  solr                  a preset for a well-known ISLE service (see --presets), on a free local port if its own is in use
+  SERVICE:REMOTE-PORT   forward to a free local port e.g. solr:8983
+  SERVICE               forward every port the service's container exposes to free local ports
+
+Forwards you use often can be saved to the context with --save. Running islectl port-forward
+without any arguments uses the context's saved forwards.
+
+On Mac OS local contexts, where Docker Desktop container IPs are not routable from the host,
+connections are relayed through a small helper container on the project network.
+
+If the SSH connection drops or a service's container is recreated, islectl reconnects
+and looks up the service again automatically. Connection statistics for each forward are
+printed when the command exits, or periodically with --stats-interval.
+
+With --reverse the direction is flipped: containers connect to a port on the context's docker
+network gateway and the connection is forwarded back to your machine. This is useful for Xdebug
+or other local tooling. For example, to let a remote drupal container reach Xdebug on your machine:
+
+islectl port-forward --reverse 9003 --context stage
+
+For remote contexts the SSH server must allow binding to the docker network gateway
+with "GatewayPorts clientspecified" in its sshd_config.
+
 Be sure to run Ctrl+c in your terminal when you are done to close the connection.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		f := cmd.Flags()
+		listPresets, err := f.GetBool("presets")
+		if err != nil {
+			return err
+		}
+		if listPresets {
+			for _, name := range isle.PresetNames() {
+				fmt.Printf("%-12s %s\n", name, isle.Presets[name])
+			}
+			return nil
+		}
+
 		c, err := config.CurrentContext(f)
 		if err != nil {
 			return err
 		}
-		if runtime.GOOS != "linux" && c.DockerHostType == config.ContextLocal {
-			return fmt.Errorf("port-forwarding on non-linux local contexts is not currently supported")
+
+		save, err := f.GetBool("save")
+		if err != nil {
+			return err
 		}
-		cli, err := isle.GetDockerCli(c)
+		reverse, err := f.GetBool("reverse")
 		if err != nil {
 			return err
 		}
-		defer cli.Close()
+		if reverse {
+			if save {
+				return fmt.Errorf("--save is not supported with --reverse")
+			}
+			return reversePortForward(f, c, args)
+		}
+		if len(args) == 0 {
+			args = c.PortForwards
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("no port forwards passed and none are saved for the %q context", c.Name)
+		}
 
-		listeners := make([]net.Listener, 0, len(args))
+		specs := make([]isle.ForwardSpec, 0, len(args))
+		for _, arg := range args {
+			spec, err := isle.ParseForwardSpec(arg)
+			if err != nil {
+				return err
+			}
+			specs = append(specs, spec)
+		}
+		if save {
+			c.PortForwards = args
+			if err := config.SaveContext(c, false); err != nil {
+				return err
+			}
+		}
+
+		keepAlive, err := f.GetDuration("keepalive")
+		if err != nil {
+			return err
+		}
+		statsInterval, err := f.GetDuration("stats-interval")
+		if err != nil {
+			return err
+		}
+
+		tunnel, err := isle.NewTunnel(c)
+		if err != nil {
+			return err
+		}
+		defer tunnel.Close()
+
+		forwards := make([]*isle.Forward, 0, len(specs))
 		done := make(chan os.Signal, 1)
 		signal.Notify(done, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		for _, arg := range args {
-			parts := strings.Split(arg, ":")
-			if len(parts) != 3 {
-				return fmt.Errorf("invalid port forwarding spec '%s': expected format LOCAL-PORT:SERVICE:REMOTE-PORT", arg)
-			}
-			localPortStr, service, remotePortStr := parts[0], parts[1], parts[2]
-
-			localPort, err := strconv.Atoi(localPortStr)
-			if err != nil {
-				return fmt.Errorf("invalid local port '%s': must be an integer", localPortStr)
-			}
-			remotePort, err := strconv.Atoi(remotePortStr)
+		for _, spec := range specs {
+			// resolve the service up front so typos fail fast
+			serviceIp, err := tunnel.ServiceIP(ctx, spec.Service, false)
 			if err != nil {
-				return fmt.Errorf("invalid remote port '%s': must be an integer", remotePortStr)
+				return err
 			}
 
-			addr := fmt.Sprintf("localhost:%d", localPort)
-			listener, err := net.Listen("tcp", addr)
-			if err != nil {
-				return fmt.Errorf("local port %d appears to be in use: %v", localPort, err)
+			remotePorts := []int{spec.RemotePort}
+			if spec.RemotePort == 0 {
+				containerName, err := tunnel.ContainerName(spec.Service)
+				if err != nil {
+					return err
+				}
+				remotePorts, err = tunnel.Client().ExposedPorts(ctx, strings.TrimPrefix(containerName, "/"))
+				if err != nil {
+					return err
+				}
+				if len(remotePorts) == 0 {
+					return fmt.Errorf("service %q does not expose any ports. Pass SERVICE:REMOTE-PORT instead", spec.Service)
+				}
 			}
-			listeners = append(listeners, listener)
 
-			containerName, err := cli.GetContainerName(c, service, false)
-			if err != nil {
-				return err
-			}
-			serviceIp, err := cli.GetServiceIp(ctx, c, containerName)
-			if err != nil {
-				return err
+			for _, remotePort := range remotePorts {
+				listener, _, err := spec.Listen()
+				if err != nil {
+					return err
+				}
+				fwd := isle.NewForward(listener, spec.Service, remotePort)
+				forwards = append(forwards, fwd)
+
+				fmt.Printf("Forwarding localhost:%d -> %s:%d (%s:%d)\n", fwd.LocalPort, spec.Service, remotePort, serviceIp, remotePort)
+				go fwd.Serve(ctx, tunnel)
 			}
+		}
 
-			remoteEndpoint := fmt.Sprintf("%s:%d", serviceIp, remotePort)
-			go func(listener net.Listener, lp, remoteAddr string) {
-				defer listener.Close()
-				fmt.Printf("Forwarding localhost:%s -> %s via SSH\n", lp, remoteAddr)
+		if c.DockerHostType == config.ContextRemote && keepAlive > 0 {
+			go tunnel.KeepAlive(ctx, keepAlive)
+		}
+		if statsInterval > 0 {
+			go func() {
+				ticker := time.NewTicker(statsInterval)
+				defer ticker.Stop()
 				for {
-					localConn, err := listener.Accept()
-					if err != nil {
-						if strings.Contains(err.Error(), "use of closed network connection") {
-							return
-						}
-						fmt.Fprintf(os.Stderr, "error accepting connection on port %s: %v\n", lp, err)
+					select {
+					case <-ctx.Done():
 						return
+					case <-ticker.C:
+						printForwardStats(forwards)
 					}
-					go forward(cli.SshCli, localConn, remoteAddr)
 				}
-			}(listener, localPortStr, remoteEndpoint)
+			}()
 		}
 
 		<-done
 		fmt.Println("Shutting down port forwards...")
-		for _, listener := range listeners {
-			listener.Close()
+		for _, fwd := range forwards {
+			fwd.Close()
 		}
+		printForwardStats(forwards)
 		return nil
 	},
 }
 
-func forward(client *ssh.Client, localConn net.Conn, remoteAddr string) {
-	defer localConn.Close()
-	remoteConn, err := client.Dial("tcp", remoteAddr)
+func reversePortForward(f *pflag.FlagSet, c *config.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("pass at least one REMOTE-PORT[:LOCAL-HOST]:LOCAL-PORT to forward")
+	}
+	if c.DockerHostType == config.ContextLocal && runtime.GOOS != "linux" {
+		return fmt.Errorf("docker desktop containers can already reach your machine at host.docker.internal")
+	}
+	keepAlive, err := f.GetDuration("keepalive")
+	if err != nil {
+		return err
+	}
+
+	specs := make([]isle.ReverseSpec, 0, len(args))
+	for _, arg := range args {
+		spec, err := isle.ParseReverseSpec(arg)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	tunnel, err := isle.NewTunnel(c)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to dial remote address %s: %v\n", remoteAddr, err)
-		return
+		return err
 	}
-	defer remoteConn.Close()
+	defer tunnel.Close()
 
-	go func() {
-		if _, err := io.Copy(remoteConn, localConn); err != nil {
-			fmt.Fprintf(os.Stderr, "error while copying local to remote: %v\n", err)
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// bind to the project network's gateway so containers can connect to it
+	gateway, err := tunnel.Client().NetworkGateway(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	forwards := make([]*isle.ReverseForward, 0, len(specs))
+	for _, spec := range specs {
+		fwd, err := isle.NewReverseForward(tunnel, gateway, spec)
+		if err != nil {
+			if c.DockerHostType == config.ContextRemote {
+				fmt.Fprintln(os.Stderr, "Binding to the docker network requires \"GatewayPorts clientspecified\" in the remote host's sshd_config")
+			}
+			return err
 		}
-	}()
-	if _, err := io.Copy(localConn, remoteConn); err != nil {
-		fmt.Fprintf(os.Stderr, "error while copying remote to local: %v\n", err)
+		forwards = append(forwards, fwd)
+		fmt.Printf("Forwarding %s (in the docker network) -> %s:%d\n", fwd.RemoteAddr, spec.LocalHost, spec.LocalPort)
+		go fwd.Serve(ctx, tunnel)
+	}
+	fmt.Printf("\nContainers can reach your machine at %s e.g. xdebug.client_host=%s\n", gateway, gateway)
+
+	if c.DockerHostType == config.ContextRemote && keepAlive > 0 {
+		go tunnel.KeepAlive(ctx, keepAlive)
+	}
+
+	<-done
+	fmt.Println("Shutting down reverse port forwards...")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REMOTE\tLOCAL\tCONNECTIONS\tFAILURES\tRECEIVED\tSENT")
+	for _, fwd := range forwards {
+		fwd.Close()
+		fmt.Fprintf(w, "%s\t%s:%d\t%d\t%d\t%d\t%d\n",
+			fwd.RemoteAddr,
+			fwd.Spec.LocalHost,
+			fwd.Spec.LocalPort,
+			fwd.Stats.Connections.Load(),
+			fwd.Stats.Failures.Load(),
+			fwd.Stats.BytesIn.Load(),
+			fwd.Stats.BytesOut.Load(),
+		)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func printForwardStats(forwards []*isle.Forward) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCAL\tSERVICE\tCONNECTIONS\tACTIVE\tFAILURES\tSENT\tRECEIVED")
+	for _, fwd := range forwards {
+		fmt.Fprintf(w, "%d\t%s:%d\t%d\t%d\t%d\t%d\t%d\n",
+			fwd.LocalPort,
+			fwd.Service,
+			fwd.RemotePort,
+			fwd.Stats.Connections.Load(),
+			fwd.Stats.Active.Load(),
+			fwd.Stats.Failures.Load(),
+			fwd.Stats.BytesOut.Load(),
+			fwd.Stats.BytesIn.Load(),
+		)
 	}
+	w.Flush()
 }
 
 func init() {
+	portForwardCmd.Flags().Bool("save", false, "Save the passed port forwards to the context so they are used when no arguments are passed")
+	portForwardCmd.Flags().Bool("reverse", false, "Forward ports on the context's docker network back to this machine. Arguments are REMOTE-PORT[:LOCAL-HOST]:LOCAL-PORT")
+	portForwardCmd.Flags().Bool("presets", false, "List the preset services that can be forwarded by name")
+	portForwardCmd.Flags().Duration("keepalive", 30*time.Second, "How often to check the SSH connection for remote contexts. Set to 0 to disable")
+	portForwardCmd.Flags().Duration("stats-interval", 0, "How often to print connection statistics for each forward. Statistics are always printed on exit")
 	rootCmd.AddCommand(portForwardCmd)
 }
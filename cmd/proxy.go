@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Start a local SOCKS5 proxy into the context's docker network",
+	Long: `Start a local SOCKS5 proxy (and optionally an HTTP proxy) into the context's docker network.
+
+Rather than forwarding one port at a time, the proxy lets any application that supports SOCKS5 or
+HTTP proxies reach every service in the context by its docker compose service name. For remote
+contexts connections are tunnelled through SSH.
+
+Hostnames that are a docker compose service (solr, fcrepo, mariadb, activemq...), bare or followed by the
+project name (solr.<project>), are resolved to the service's container IP on the <project>_default network.
+Other hostnames, like solr.example.org, are connected to from the docker host.
+
+Examples:
+  islectl proxy --context stage
+  curl --socks5-hostname localhost:1080 http://solr:8983/solr/admin/cores
+
+  # also start an HTTP proxy for tools without SOCKS support
+  islectl proxy --http-port 3128 --context stage
+  curl --proxy http://localhost:3128 http://fcrepo:8080/fcrepo/rest
+
+To browse with Firefox set Settings > Network Settings > Manual proxy configuration > SOCKS Host
+to localhost and port 1080, select SOCKS v5 and check "Proxy DNS when using SOCKS v5".
+
+Be sure to run Ctrl+c in your terminal when you are done to close the proxy.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+
+		port, err := f.GetInt("port")
+		if err != nil {
+			return err
+		}
+		httpPort, err := f.GetInt("http-port")
+		if err != nil {
+			return err
+		}
+		keepAlive, err := f.GetDuration("keepalive")
+		if err != nil {
+			return err
+		}
+
+		tunnel, err := isle.NewTunnel(c)
+		if err != nil {
+			return err
+		}
+		defer tunnel.Close()
+
+		done := make(chan os.Signal, 1)
+		signal.Notify(done, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		proxy := &isle.Proxy{Tunnel: tunnel}
+		socksListener, socksPort, err := isle.ListenLocal(port)
+		if err != nil {
+			return err
+		}
+		defer socksListener.Close()
+		fmt.Printf("SOCKS5 proxy listening on localhost:%d\n", socksPort)
+		go func() {
+			if err := proxy.ServeSOCKS5(ctx, socksListener); err != nil {
+				slog.Error("SOCKS5 proxy stopped", "err", err)
+			}
+		}()
+
+		if f.Changed("http-port") {
+			httpListener, p, err := isle.ListenLocal(httpPort)
+			if err != nil {
+				return err
+			}
+			defer httpListener.Close()
+			fmt.Printf("HTTP proxy listening on localhost:%d\n", p)
+			go func() {
+				if err := proxy.ServeHTTPProxy(ctx, httpListener); err != nil {
+					slog.Error("HTTP proxy stopped", "err", err)
+				}
+			}()
+		}
+
+		if c.DockerHostType == config.ContextRemote && keepAlive > 0 {
+			go tunnel.KeepAlive(ctx, keepAlive)
+		}
+
+		<-done
+		fmt.Println("Shutting down proxy...")
+		return nil
+	},
+}
+
+func init() {
+	proxyCmd.Flags().Int("port", 1080, "Local port for the SOCKS5 proxy. Use 0 to pick a free port")
+	proxyCmd.Flags().Int("http-port", 0, "Also start an HTTP proxy on this local port. Use 0 to pick a free port")
+	proxyCmd.Flags().Duration("keepalive", 30*time.Second, "How often to check the SSH connection for remote contexts. Set to 0 to disable")
+	rootCmd.AddCommand(proxyCmd)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var queuesCmd = &cobra.Command{
+	Use:   "queues",
+	Short: "Inspect and manage the ActiveMQ queues behind derivatives and indexing",
+	Long: `Inspect and manage the ActiveMQ queues Islandora uses for derivatives and indexing.
+
+The activemq service is reached through the context's SSH connection, like port-forward.
+A queue with pending messages but no consumers is marked as stalled, which usually means
+the microservice that generates those derivatives is down.
+
+Examples:
+  islectl queues --context prod
+  islectl queues watch --context prod
+  islectl queues redrive --context prod
+  islectl queues purge islandora-connector-houdini --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listQueues(cmd.Flags())
+	},
+}
+
+var queuesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queues with their pending, consumer, enqueued and dequeued counts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listQueues(cmd.Flags())
+	},
+}
+
+var queuesWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the queues in a live refreshing view",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		interval, err := f.GetDuration("interval")
+		if err != nil {
+			return err
+		}
+		all, err := f.GetBool("all")
+		if err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be greater than zero, got %s", interval)
+		}
+
+		return withActiveMQ(c, func(ctx context.Context, amq *isle.ActiveMQ) error {
+			previous := map[string]isle.Queue{}
+			var previousAt time.Time
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				// the tunnel's HTTP client has no timeout, don't let a stalled link freeze the view
+				requestCtx, cancel := context.WithTimeout(ctx, queuesRequestTimeout)
+				queues, err := amq.Queues(requestCtx)
+				cancel()
+				if ctx.Err() != nil {
+					return nil
+				}
+				now := time.Now()
+
+				var view bytes.Buffer
+				fmt.Fprintf(&view, "%s queues at %s, refreshing every %s. Press Ctrl+c to stop\n\n", c.Name, now.Format("15:04:05"), interval)
+				if err != nil {
+					fmt.Fprintf(&view, "%v\n", err)
+				} else {
+					rows := [][]string{}
+					for _, q := range filterQueues(queues, all) {
+						rate := ""
+						if prev, ok := previous[q.Name]; ok && !previousAt.IsZero() {
+							perMinute := float64(q.Dequeued-prev.Dequeued) / now.Sub(previousAt).Minutes()
+							rate = fmt.Sprintf("%.0f/min", perMinute)
+						}
+						rows = append(rows, append(queueRow(q), rate))
+						previous[q.Name] = q
+					}
+					previousAt = now
+					if err := utils.WriteRows(&view, "table", append(queueHeader(), "DEQUEUE RATE"), rows); err != nil {
+						return err
+					}
+				}
+				// clear the screen and redraw from the top
+				fmt.Print("\033[H\033[2J" + view.String())
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		})
+	},
+}
+
+var queuesPurgeCmd = &cobra.Command{
+	Use:   "purge QUEUE",
+	Short: "Delete every pending message in a queue",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+		if !yes {
+			if ok, err := config.Confirm(fmt.Sprintf("Delete every pending message in %s on %s?", args[0], c.Name)); err != nil || !ok {
+				return err
+			}
+		}
+
+		return withActiveMQ(c, func(ctx context.Context, amq *isle.ActiveMQ) error {
+			purged, err := amq.Purge(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Purged %d messages from %s\n", purged, args[0])
+			return nil
+		})
+	},
+}
+
+var queuesRedriveCmd = &cobra.Command{
+	Use:   "redrive [DLQ]",
+	Short: "Send the messages in a dead letter queue back to the queues they failed on",
+	Long: `Send the messages in a dead letter queue back to the queues they failed on, e.g. once the
+microservice that failed to process them is fixed. Defaults to ` + isle.DeadLetterQueue + `.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		name := isle.DeadLetterQueue
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		return withActiveMQ(c, func(ctx context.Context, amq *isle.ActiveMQ) error {
+			retried, err := amq.Redrive(ctx, name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Sent %d messages from %s back to their queues\n", retried, name)
+			return nil
+		})
+	},
+}
+
+func listQueues(f *pflag.FlagSet) error {
+	c, err := config.CurrentContext(f)
+	if err != nil {
+		return err
+	}
+	all, err := f.GetBool("all")
+	if err != nil {
+		return err
+	}
+	format, err := f.GetString("format")
+	if err != nil {
+		return err
+	}
+
+	return withActiveMQ(c, func(ctx context.Context, amq *isle.ActiveMQ) error {
+		queues, err := amq.Queues(ctx)
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, q := range filterQueues(queues, all) {
+			rows = append(rows, queueRow(q))
+		}
+		return utils.WriteRows(os.Stdout, format, queueHeader(), rows)
+	})
+}
+
+// queuesRequestTimeout bounds each refresh of queues watch.
+const queuesRequestTimeout = 10 * time.Second
+
+func filterQueues(queues []isle.Queue, all bool) []isle.Queue {
+	if all {
+		return queues
+	}
+	filtered := []isle.Queue{}
+	for _, q := range queues {
+		if isle.IsIslandoraQueue(q.Name) {
+			filtered = append(filtered, q)
+		}
+	}
+	return filtered
+}
+
+func queueHeader() []string {
+	return []string{"QUEUE", "PENDING", "CONSUMERS", "ENQUEUED", "DEQUEUED", "STATUS"}
+}
+
+func queueRow(q isle.Queue) []string {
+	status := "ok"
+	if q.Stalled() {
+		status = "stalled"
+	}
+	return []string{
+		q.Name,
+		fmt.Sprint(q.Pending),
+		fmt.Sprint(q.Consumers),
+		fmt.Sprint(q.Enqueued),
+		fmt.Sprint(q.Dequeued),
+		status,
+	}
+}
+
+func init() {
+	for _, c := range []*cobra.Command{queuesCmd, queuesListCmd} {
+		c.Flags().Bool("all", false, "Include queues not used by Islandora")
+		c.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	}
+	queuesWatchCmd.Flags().Bool("all", false, "Include queues not used by Islandora")
+	queuesWatchCmd.Flags().Duration("interval", 2*time.Second, "How often to refresh")
+	queuesPurgeCmd.Flags().BoolP("yes", "y", false, "Purge without asking for confirmation")
+
+	queuesCmd.AddCommand(queuesListCmd)
+	queuesCmd.AddCommand(queuesWatchCmd)
+	queuesCmd.AddCommand(queuesPurgeCmd)
+	queuesCmd.AddCommand(queuesRedriveCmd)
+	rootCmd.AddCommand(queuesCmd)
+}
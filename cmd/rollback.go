@@ -0,0 +1,160 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Roll a context back to the snapshot taken before a deploy",
+	Long: `Roll a context back to the snapshot taken before a deploy.
+
+The services are restarted on the exact images they ran when the snapshot was taken, even if the
+tags in your compose file now point at newer images, and the database is restored from the snapshot's dump.
+
+The services stay pinned until they are recreated without the snapshot, e.g. by the next islectl deploy.
+
+Examples:
+  islectl rollback --context prod                     # roll back to the latest snapshot
+  islectl rollback --context prod --snapshot 20251018-091500
+  islectl rollback list --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		id, err := f.GetString("snapshot")
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		s, err := isle.FindSnapshot(c, id)
+		if err != nil {
+			return err
+		}
+		site, err := isle.FindSite(c, s.Site)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Rolling %s back to snapshot %s taken %s:\n", c.Name, s.ID, s.Created.Local().Format("2006-01-02 15:04:05"))
+		services := make([]string, 0, len(s.Services))
+		for service := range s.Services {
+			services = append(services, service)
+		}
+		slices.Sort(services)
+		for _, service := range services {
+			fmt.Printf("  %s: %s (%s)\n", service, s.Services[service].Image, shortImageID(s.Services[service].ID))
+		}
+		fmt.Printf("  %s database: %s\n", site.Name, s.DatabaseDump(c))
+
+		if !yes {
+			if ok, err := config.Confirm(fmt.Sprintf("This replaces the %s database on %s. Continue?", site.Name, c.Name)); err != nil || !ok {
+				return err
+			}
+		}
+
+		for _, args := range isle.RollbackCommands(c, s, site) {
+			command := exec.Command(args[0], args[1:]...)
+			command.Dir = c.ProjectDir
+			if _, err := c.RunCommand(command); err != nil {
+				return fmt.Errorf("rollback to %s failed: %v", s.ID, err)
+			}
+		}
+		fmt.Printf("Rolled %s back to snapshot %s\n", c.Name, s.ID)
+
+		return nil
+	},
+}
+
+var rollbackListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots a context can be rolled back to",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := isle.ListSnapshots(c)
+		if err != nil {
+			return err
+		}
+		rows := [][]string{}
+		for _, s := range snapshots {
+			rows = append(rows, []string{
+				s.ID,
+				s.Created.Local().Format("2006-01-02 15:04:05"),
+				s.Site,
+				fmt.Sprint(len(s.Services)),
+			})
+		}
+
+		return utils.WriteRows(os.Stdout, format, []string{"ID", "CREATED", "SITE", "SERVICES"}, rows)
+	},
+}
+
+var rollbackSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record a snapshot to roll back to without deploying",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		keep, err := f.GetInt("keep-snapshots")
+		if err != nil {
+			return err
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		return takeSnapshot(c, site, keep)
+	},
+}
+
+// shortImageID trims an image ID to the 12 characters docker shows
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func init() {
+	rollbackCmd.Flags().String("snapshot", "", "ID of the snapshot to roll back to. Defaults to the latest")
+	rollbackCmd.Flags().BoolP("yes", "y", false, "Roll back without asking for confirmation")
+	rollbackListCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	rollbackSnapshotCmd.Flags().String("site", "", "Drupal multisite to dump. Defaults to the context's site")
+	rollbackSnapshotCmd.Flags().Int("keep-snapshots", 5, "Number of snapshots to keep")
+
+	rollbackCmd.AddCommand(rollbackListCmd)
+	rollbackCmd.AddCommand(rollbackSnapshotCmd)
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -0,0 +1,447 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the docker compose secrets of your ISLE site",
+	Long: `Manage the docker compose secrets of your ISLE site.
+
+Secrets are the files in the secrets directory of an isle-site-template project.
+For remote contexts they are read and written over SSH.`,
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List secrets and the services that use them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		names, err := isle.SecretNames(c)
+		if err != nil {
+			return err
+		}
+
+		// services are informational, so still list the secrets when docker isn't reachable
+		cli, err := isle.GetDockerCli(c)
+		if err != nil {
+			slog.Warn("Unable to connect to docker, not listing services", "err", err)
+		} else {
+			defer cli.Close()
+		}
+
+		rows := make([][]string, 0, len(names))
+		for _, name := range names {
+			services := []string{}
+			if cli != nil {
+				services, err = cli.ServicesUsingSecret(context.Background(), c, name)
+				if err != nil {
+					return err
+				}
+			}
+			rows = append(rows, []string{name, strings.Join(services, ",")})
+		}
+
+		return utils.WriteRows(os.Stdout, format, []string{"NAME", "SERVICES"}, rows)
+	},
+}
+
+var secretsGetCmd = &cobra.Command{
+	Use:   "get NAME",
+	Args:  cobra.ExactArgs(1),
+	Short: "Print the value of a secret",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := config.CurrentContext(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		value, err := isle.ReadSecret(c, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.TrimRight(value, "\n"))
+
+		return nil
+	},
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Set the value of a secret",
+	Long: `Set the value of a secret.
+
+If VALUE is not passed it is read from stdin, which keeps it out of your shell history.
+
+When the context reads the secret from its vault, the vault is updated too. Secrets read from
+other secret backends are refused, change them in the backend instead.
+
+Examples:
+  islectl secrets set ACTIVEMQ_PASSWORD < activemq-password.txt
+  pass show islandora/prod/solr | islectl secrets set SOLR_PASSWORD --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := config.CurrentContext(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			input, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("unable to read secret from stdin: %v", err)
+			}
+			value = strings.TrimRight(string(input), "\r\n")
+		}
+		if value == "" {
+			return fmt.Errorf("refusing to set %s to an empty value", args[0])
+		}
+
+		if err := isle.WriteSecret(c, args[0], value); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s\n", args[0])
+
+		return printSecretFollowUp(c, []string{args[0]})
+	},
+}
+
+var secretsRotateCmd = &cobra.Command{
+	Use:   "rotate NAME...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Replace secrets with newly generated values",
+	Long: `Replace secrets with newly generated values.
+
+Passwords are replaced with 32 random alphanumeric characters, secrets ending in _SALT with
+a new Drupal hash salt, and rotating JWT_PRIVATE_KEY or JWT_PUBLIC_KEY generates a new key pair.
+
+Database passwords (DB_ROOT_PASSWORD and *_DB_PASSWORD) are also stored in the database, so the
+services can't connect after only the file changes. They are refused unless --force is passed, after
+which change the database user's password to the new value before restarting.
+
+Examples:
+  islectl secrets rotate ACTIVEMQ_PASSWORD ACTIVEMQ_WEB_ADMIN_PASSWORD
+  islectl secrets rotate JWT_PRIVATE_KEY --context prod`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		force, err := f.GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		names, err := isle.SecretNames(c)
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("secret %q not found in %s", name, isle.SecretsDir(c))
+			}
+			if isle.StoredInDatabase(name) && !force {
+				return fmt.Errorf("%s is also stored in the database and rotating only the file locks the services out of it. Pass --force if you will change the database user's password yourself", name)
+			}
+		}
+
+		// generate every value first so a secret held by a read-only backend stops the rotation before anything changes
+		rotated := map[string]string{}
+		for _, name := range args {
+			if _, ok := rotated[name]; ok {
+				continue
+			}
+			values, err := isle.GenerateSecret(name)
+			if err != nil {
+				return err
+			}
+			maps.Copy(rotated, values)
+		}
+		updated := slices.Sorted(maps.Keys(rotated))
+		if err := isle.CheckSecretsWritable(c, updated...); err != nil {
+			return err
+		}
+		for _, secret := range updated {
+			if err := isle.WriteSecret(c, secret, rotated[secret]); err != nil {
+				return err
+			}
+			fmt.Printf("Rotated %s\n", secret)
+		}
+
+		return printSecretFollowUp(c, updated)
+	},
+}
+
+var secretsDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show which secrets differ from another context",
+	Long: `Compare the secrets of the current context with another context's, without printing their values.
+
+Useful to check a new environment doesn't reuse production's passwords, or that secrets which must
+match, like a shared JWT key pair, do.
+
+Examples:
+  islectl secrets diff --source prod --context stage`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		sourceName, err := f.GetString("source")
+		if err != nil {
+			return err
+		}
+		if sourceName == "" {
+			return fmt.Errorf("pass the context to compare with as --source")
+		}
+		exists, err := config.ContextExists(sourceName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("context %q does not exist", sourceName)
+		}
+		source, err := config.GetContext(sourceName)
+		if err != nil {
+			return err
+		}
+
+		sourceSecrets, err := isle.ReadSecrets(&source)
+		if err != nil {
+			return err
+		}
+		secrets, err := isle.ReadSecrets(c)
+		if err != nil {
+			return err
+		}
+
+		diff := isle.DiffSecrets(sourceSecrets, secrets)
+		rows := [][]string{}
+		for _, name := range diff.Added {
+			rows = append(rows, []string{name, "only in " + source.Name})
+		}
+		for _, name := range diff.Deleted {
+			rows = append(rows, []string{name, "only in " + c.Name})
+		}
+		for _, name := range diff.Changed {
+			rows = append(rows, []string{name, "differs"})
+		}
+		slices.SortFunc(rows, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+
+		identical := len(sourceSecrets) - len(diff.Added) - len(diff.Changed)
+		if len(rows) == 0 {
+			fmt.Printf("The %d secrets of %s and %s are identical\n", identical, source.Name, c.Name)
+			return nil
+		}
+		if err := utils.WriteRows(os.Stdout, format, []string{"NAME", "STATUS"}, rows); err != nil {
+			return err
+		}
+		if format == "table" {
+			fmt.Printf("\n%d identical\n", identical)
+		}
+		return nil
+	},
+}
+
+var secretsVaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Manage an encrypted vault secret backend",
+	Long: `Manage an encrypted vault secret backend.
+
+A vault is a file on this machine with secrets encrypted by a passphrase. Add it to a context with
+  islectl config set-context prod --secret-backends vault:~/.islectl/prod.vault
+and islectl reads secrets from it instead of the project's secrets directory.
+
+The passphrase is read from ` + config.VaultPassphraseEnv + ` or prompted for.
+
+Examples:
+  islectl secrets get DB_ROOT_PASSWORD --context prod | islectl secrets vault set DB_ROOT_PASSWORD --context prod
+  islectl secrets vault list --vault ~/.islectl/prod.vault`,
+}
+
+var secretsVaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the secrets in a vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		for _, name := range vault.Names() {
+			fmt.Println(name)
+		}
+		return nil
+	},
+}
+
+var secretsVaultSetCmd = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Add or update a secret in a vault. VALUE is read from stdin when not passed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		value := ""
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			input, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("unable to read secret from stdin: %v", err)
+			}
+			value = strings.TrimRight(string(input), "\r\n")
+		}
+		if value == "" {
+			return fmt.Errorf("refusing to set %s to an empty value", args[0])
+		}
+
+		vault.Secrets[args[0]] = value
+		if err := vault.Save(); err != nil {
+			return fmt.Errorf("unable to save vault: %v", err)
+		}
+		fmt.Printf("Saved %s to %s\n", args[0], vault.Path)
+
+		return nil
+	},
+}
+
+var secretsVaultRemoveCmd = &cobra.Command{
+	Use:   "remove NAME...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Remove secrets from a vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			if _, ok := vault.Secrets[name]; !ok {
+				return fmt.Errorf("secret %q is not in %s", name, vault.Path)
+			}
+			delete(vault.Secrets, name)
+		}
+
+		return vault.Save()
+	},
+}
+
+// openVault opens the vault from --vault, or the current context's vault backend.
+func openVault(f *pflag.FlagSet) (*config.Vault, error) {
+	path, err := f.GetString("vault")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return nil, err
+		}
+		path = c.VaultPath()
+		if path == "" {
+			return nil, fmt.Errorf("context %q does not have a vault secret backend. Pass --vault or add one with --secret-backends vault:PATH", c.Name)
+		}
+	}
+
+	passphrase, err := config.VaultPassphrase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.OpenVault(path, passphrase)
+}
+
+// printSecretFollowUp tells the user what is needed for changed secrets to take effect.
+func printSecretFollowUp(c *config.Context, secrets []string) error {
+	for _, name := range secrets {
+		if warning := isle.RotationWarning(name); warning != "" {
+			fmt.Fprintf(os.Stderr, "\nWARNING: %s\n", warning)
+		}
+	}
+
+	cli, err := isle.GetDockerCli(c)
+	if err != nil {
+		slog.Warn("Unable to connect to docker to find the services using the secrets", "err", err)
+		return nil
+	}
+	defer cli.Close()
+
+	restart := []string{}
+	for _, name := range secrets {
+		services, err := cli.ServicesUsingSecret(context.Background(), c, name)
+		if err != nil {
+			return err
+		}
+		for _, service := range services {
+			if !slices.Contains(restart, service) {
+				restart = append(restart, service)
+			}
+		}
+	}
+	if len(restart) == 0 {
+		return nil
+	}
+	slices.Sort(restart)
+
+	fmt.Println("\nRestart these services for the change to take effect:")
+	fmt.Printf("  islectl compose --context %s restart %s\n", c.Name, strings.Join(restart, " "))
+
+	return nil
+}
+
+func init() {
+	secretsListCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	secretsRotateCmd.Flags().Bool("force", false, "Rotate database passwords, which you must then change in the database")
+	secretsDiffCmd.Flags().String("source", "", "Context to compare the current context's secrets with")
+	secretsDiffCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+
+	secretsVaultCmd.PersistentFlags().String("vault", "", "Path to the vault file. Defaults to the context's vault secret backend")
+	secretsVaultCmd.AddCommand(secretsVaultListCmd)
+	secretsVaultCmd.AddCommand(secretsVaultSetCmd)
+	secretsVaultCmd.AddCommand(secretsVaultRemoveCmd)
+
+	secretsCmd.AddCommand(secretsListCmd)
+	secretsCmd.AddCommand(secretsGetCmd)
+	secretsCmd.AddCommand(secretsSetCmd)
+	secretsCmd.AddCommand(secretsRotateCmd)
+	secretsCmd.AddCommand(secretsDiffCmd)
+	secretsCmd.AddCommand(secretsVaultCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var sitesCmd = &cobra.Command{
+	Use:   "sites",
+	Short: "Work with Drupal multisites",
+}
+
+var sitesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the Drupal multisites of a context",
+	Long: `List the Drupal multisites of a context.
+
+Sites are discovered from the site directories with a settings.php and the hosts mapped in sites.php.
+The URI is what drush is passed as --uri for the site. Set it per site with
+  islectl config set-context CONTEXT --uri SITE=URL
+Any of the listed names can be passed to --site on drush, drupal and db commands.
+
+Examples:
+  islectl sites list
+  islectl sites list --context prod --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+
+		sites, err := isle.ListSites(c)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(sites))
+		for _, site := range sites {
+			uri := site.URI(c)
+			if uri == "" {
+				uri = "$DRUPAL_DRUSH_URI"
+			}
+			rows = append(rows, []string{site.Name, strings.Join(site.Hosts, ","), uri, site.Database()})
+		}
+
+		return utils.WriteRows(os.Stdout, format, []string{"NAME", "HOSTS", "URI", "DATABASE"}, rows)
+	},
+}
+
+func init() {
+	sitesListCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+
+	sitesCmd.AddCommand(sitesListCmd)
+	rootCmd.AddCommand(sitesCmd)
+}
@@ -0,0 +1,319 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var solrCmd = &cobra.Command{
+	Use:   "solr",
+	Short: "Check and manage the Solr search index",
+	Long: `Check and manage the Solr search index.
+
+The solr service is reached over the context's internal network, so the admin UI doesn't need to be
+port forwarded to check index health.
+
+Examples:
+  islectl solr status --context prod
+  islectl solr reindex --context prod
+  islectl solr optimize --context prod
+  islectl solr backup --context prod --download ./backups`,
+}
+
+var solrStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Compare the documents in Solr with Drupal's search_api tracker",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		if err := utils.ValidateFormat(format); err != nil {
+			return err
+		}
+
+		indexes, err := searchAPIIndexes(f, c)
+		if err != nil {
+			return err
+		}
+		var cores []isle.SolrCore
+		err = withSolr(c, func(ctx context.Context, solr *isle.Solr, tunnel *isle.Tunnel) error {
+			cores, err = solr.Cores(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		return utils.WriteRows(os.Stdout, format, isle.SolrStatusHeader, isle.SolrStatusRows(indexes, cores))
+	},
+}
+
+var solrReindexCmd = &cobra.Command{
+	Use:   "reindex [INDEX]",
+	Short: "Queue every item for reindexing and index them, showing progress",
+	Long: `Queue every item of a search_api index, or all indexes, for reindexing with drush search-api:reindex
+and index them with drush search-api:index, showing how many items have been indexed.
+
+Pass --clear to delete the indexed documents first, e.g. after changing the Solr schema.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		clearIndex, err := f.GetBool("clear")
+		if err != nil {
+			return err
+		}
+		batchSize, err := f.GetInt("batch-size")
+		if err != nil {
+			return err
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		queue := []string{"search-api:reindex"}
+		if clearIndex {
+			queue = []string{"search-api:clear"}
+		}
+		queue = append(queue, args...)
+		command := exec.Command("docker", isle.DrupalExecArgs(c, isle.DrushCommand(c, site, queue...))...)
+		command.Dir = c.ProjectDir
+		if _, err := c.RunCommand(command); err != nil {
+			return err
+		}
+
+		indexes, err := searchAPIIndexes(f, c)
+		if err != nil {
+			return err
+		}
+		total := 0
+		for _, index := range indexes {
+			if len(args) == 0 || index.ID == args[0] {
+				total += index.Total.Int()
+			}
+		}
+
+		index := append([]string{"search-api:index"}, args...)
+		index = append(index, fmt.Sprintf("--batch-size=%d", batchSize))
+		command = exec.Command("docker", isle.DrupalExecArgs(c, isle.DrushCommand(c, site, index...))...)
+		command.Dir = c.ProjectDir
+		err = c.StreamCommand(command, func(line string) {
+			indexed, ok := isle.ParseIndexProgress(line)
+			if !ok {
+				fmt.Println(line)
+				return
+			}
+			if total > 0 {
+				fmt.Printf("Indexed %d/%d items (%d%%)\n", indexed, total, min(100, indexed*100/total))
+				return
+			}
+			fmt.Printf("Indexed %d items\n", indexed)
+		})
+		if err != nil {
+			return fmt.Errorf("indexing failed: %v", err)
+		}
+
+		return nil
+	},
+}
+
+var solrOptimizeCmd = &cobra.Command{
+	Use:   "optimize [CORE...]",
+	Short: "Merge the index segments of Solr cores",
+	Long: `Merge the index segments of Solr cores, or all cores, to reclaim the space of deleted documents.
+This can take a long time and a lot of disk space on big indexes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := config.CurrentContext(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		return withSolr(c, func(ctx context.Context, solr *isle.Solr, tunnel *isle.Tunnel) error {
+			cores, err := selectCores(ctx, solr, args)
+			if err != nil {
+				return err
+			}
+			for _, core := range cores {
+				fmt.Printf("Optimizing %s...\n", core.Name)
+				start := time.Now()
+				if err := solr.Optimize(ctx, core.Name); err != nil {
+					return fmt.Errorf("unable to optimize %s: %v", core.Name, err)
+				}
+				fmt.Printf("Optimized %s in %s\n", core.Name, time.Since(start).Round(time.Second))
+			}
+			return nil
+		})
+	},
+}
+
+var solrBackupCmd = &cobra.Command{
+	Use:   "backup [CORE...]",
+	Short: "Back up Solr cores",
+	Long: `Back up Solr cores, or all cores, with the replication handler.
+
+The backups are written to each core's data directory in the solr container. Pass --download to
+also save them to a local directory as tar archives. Waiting for a core's backup gives up after
+--timeout, or on Ctrl+c.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		name, err := f.GetString("name")
+		if err != nil {
+			return err
+		}
+		download, err := f.GetString("download")
+		if err != nil {
+			return err
+		}
+		timeout, err := f.GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be greater than zero, got %s", timeout)
+		}
+		if name == "" {
+			name = "islectl-" + time.Now().Format("20060102-150405")
+		}
+
+		return withSolr(c, func(ctx context.Context, solr *isle.Solr, tunnel *isle.Tunnel) error {
+			cores, err := selectCores(ctx, solr, args)
+			if err != nil {
+				return err
+			}
+			for _, core := range cores {
+				fmt.Printf("Backing up %s...\n", core.Name)
+				backupCtx, cancel := context.WithTimeout(ctx, timeout)
+				dir, err := solr.Backup(backupCtx, core, name, time.Second)
+				cancel()
+				if errors.Is(err, context.DeadlineExceeded) {
+					return fmt.Errorf("backup of %s did not finish within %s, it may still be running in solr", core.Name, timeout)
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Backed up %s to %s\n", core.Name, dir)
+
+				if download == "" {
+					continue
+				}
+				file := filepath.Join(download, fmt.Sprintf("solr-%s-%s.tar", core.Name, name))
+				if err := downloadFromService(ctx, tunnel, "solr", dir, file); err != nil {
+					return err
+				}
+				fmt.Printf("Downloaded %s\n", file)
+			}
+			return nil
+		})
+	},
+}
+
+func selectCores(ctx context.Context, solr *isle.Solr, names []string) ([]isle.SolrCore, error) {
+	cores, err := solr.Cores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return cores, nil
+	}
+	selected := []isle.SolrCore{}
+	for _, name := range names {
+		found := false
+		for _, core := range cores {
+			if core.Name == name {
+				selected = append(selected, core)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("solr core %q not found", name)
+		}
+	}
+	return selected, nil
+}
+
+// downloadFromService saves a path in a service's container to a local tar archive
+func downloadFromService(ctx context.Context, tunnel *isle.Tunnel, service, src, dest string) error {
+	containerName, err := tunnel.ContainerName(service)
+	if err != nil {
+		return err
+	}
+	archive, _, err := tunnel.Client().CLI.CopyFromContainer(ctx, strings.TrimPrefix(containerName, "/"), src)
+	if err != nil {
+		return fmt.Errorf("unable to copy %s from %s: %v", path.Base(src), service, err)
+	}
+	defer archive.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, archive); err != nil {
+		return fmt.Errorf("unable to write %s: %v", dest, err)
+	}
+	return out.Close()
+}
+
+func searchAPIIndexes(f *pflag.FlagSet, c *config.Context) ([]isle.SearchAPIIndex, error) {
+	site, err := isle.CurrentSite(f, c)
+	if err != nil {
+		return nil, err
+	}
+	command := exec.Command("docker", isle.DrupalExecArgs(c, isle.DrushCommand(c, site, "php:eval", isle.SearchAPIStatusScript))...)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+	return isle.ParseSearchAPIIndexes(output)
+}
+
+func init() {
+	solrCmd.PersistentFlags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+	solrStatusCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	solrReindexCmd.Flags().Bool("clear", false, "Delete the indexed documents before reindexing")
+	solrReindexCmd.Flags().Int("batch-size", 100, "Items to index per batch")
+	solrBackupCmd.Flags().String("name", "", "Name of the backup. Defaults to islectl-TIMESTAMP")
+	solrBackupCmd.Flags().String("download", "", "Local directory to download the backups to")
+	solrBackupCmd.Flags().Duration("timeout", time.Hour, "How long to wait for each core's backup to finish")
+
+	solrCmd.AddCommand(solrStatusCmd)
+	solrCmd.AddCommand(solrReindexCmd)
+	solrCmd.AddCommand(solrOptimizeCmd)
+	solrCmd.AddCommand(solrBackupCmd)
+	rootCmd.AddCommand(solrCmd)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright © 2025 Islandora Foundation
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/islandora-devops/islectl/pkg/isle"
+	"github.com/spf13/cobra"
+)
+
+var triplestoreCmd = &cobra.Command{
+	Use:   "triplestore",
+	Short: "Query and reindex the blazegraph triplestore",
+	Long: `Query and reindex the blazegraph triplestore.
+
+The triplestore service is reached through the context's SSH connection, like port-forward,
+so the SPARQL endpoint doesn't need to be exposed.
+
+Examples:
+  islectl triplestore count --context prod
+  islectl triplestore query "SELECT * WHERE { ?s ?p ?o } LIMIT 10" --context prod
+  islectl triplestore query --file ./orphans.rq --format csv > orphans.csv
+  islectl triplestore reindex --context prod`,
+}
+
+var triplestoreQueryCmd = &cobra.Command{
+	Use:   "query [SPARQL]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Run a SPARQL query",
+	Long: `Run a SPARQL query or a SPARQL file against the triplestore.
+
+SELECT results are rendered as a table, CSV, or JSON. CONSTRUCT and DESCRIBE results are printed as N-Triples.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		file, err := f.GetString("file")
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		if (len(args) == 0) == (file == "") {
+			return fmt.Errorf("pass either a SPARQL query or --file")
+		}
+		query := ""
+		if len(args) == 1 {
+			query = args[0]
+		} else {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("unable to read SPARQL file: %v", err)
+			}
+			query = string(data)
+		}
+
+		return withTriplestore(f, c, func(ctx context.Context, store *isle.Triplestore) error {
+			result, err := store.Query(ctx, query)
+			if err != nil {
+				return err
+			}
+			return writeSPARQLResult(result, format)
+		})
+	},
+}
+
+var triplestoreCountCmd = &cobra.Command{
+	Use:   "count",
+	Short: "Count the triples, or the resources of each rdf:type",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		format, err := f.GetString("format")
+		if err != nil {
+			return err
+		}
+		byType, err := f.GetBool("by-type")
+		if err != nil {
+			return err
+		}
+
+		return withTriplestore(f, c, func(ctx context.Context, store *isle.Triplestore) error {
+			result, err := store.Query(ctx, isle.CountQuery(byType))
+			if err != nil {
+				return err
+			}
+			return writeSPARQLResult(result, format)
+		})
+	},
+}
+
+var triplestoreReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Queue every Drupal node to be indexed in the triplestore",
+	Long: `Run Islandora's index action on every Drupal node of the given content types, which queues them on
+ActiveMQ for the triplestore indexer. Follow the progress with islectl queues watch.
+
+Pass --clear to delete every triple first, e.g. to remove triples of deleted nodes. Every media and
+taxonomy term is then queued too, so it can't be combined with --type. The triplestore is empty
+until the indexer catches up.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		c, err := config.CurrentContext(f)
+		if err != nil {
+			return err
+		}
+		clearStore, err := f.GetBool("clear")
+		if err != nil {
+			return err
+		}
+		yes, err := f.GetBool("yes")
+		if err != nil {
+			return err
+		}
+		action, err := f.GetString("action")
+		if err != nil {
+			return err
+		}
+		types, err := f.GetStringSlice("type")
+		if err != nil {
+			return err
+		}
+		mediaAction, err := f.GetString("media-action")
+		if err != nil {
+			return err
+		}
+		termAction, err := f.GetString("term-action")
+		if err != nil {
+			return err
+		}
+		if clearStore && len(types) > 0 {
+			return fmt.Errorf("--clear deletes every triple, it can't be combined with --type")
+		}
+		site, err := isle.CurrentSite(f, c)
+		if err != nil {
+			return err
+		}
+
+		// everything cleared must be queued again, so check it can be before deleting anything
+		if clearStore {
+			missing, err := isle.MissingActions(c, site, []string{action, mediaAction, termAction})
+			if err != nil {
+				return err
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("refusing to clear the triplestore, these actions don't exist on %s: %s", c.Name, strings.Join(missing, ", "))
+			}
+		}
+
+		nodes, err := isle.DrupalNodes(c, site, types)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 && !clearStore {
+			fmt.Println("No nodes to index")
+			return nil
+		}
+
+		if clearStore {
+			if !yes {
+				if ok, err := config.Confirm(fmt.Sprintf("Delete every triple in the %s triplestore and reindex %d nodes and every media and taxonomy term?", c.Name, len(nodes))); err != nil || !ok {
+					return err
+				}
+			}
+			err = withTriplestore(f, c, func(ctx context.Context, store *isle.Triplestore) error {
+				return store.Clear(ctx)
+			})
+			if err != nil {
+				return fmt.Errorf("unable to clear the triplestore: %v", err)
+			}
+			fmt.Println("Cleared the triplestore")
+		}
+
+		ids := make([]int, len(nodes))
+		for i, node := range nodes {
+			ids[i] = node.ID
+		}
+		queued, err := isle.RunNodeAction(c, site, action, ids, func(done int) {
+			fmt.Printf("Queued %d/%d nodes\n", done, len(ids))
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d nodes to be indexed in the triplestore\n", queued)
+
+		if !clearStore {
+			return nil
+		}
+		for _, entity := range []struct{ entityType, label, action string }{
+			{"media", "media", mediaAction},
+			{"taxonomy_term", "taxonomy terms", termAction},
+		} {
+			ids, err := isle.EntityIDs(c, site, entity.entityType)
+			if err != nil {
+				return err
+			}
+			queued, err := isle.RunEntityAction(c, site, entity.entityType, entity.action, ids, func(done int) {
+				fmt.Printf("Queued %d/%d %s\n", done, len(ids), entity.label)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Queued %d %s to be indexed in the triplestore\n", queued, entity.label)
+		}
+		return nil
+	},
+}
+
+func writeSPARQLResult(result isle.SPARQLResult, format string) error {
+	switch {
+	case result.Boolean != nil:
+		return utils.WriteRows(os.Stdout, format, []string{"RESULT"}, [][]string{{fmt.Sprint(*result.Boolean)}})
+	case result.Vars == nil:
+		fmt.Print(result.Graph)
+		return nil
+	}
+	return utils.WriteRows(os.Stdout, format, result.Vars, result.Rows)
+}
+
+func init() {
+	triplestoreCmd.PersistentFlags().String("namespace", isle.TriplestoreNamespace, "Blazegraph namespace to use")
+	triplestoreQueryCmd.Flags().String("file", "", "Path to a SPARQL file on this machine to run")
+	triplestoreQueryCmd.Flags().String("format", "table", "Output format for query results: "+strings.Join(utils.OutputFormats, ", "))
+	triplestoreCountCmd.Flags().String("format", "table", "Output format: "+strings.Join(utils.OutputFormats, ", "))
+	triplestoreCountCmd.Flags().Bool("by-type", false, "Count the resources of each rdf:type")
+	triplestoreReindexCmd.Flags().String("site", "", "Drupal multisite to use. Defaults to the context's site")
+	triplestoreReindexCmd.Flags().StringSlice("type", []string{}, "Content types to reindex. Defaults to every node")
+	triplestoreReindexCmd.Flags().String("action", isle.TriplestoreIndexAction, "Drupal action that queues a node for the triplestore")
+	triplestoreReindexCmd.Flags().String("media-action", isle.TriplestoreMediaIndexAction, "Drupal action that queues a media for the triplestore, used with --clear")
+	triplestoreReindexCmd.Flags().String("term-action", isle.TriplestoreTermIndexAction, "Drupal action that queues a taxonomy term for the triplestore, used with --clear")
+	triplestoreReindexCmd.Flags().Bool("clear", false, "Delete every triple, then reindex every node, media and taxonomy term")
+	triplestoreReindexCmd.Flags().BoolP("yes", "y", false, "Clear without asking for confirmation")
+
+	triplestoreCmd.AddCommand(triplestoreQueryCmd)
+	triplestoreCmd.AddCommand(triplestoreCountCmd)
+	triplestoreCmd.AddCommand(triplestoreReindexCmd)
+	rootCmd.AddCommand(triplestoreCmd)
+}
@@ -33,6 +33,50 @@ func OpenURL(url string) error {
 	return cmd.Start()
 }
 
+// CanOpenBrowser reports whether there is likely a desktop session to open URLs in
+func CanOpenBrowser() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+	if runtime.GOOS == "linux" {
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+	return true
+}
+
+// CopyToClipboard copies text to the system clipboard using the platform's clipboard tool
+func CopyToClipboard(text string) error {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		candidates = append(candidates,
+			[]string{"xclip", "-selection", "clipboard"},
+			[]string{"xsel", "--clipboard", "--input"},
+		)
+	}
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(candidate[0], candidate[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("error running %s: %v", candidate[0], err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no clipboard tool found")
+}
+
 // for cobra commands that allow arbitrary args to facilitate passing flags to other commands
 // strip out islectl's context flag from the args if it was passed
 func GetContextFromArgs(cmd *cobra.Command, args []string) ([]string, string, error) {
@@ -67,3 +111,43 @@ func GetContextFromArgs(cmd *cobra.Command, args []string) ([]string, string, er
 
 	return filteredArgs, isleContext, nil
 }
+
+// for cobra commands that allow arbitrary args, strip an islectl flag out of the args
+// and return its value. Boolean flags do not consume the next argument.
+// Only the flags before the first positional argument or "--" are islectl's, the rest
+// belong to the command being passed through and are left untouched.
+func GetFlagFromArgs(args []string, name string, isBool bool) ([]string, string, bool) {
+	flag := "--" + name
+	filteredArgs := []string{}
+	value := ""
+	found := false
+	skipNext := false
+	for i, arg := range args {
+		if skipNext {
+			value = arg
+			skipNext = false
+			continue
+		}
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			filteredArgs = append(filteredArgs, args[i:]...)
+			break
+		}
+		if arg == flag {
+			found = true
+			if isBool {
+				value = "true"
+			} else {
+				skipNext = true
+			}
+			continue
+		}
+		if v, ok := strings.CutPrefix(arg, flag+"="); ok {
+			found = true
+			value = v
+			continue
+		}
+		filteredArgs = append(filteredArgs, arg)
+	}
+
+	return filteredArgs, strings.Trim(value, `" `), found
+}
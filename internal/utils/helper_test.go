@@ -67,3 +67,88 @@ func TestGetContextFromArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFlagFromArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		flag          string
+		isBool        bool
+		expectedArgs  []string
+		expectedValue string
+		expectedFound bool
+	}{
+		{
+			name:         "flag not passed",
+			args:         []string{"status", "--uri=foo"},
+			flag:         "site",
+			expectedArgs: []string{"status", "--uri=foo"},
+		},
+		{
+			name:          "separate value",
+			args:          []string{"--site", "history", "status"},
+			flag:          "site",
+			expectedArgs:  []string{"status"},
+			expectedValue: "history",
+			expectedFound: true,
+		},
+		{
+			name:          "equals value",
+			args:          []string{"--site=history", "cr"},
+			flag:          "site",
+			expectedArgs:  []string{"cr"},
+			expectedValue: "history",
+			expectedFound: true,
+		},
+		{
+			name:          "boolean flag does not consume the next arg",
+			args:          []string{"--all-sites", "cr"},
+			flag:          "all-sites",
+			isBool:        true,
+			expectedArgs:  []string{"cr"},
+			expectedValue: "true",
+			expectedFound: true,
+		},
+		{
+			name:          "boolean flag with value",
+			args:          []string{"--all-sites=false", "cr"},
+			flag:          "all-sites",
+			isBool:        true,
+			expectedArgs:  []string{"cr"},
+			expectedValue: "false",
+			expectedFound: true,
+		},
+		{
+			name:          "other islectl flags before the command",
+			args:          []string{"--all-sites", "--site", "history", "updb", "-y"},
+			flag:          "site",
+			expectedArgs:  []string{"--all-sites", "updb", "-y"},
+			expectedValue: "history",
+			expectedFound: true,
+		},
+		{
+			name:         "flags after the command are passed through",
+			args:         []string{"sql:dump", "--site=history", "--result-file=dump.sql"},
+			flag:         "site",
+			expectedArgs: []string{"sql:dump", "--site=history", "--result-file=dump.sql"},
+		},
+		{
+			name:         "stops at --",
+			args:         []string{"--", "--site", "history"},
+			flag:         "site",
+			expectedArgs: []string{"--", "--site", "history"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, value, found := GetFlagFromArgs(tt.args, tt.flag, tt.isBool)
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %v, got %v", tt.expectedArgs, args)
+			}
+			if value != tt.expectedValue || found != tt.expectedFound {
+				t.Errorf("expected %q, %v, got %q, %v", tt.expectedValue, tt.expectedFound, value, found)
+			}
+		})
+	}
+}
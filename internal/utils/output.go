@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// OutputFormats are the formats supported by WriteRows.
+var OutputFormats = []string{"table", "csv", "json"}
+
+// ValidateFormat checks format is one of OutputFormats, so commands can fail before doing any work.
+func ValidateFormat(format string) error {
+	if !slices.Contains(OutputFormats, format) {
+		return fmt.Errorf("unknown output format %q. Valid formats are %s", format, strings.Join(OutputFormats, ", "))
+	}
+	return nil
+}
+
+// WriteRows renders tabular data as an aligned table, CSV, or a JSON array of objects keyed by header.
+func WriteRows(w io.Writer, format string, header []string, rows [][]string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			// tabs and newlines inside a value would break the table layout
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case "json":
+		records := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			record := make(map[string]string, len(header))
+			for i, column := range header {
+				if i < len(row) {
+					record[column] = row[i]
+				}
+			}
+			records = append(records, record)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	return ValidateFormat(format)
+}
+
+// FormatBytes renders a size in bytes with a binary unit, e.g. 1.5 MiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
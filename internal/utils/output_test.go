@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRows(t *testing.T) {
+	header := []string{"nid", "title"}
+	rows := [][]string{
+		{"1", "Hello, world"},
+		{"22", "Line\tbreak"},
+	}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"table", "nid  title\n1    Hello, world\n22   Line break\n", false},
+		{"csv", "nid,title\n1,\"Hello, world\"\n22,Line\tbreak\n", false},
+		{"json", "[\n  {\n    \"nid\": \"1\",\n    \"title\": \"Hello, world\"\n  },\n  {\n    \"nid\": \"22\",\n    \"title\": \"Line\\tbreak\"\n  }\n]\n", false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteRows(&buf, tt.format, header, rows)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got %q, want %q", buf.String(), tt.want)
+			}
+			if err := ValidateFormat(tt.format); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1536:                   "1.5 KiB",
+		5 * 1024 * 1024:        "5.0 MiB",
+		3 * 1024 * 1024 * 1024: "3.0 GiB",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	// the standard library has no QR encoder, rsc.io/qr is a small one without dependencies
+	"rsc.io/qr"
+)
+
+// quiet zone around the code, in modules
+const qrMargin = 2
+
+// RenderQR writes text as a QR code using unicode half blocks, two modules per line.
+// Light modules are drawn so the code scans on terminals with a dark background.
+func RenderQR(w io.Writer, text string) error {
+	code, err := qr.Encode(text, qr.L)
+	if err != nil {
+		return fmt.Errorf("unable to encode QR code: %v", err)
+	}
+
+	light := func(x, y int) bool {
+		return !code.Black(x, y)
+	}
+	var b strings.Builder
+	for y := -qrMargin; y < code.Size+qrMargin; y += 2 {
+		for x := -qrMargin; x < code.Size+qrMargin; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
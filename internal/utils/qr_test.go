@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+func TestRenderQR(t *testing.T) {
+	text := "https://islandora.dev/user/reset/1/1700000000/abc123/login"
+	var b strings.Builder
+	if err := RenderQR(&b, text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	code, err := qr.Encode(text, qr.L)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	size := code.Size + 2*qrMargin
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != (size+1)/2 {
+		t.Errorf("expected %d lines, got %d", (size+1)/2, len(lines))
+	}
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) != size {
+			t.Fatalf("line %d: expected %d modules, got %d", i, size, utf8.RuneCountInString(line))
+		}
+	}
+	// the quiet zone is light
+	if strings.Trim(lines[0], "█") != "" {
+		t.Errorf("expected the first line to be the quiet zone, got %q", lines[0])
+	}
+}
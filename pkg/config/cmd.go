@@ -2,6 +2,7 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -15,6 +16,15 @@ import (
 	"golang.org/x/term"
 )
 
+// remoteCommand is the shell command running cmd in the context's project directory over SSH.
+func (c *Context) remoteCommand(cmd *exec.Cmd) string {
+	args := cmd.Args
+	if c.RunSudo {
+		args = append([]string{"sudo"}, args...)
+	}
+	return "cd " + shellquote.Join(c.ProjectDir) + " && " + shellquote.Join(args...)
+}
+
 func (c *Context) RunCommand(cmd *exec.Cmd) (string, error) {
 	var output string
 	if c.DockerHostType == ContextLocal {
@@ -53,14 +63,7 @@ func (c *Context) RunCommand(cmd *exec.Cmd) (string, error) {
 	}
 	defer sshClient.Close()
 
-	remoteCmd := fmt.Sprintf("cd %s &&", c.ProjectDir)
-	if c.RunSudo {
-		remoteCmd += " sudo"
-	}
-	remoteCmd += " " + cmd.Args[0]
-	if len(cmd.Args) > 1 {
-		remoteCmd += " " + shellquote.Join(cmd.Args[1:]...)
-	}
+	remoteCmd := c.remoteCommand(cmd)
 
 	slog.Info("Running remote command", "host", c.SSHHostname, "cmd", remoteCmd)
 	session, err := sshClient.NewSession()
@@ -142,3 +145,128 @@ func (c *Context) RunCommand(cmd *exec.Cmd) (string, error) {
 
 	return output, nil
 }
+
+// RunInteractiveCommand runs cmd attached to the terminal so interactive programs
+// (shells, REPLs) can draw prompts that RunCommand's line buffering would hide.
+func (c *Context) RunInteractiveCommand(cmd *exec.Cmd) error {
+	if c.DockerHostType != ContextLocal {
+		_, err := c.RunCommand(cmd)
+		return err
+	}
+
+	cmd = exec.Command(cmd.Path, cmd.Args[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Dir = c.ProjectDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running command %s: %v", cmd.String(), err)
+	}
+
+	return nil
+}
+
+// CaptureCommand runs cmd without a terminal and returns its full stdout.
+// stdin, when not nil, is streamed to the command which allows sending local files
+// to remote contexts. stderr is passed through so errors are still visible.
+func (c *Context) CaptureCommand(cmd *exec.Cmd, stdin io.Reader) (string, error) {
+	var stdout bytes.Buffer
+	if c.DockerHostType == ContextLocal {
+		cmd = exec.Command(cmd.Path, cmd.Args[1:]...)
+		cmd.Env = os.Environ()
+		cmd.Dir = c.ProjectDir
+		cmd.Stdin = stdin
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return stdout.String(), fmt.Errorf("error running command %s: %v", cmd.String(), err)
+		}
+		return stdout.String(), nil
+	}
+
+	sshClient, err := c.DialSSH()
+	if err != nil {
+		return "", fmt.Errorf("error establishing SSH connection: %v", err)
+	}
+	defer sshClient.Close()
+
+	remoteCmd := c.remoteCommand(cmd)
+
+	slog.Debug("Running remote command", "host", c.SSHHostname, "cmd", remoteCmd)
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("error creating SSH session: %v", err)
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = os.Stderr
+	if err := session.Run(remoteCmd); err != nil {
+		return stdout.String(), fmt.Errorf("error running remote command %q: %v", remoteCmd, err)
+	}
+
+	return stdout.String(), nil
+}
+
+// StreamCommand runs cmd without a terminal and calls onLine for every line of its
+// stdout and stderr as it is printed, e.g. to turn progress messages into a status line.
+func (c *Context) StreamCommand(cmd *exec.Cmd, onLine func(string)) error {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			onLine(scanner.Text())
+		}
+		// keep draining so the command never blocks on a full pipe
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	var runErr error
+	if c.DockerHostType == ContextLocal {
+		cmd = exec.Command(cmd.Path, cmd.Args[1:]...)
+		cmd.Env = os.Environ()
+		cmd.Dir = c.ProjectDir
+		// the same writer makes exec share one pipe, keeping stdout and stderr in order
+		cmd.Stdout = pw
+		cmd.Stderr = pw
+		if err := cmd.Run(); err != nil {
+			runErr = fmt.Errorf("error running command %s: %v", cmd.String(), err)
+		}
+	} else {
+		runErr = c.streamRemote(cmd, pw)
+	}
+
+	pw.Close()
+	<-done
+	return runErr
+}
+
+func (c *Context) streamRemote(cmd *exec.Cmd, out io.Writer) error {
+	sshClient, err := c.DialSSH()
+	if err != nil {
+		return fmt.Errorf("error establishing SSH connection: %v", err)
+	}
+	defer sshClient.Close()
+
+	remoteCmd := c.remoteCommand(cmd)
+
+	slog.Debug("Running remote command", "host", c.SSHHostname, "cmd", remoteCmd)
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("error creating SSH session: %v", err)
+	}
+	defer session.Close()
+
+	session.Stdout = out
+	session.Stderr = out
+	if err := session.Run(remoteCmd); err != nil {
+		return fmt.Errorf("error running remote command %q: %v", remoteCmd, err)
+	}
+
+	return nil
+}
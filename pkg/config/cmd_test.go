@@ -19,3 +19,55 @@ func TestRunCommandLocal(t *testing.T) {
 		t.Fatalf("expected output to contain 'hello', got %v", output)
 	}
 }
+
+func TestRemoteCommand(t *testing.T) {
+	c := &Context{ProjectDir: "/opt/my isle"}
+	cmd := exec.Command("docker", "compose", "exec", "drupal", "drush", "sql:query", "SELECT 1; DROP")
+	want := `cd '/opt/my isle' && docker compose exec drupal drush sql:query 'SELECT 1; DROP'`
+	if got := c.remoteCommand(cmd); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	c.RunSudo = true
+	want = `cd '/opt/my isle' && sudo docker compose exec drupal drush sql:query 'SELECT 1; DROP'`
+	if got := c.remoteCommand(cmd); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestCaptureCommandLocal(t *testing.T) {
+	ctx := &Context{
+		DockerHostType: ContextLocal,
+	}
+	cmd := exec.Command("cat")
+	output, err := ctx.CaptureCommand(cmd, strings.NewReader("line one\nline two\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "line one\nline two\n" {
+		t.Fatalf("expected the full stdin to be echoed back, got %q", output)
+	}
+
+	_, err = ctx.CaptureCommand(exec.Command("false"), nil)
+	if err == nil {
+		t.Fatal("expected an error for a failing command")
+	}
+}
+
+func TestStreamCommandLocal(t *testing.T) {
+	ctx := &Context{
+		DockerHostType: ContextLocal,
+	}
+	lines := []string{}
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2; echo done")
+	if err := ctx.StreamCommand(cmd, func(line string) { lines = append(lines, line) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(lines, ",") != "out,err,done" {
+		t.Fatalf("expected stdout and stderr lines in order, got %q", lines)
+	}
+
+	if err := ctx.StreamCommand(exec.Command("false"), func(string) {}); err == nil {
+		t.Fatal("expected an error for a failing command")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -41,6 +42,9 @@ type Context struct {
 	EnvFile        []string          `yaml:"env-file"`
 	RunSudo        bool              `yaml:"sudo"`
 	UriMap         map[string]string `yaml:"uriMap"`
+	PortForwards   []string          `yaml:"port-forwards,omitempty"`
+	SecretBackends []string          `yaml:"secret-backends,omitempty"`
+	DeploySteps    []string          `yaml:"deploy-steps,omitempty"`
 
 	ReadSmallFileFunc func(filename string) string `yaml:"-"`
 }
@@ -434,3 +438,112 @@ func (c *Context) UploadFile(source, destination string) error {
 
 	return nil
 }
+
+// ListDir returns the names of the regular files in dir.
+func (c *Context) ListDir(dir string) ([]string, error) {
+	var entries []os.FileInfo
+	if c.DockerHostType == ContextLocal {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range dirEntries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, info)
+		}
+	} else {
+		client, err := c.DialSSH()
+		if err != nil {
+			return nil, err
+		}
+		defer client.Close()
+
+		sftpClient, err := sftp.NewClient(client)
+		if err != nil {
+			return nil, fmt.Errorf("error creating SFTP client: %w", err)
+		}
+		defer sftpClient.Close()
+
+		entries, err = sftpClient.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// WriteSmallFile replaces the contents of filename, keeping the mode of an existing file.
+// New files are only readable by their owner.
+func (c *Context) WriteSmallFile(filename, data string) error {
+	if c.DockerHostType == ContextLocal {
+		mode := os.FileMode(0600)
+		if info, err := os.Stat(filename); err == nil {
+			mode = info.Mode().Perm()
+		}
+		return os.WriteFile(filename, []byte(data), mode)
+	}
+
+	client, err := c.DialSSH()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return fmt.Errorf("error creating SFTP client: %w", err)
+	}
+	defer sftpClient.Close()
+
+	mode := os.FileMode(0600)
+	if info, err := sftpClient.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	remoteFile, err := sftpClient.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	defer remoteFile.Close()
+	if err := remoteFile.Chmod(mode); err != nil {
+		return err
+	}
+	_, err = remoteFile.Write([]byte(data))
+
+	return err
+}
+
+// SiteURI returns the base URL the context's UriMap sets for a Drupal site.
+func (c *Context) SiteURI(site string) (string, bool) {
+	uri, ok := c.UriMap[site]
+	return uri, ok && uri != ""
+}
+
+// UpdateUriMap adds or replaces the URIs of sites and then removes the sites in remove.
+func (c *Context) UpdateUriMap(set map[string]string, remove []string) error {
+	for site, uri := range set {
+		u, err := url.Parse(uri)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URI %q for site %q: expected an http or https URL", uri, site)
+		}
+		if c.UriMap == nil {
+			c.UriMap = map[string]string{}
+		}
+		c.UriMap[site] = strings.TrimRight(uri, "/")
+	}
+	for _, site := range remove {
+		delete(c.UriMap, site)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -211,6 +212,86 @@ func TestReadSmallFileLocal(t *testing.T) {
 	}
 }
 
+func TestWriteSmallFileAndListDirLocal(t *testing.T) {
+	dir := t.TempDir()
+	ctx := &Context{
+		DockerHostType: ContextLocal,
+	}
+
+	existing := filepath.Join(dir, "EXISTING")
+	if err := os.WriteFile(existing, []byte("old"), 0640); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatalf("failed to create subdir: %v", err)
+	}
+
+	if err := ctx.WriteSmallFile(existing, "new"); err != nil {
+		t.Fatalf("WriteSmallFile error: %v", err)
+	}
+	if got := ctx.ReadSmallFile(existing); got != "new" {
+		t.Errorf("expected %q, got %q", "new", got)
+	}
+	if info, _ := os.Stat(existing); info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be kept, got %v", info.Mode().Perm())
+	}
+
+	created := filepath.Join(dir, "CREATED")
+	if err := ctx.WriteSmallFile(created, "value"); err != nil {
+		t.Fatalf("WriteSmallFile error: %v", err)
+	}
+	if info, _ := os.Stat(created); info.Mode().Perm() != 0600 {
+		t.Errorf("expected new file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	names, err := ctx.ListDir(dir)
+	if err != nil {
+		t.Fatalf("ListDir error: %v", err)
+	}
+	if strings.Join(names, ",") != "CREATED,EXISTING" {
+		t.Errorf("expected only regular files, got %v", names)
+	}
+}
+
+func TestUpdateUriMap(t *testing.T) {
+	c := &Context{UriMap: map[string]string{"old": "https://old.example.com"}}
+
+	err := c.UpdateUriMap(map[string]string{
+		"default": "https://islandora.dev/",
+		"history": "https://history.example.com",
+	}, []string{"old"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"default": "https://islandora.dev",
+		"history": "https://history.example.com",
+	}
+	if !reflect.DeepEqual(c.UriMap, expected) {
+		t.Errorf("expected %v, got %v", expected, c.UriMap)
+	}
+	if uri, ok := c.SiteURI("history"); !ok || uri != "https://history.example.com" {
+		t.Errorf("unexpected SiteURI %q, %v", uri, ok)
+	}
+	if _, ok := c.SiteURI("old"); ok {
+		t.Errorf("expected the removed site to have no URI")
+	}
+
+	for _, uri := range []string{"history.example.com", "ftp://example.com", "https://"} {
+		if err := c.UpdateUriMap(map[string]string{"bad": uri}, nil); err == nil {
+			t.Errorf("expected an error for URI %q", uri)
+		}
+	}
+
+	empty := &Context{}
+	if err := empty.UpdateUriMap(map[string]string{"default": "http://localhost:8080"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if empty.UriMap["default"] != "http://localhost:8080" {
+		t.Errorf("expected the map to be created, got %v", empty.UriMap)
+	}
+}
+
 func TestDialSSHError(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
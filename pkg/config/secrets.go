@@ -0,0 +1,370 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// VaultPassphraseEnv is the environment variable holding the passphrase for vault secret backends.
+// When it is not set the passphrase is prompted for.
+const VaultPassphraseEnv = "ISLECTL_VAULT_PASSPHRASE"
+
+// SecretBackend looks up secrets outside of the project's secrets directory.
+type SecretBackend interface {
+	// Lookup returns the secret's value and whether the backend has it.
+	Lookup(name string) (string, bool, error)
+}
+
+// SecretBackendTypes documents the supported secret backend specs.
+var SecretBackendTypes = []string{
+	"env[:PREFIX]",
+	"pass[:PREFIX]",
+	"command:COMMAND",
+	"vault:PATH",
+}
+
+// ParseSecretBackend parses a secret backend spec from a context's secret-backends.
+func ParseSecretBackend(spec string) (SecretBackend, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "env":
+		return envBackend{prefix: arg}, nil
+	case "pass":
+		return passBackend{prefix: strings.Trim(arg, "/")}, nil
+	case "command":
+		if arg == "" {
+			return nil, fmt.Errorf("secret backend %q is missing a command", spec)
+		}
+		return commandBackend{command: arg}, nil
+	case "vault":
+		if arg == "" {
+			return nil, fmt.Errorf("secret backend %q is missing a vault path", spec)
+		}
+		return vaultBackend{path: expandHome(arg)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown secret backend %q. Valid backends are %s", spec, strings.Join(SecretBackendTypes, ", "))
+}
+
+// LookupSecret consults the context's secret backends in order.
+// A backend that fails, e.g. a locked vault or pass not being installed, doesn't stop the later ones
+// from being consulted. The errors are only returned when no backend had the secret.
+func (c *Context) LookupSecret(name string) (string, bool, error) {
+	errs := []error{}
+	for _, spec := range c.SecretBackends {
+		backend, err := ParseSecretBackend(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		value, ok, err := backend.Lookup(name)
+		if err != nil {
+			slog.Debug("Secret backend failed", "secret", name, "backend", spec, "err", err)
+			errs = append(errs, fmt.Errorf("error reading %s from secret backend %q: %w", name, spec, err))
+			continue
+		}
+		if ok {
+			slog.Debug("Found secret in backend", "secret", name, "backend", spec)
+			return value, true, nil
+		}
+	}
+
+	return "", false, errors.Join(errs...)
+}
+
+// secretBackend returns the first backend LookupSecret would read the secret from.
+func (c *Context) secretBackend(name string) (string, SecretBackend, bool) {
+	for _, spec := range c.SecretBackends {
+		backend, err := ParseSecretBackend(spec)
+		if err != nil {
+			continue
+		}
+		if _, ok, err := backend.Lookup(name); err == nil && ok {
+			return spec, backend, true
+		}
+	}
+	return "", nil, false
+}
+
+// CheckSecretWritable returns an error when the secret is read from a backend islectl can't
+// update, so changing only the project's secret file would leave the context reading the old value.
+func (c *Context) CheckSecretWritable(name string) error {
+	spec, backend, ok := c.secretBackend(name)
+	if !ok {
+		return nil
+	}
+	if _, isVault := backend.(vaultBackend); !isVault {
+		return fmt.Errorf("%s is read from the secret backend %q, which islectl can't update. Change it there, or remove it from the backend first", name, spec)
+	}
+	return nil
+}
+
+// UpdateSecret stores a new value for the secret in the vault backend it is read from.
+// It reports the backend it updated, or "" when no backend has the secret.
+func (c *Context) UpdateSecret(name, value string) (string, error) {
+	if err := c.CheckSecretWritable(name); err != nil {
+		return "", err
+	}
+	spec, backend, ok := c.secretBackend(name)
+	if !ok {
+		return "", nil
+	}
+	if err := backend.(vaultBackend).Set(name, value); err != nil {
+		return "", fmt.Errorf("unable to update %s in secret backend %q: %w", name, spec, err)
+	}
+	return spec, nil
+}
+
+// VaultPath returns the path of the context's first vault backend.
+func (c *Context) VaultPath() string {
+	for _, spec := range c.SecretBackends {
+		if backend, err := ParseSecretBackend(spec); err == nil {
+			if vault, ok := backend.(vaultBackend); ok {
+				return vault.path
+			}
+		}
+	}
+	return ""
+}
+
+// envBackend reads secrets from environment variables named PREFIX + secret name.
+type envBackend struct {
+	prefix string
+}
+
+func (b envBackend) Lookup(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(b.prefix + name)
+	return value, ok, nil
+}
+
+// passBackend reads secrets from the pass password store at PREFIX/secret name.
+type passBackend struct {
+	prefix string
+}
+
+func (b passBackend) Lookup(name string) (string, bool, error) {
+	entry := name
+	if b.prefix != "" {
+		entry = b.prefix + "/" + name
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("pass", "show", entry)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "is not in the password store") {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("pass show %s: %v: %s", entry, err, strings.TrimSpace(stderr.String()))
+	}
+
+	// like pass -c, only the first line is the password
+	value, _, _ := strings.Cut(stdout.String(), "\n")
+	return value, true, nil
+}
+
+// commandBackend runs a shell command with the secret name as $1 and in $ISLECTL_SECRET.
+// The command prints the value, or nothing if it does not have the secret.
+type commandBackend struct {
+	command string
+}
+
+func (b commandBackend) Lookup(name string) (string, bool, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", b.command, "sh", name)
+	cmd.Env = append(os.Environ(), "ISLECTL_SECRET="+name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", false, err
+	}
+
+	value := strings.TrimRight(stdout.String(), "\r\n")
+	return value, value != "", nil
+}
+
+// vaultBackend reads secrets from an encrypted vault file.
+type vaultBackend struct {
+	path string
+}
+
+var (
+	openVaultsMu sync.Mutex
+	openVaults   = map[string]*Vault{}
+)
+
+func (b vaultBackend) Lookup(name string) (string, bool, error) {
+	// only ask for the passphrase once per process
+	openVaultsMu.Lock()
+	defer openVaultsMu.Unlock()
+	vault, ok := openVaults[b.path]
+	if !ok {
+		passphrase, err := VaultPassphrase(b.path)
+		if err != nil {
+			return "", false, err
+		}
+		vault, err = OpenVault(b.path, passphrase)
+		if err != nil {
+			return "", false, err
+		}
+		openVaults[b.path] = vault
+	}
+
+	value, ok := vault.Secrets[name]
+	return value, ok, nil
+}
+
+// Set stores the secret in the vault, which Lookup has already opened.
+func (b vaultBackend) Set(name, value string) error {
+	openVaultsMu.Lock()
+	defer openVaultsMu.Unlock()
+	vault, ok := openVaults[b.path]
+	if !ok {
+		return fmt.Errorf("vault %s is not open", b.path)
+	}
+	vault.Secrets[name] = value
+	return vault.Save()
+}
+
+// VaultPassphrase returns the passphrase from VaultPassphraseEnv or prompts for it.
+func VaultPassphrase(path string) (string, error) {
+	if passphrase := os.Getenv(VaultPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("set %s to unlock the vault %s", VaultPassphraseEnv, path)
+	}
+
+	fmt.Fprintf(os.Stderr, "Passphrase for %s: ", path)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase: %w", err)
+	}
+
+	return string(passphrase), nil
+}
+
+// Vault is a file of secrets encrypted with AES-GCM using a key derived from a passphrase with scrypt.
+type Vault struct {
+	Path    string
+	Secrets map[string]string
+
+	passphrase string
+}
+
+type vaultFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// OpenVault decrypts the vault at path. A missing file is an empty vault.
+func OpenVault(path, passphrase string) (*Vault, error) {
+	v := &Vault{Path: path, Secrets: map[string]string{}, passphrase: passphrase}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f vaultFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse vault %s: %w", path, err)
+	}
+	gcm, err := vaultCipher(passphrase, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt vault %s: wrong passphrase or corrupt file", path)
+	}
+	if err := json.Unmarshal(plaintext, &v.Secrets); err != nil {
+		return nil, fmt.Errorf("unable to parse vault %s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+// Names returns the sorted names of the secrets in the vault.
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.Secrets))
+	for name := range v.Secrets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Save encrypts the vault with a new salt and nonce and writes it, only readable by its owner.
+func (v *Vault) Save() error {
+	plaintext, err := json.Marshal(v.Secrets)
+	if err != nil {
+		return err
+	}
+
+	f := vaultFile{Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := vaultCipher(v.passphrase, f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.Path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(v.Path, data, 0600)
+}
+
+func vaultCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive vault key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
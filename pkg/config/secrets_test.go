@@ -0,0 +1,111 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSecretBackend(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    SecretBackend
+		wantErr bool
+	}{
+		{spec: "env", want: envBackend{}},
+		{spec: "env:PROD_", want: envBackend{prefix: "PROD_"}},
+		{spec: "pass:islandora/prod/", want: passBackend{prefix: "islandora/prod"}},
+		{spec: "command:op read op://isle/$1", want: commandBackend{command: "op read op://isle/$1"}},
+		{spec: "vault:/tmp/prod.vault", want: vaultBackend{path: "/tmp/prod.vault"}},
+		{spec: "command", wantErr: true},
+		{spec: "vault", wantErr: true},
+		{spec: "keychain", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSecretBackend(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSecretBackend(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSecretBackend(%q) = %#v, want %#v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupSecret(t *testing.T) {
+	t.Setenv("PROD_DB_ROOT_PASSWORD", "from-env")
+	c := &Context{
+		SecretBackends: []string{
+			"env:PROD_",
+			`command:[ "$1" = JWT_ADMIN_TOKEN ] && echo "from-$ISLECTL_SECRET" || true`,
+		},
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		found bool
+	}{
+		{name: "DB_ROOT_PASSWORD", value: "from-env", found: true},
+		{name: "JWT_ADMIN_TOKEN", value: "from-JWT_ADMIN_TOKEN", found: true},
+		{name: "ACTIVEMQ_PASSWORD"},
+	}
+	for _, tt := range tests {
+		value, found, err := c.LookupSecret(tt.name)
+		if err != nil {
+			t.Fatalf("unexpected error looking up %s: %v", tt.name, err)
+		}
+		if value != tt.value || found != tt.found {
+			t.Errorf("LookupSecret(%q) = %q, %v, want %q, %v", tt.name, value, found, tt.value, tt.found)
+		}
+	}
+
+	c.SecretBackends = []string{"command:exit 1"}
+	if _, _, err := c.LookupSecret("DB_ROOT_PASSWORD"); err == nil {
+		t.Errorf("expected an error when the command hook fails")
+	}
+
+	// a failing backend doesn't hide the ones after it
+	c.SecretBackends = []string{"command:exit 1", "env:PROD_"}
+	value, found, err := c.LookupSecret("DB_ROOT_PASSWORD")
+	if err != nil || !found || value != "from-env" {
+		t.Errorf("expected the env backend to be used after the failing one, got %q, %v, %v", value, found, err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vaults", "prod.vault")
+	vault, err := OpenVault(path, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error opening a new vault: %v", err)
+	}
+	vault.Secrets["DB_ROOT_PASSWORD"] = "s3cret"
+	vault.Secrets["ACTIVEMQ_PASSWORD"] = "password"
+	if err := vault.Save(); err != nil {
+		t.Fatalf("unexpected error saving vault: %v", err)
+	}
+
+	if _, err := OpenVault(path, "wrong"); err == nil {
+		t.Errorf("expected an error opening the vault with the wrong passphrase")
+	}
+
+	reopened, err := OpenVault(path, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error reopening vault: %v", err)
+	}
+	if !reflect.DeepEqual(reopened.Names(), []string{"ACTIVEMQ_PASSWORD", "DB_ROOT_PASSWORD"}) {
+		t.Errorf("unexpected secrets in vault: %v", reopened.Names())
+	}
+
+	t.Setenv(VaultPassphraseEnv, "correct horse")
+	c := &Context{SecretBackends: []string{"env:UNUSED_", "vault:" + path}}
+	if c.VaultPath() != path {
+		t.Errorf("expected vault path %q, got %q", path, c.VaultPath())
+	}
+	value, found, err := c.LookupSecret("DB_ROOT_PASSWORD")
+	if err != nil || !found || value != "s3cret" {
+		t.Errorf("expected to find the secret in the vault, got %q, %v, %v", value, found, err)
+	}
+}
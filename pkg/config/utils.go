@@ -35,6 +35,20 @@ func GetInput(question ...string) (string, error) {
 	return input, nil
 }
 
+// Confirm asks a [y/N] question and reports whether it was answered yes,
+// printing that the command is cancelled otherwise.
+func Confirm(question string) (bool, error) {
+	answer, err := GetInput(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		fmt.Println("Cancelling...")
+		return false, nil
+	}
+	return true, nil
+}
+
 func LoadFromFlags(f *pflag.FlagSet, context Context) (*Context, error) {
 	t := reflect.TypeOf(Context{})
 	exists := context.DockerSocket != ""
@@ -111,17 +125,17 @@ func LoadFromFlags(f *pflag.FlagSet, context Context) (*Context, error) {
 // this is mostly needed for Mac OS
 func GetDefaultLocalDockerSocket(dockerSocket string) string {
 	macOsSocket := filepath.Join(os.Getenv("HOME"), ".docker/run/docker.sock")
-	if isDockerSocketAlive(macOsSocket) {
+	if IsDockerSocketAlive(macOsSocket) {
 		return macOsSocket
 	}
 
 	tried := []string{macOsSocket}
-	if isDockerSocketAlive(dockerSocket) {
+	if IsDockerSocketAlive(dockerSocket) {
 		return strings.TrimPrefix(dockerSocket, "unix://")
 	}
 
 	dockerSocket = os.Getenv("DOCKER_HOST")
-	if isDockerSocketAlive(dockerSocket) {
+	if IsDockerSocketAlive(dockerSocket) {
 		return strings.TrimPrefix(dockerSocket, "unix://")
 	}
 
@@ -130,7 +144,8 @@ func GetDefaultLocalDockerSocket(dockerSocket string) string {
 	return ""
 }
 
-func isDockerSocketAlive(socket string) bool {
+// IsDockerSocketAlive reports whether something accepts connections on a local unix socket.
+func IsDockerSocketAlive(socket string) bool {
 	socket = strings.TrimPrefix(socket, "unix://")
 	conn, err := net.DialTimeout("unix", socket, 1*time.Second)
 	if err != nil {
@@ -166,4 +181,7 @@ func SetCommandFlags(flags *pflag.FlagSet) {
 	flags.String("site", "default", "drupal multisite")
 	flags.Bool("sudo", false, "for remote contexts, run commands as sudo")
 	flags.StringSlice("env-file", []string{}, "when running remote docker commands, the --env-file paths to pass to docker compose")
+	flags.StringSlice("port-forwards", []string{}, "port-forward specs to use when islectl port-forward is ran without arguments")
+	flags.StringSlice("deploy-steps", []string{}, "ordered steps islectl deploy runs for this context")
+	flags.StringSlice("secret-backends", []string{}, "where to look up secrets before the project's secrets directory, in order: "+strings.Join(SecretBackendTypes, ", "))
 }
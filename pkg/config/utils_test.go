@@ -26,6 +26,9 @@ func TestLoadFromFlags(t *testing.T) {
 	flags.String("site", "foo", "Composer Project Name")
 	flags.Bool("sudo", false, "Run commands on remote hosts as sudo")
 	flags.StringSlice("env-file", []string{}, "path to env files to pass to docker compose")
+	flags.StringSlice("port-forwards", []string{}, "default port-forward specs")
+	flags.StringSlice("secret-backends", []string{}, "secret backends")
+	flags.StringSlice("deploy-steps", []string{}, "deploy steps")
 
 	// Define test arguments to override defaults.
 	args := []string{
@@ -42,6 +45,9 @@ func TestLoadFromFlags(t *testing.T) {
 		"--sudo", "true",
 		"--env-file", ".env",
 		"--env-file", "/tmp/.env",
+		"--port-forwards", "solr,8080:traefik:8080",
+		"--secret-backends", "env:PROD_",
+		"--secret-backends", "vault:~/.islectl/prod.vault",
 	}
 	if err := flags.Parse(args); err != nil {
 		t.Fatalf("Error parsing flags: %v", err)
@@ -91,6 +97,14 @@ func TestLoadFromFlags(t *testing.T) {
 	if !reflect.DeepEqual(ctx.EnvFile, expectedSlice) {
 		t.Errorf("expected env-file slice %v but got %v", expectedSlice, ctx.EnvFile)
 	}
+	expectedForwards := []string{"solr", "8080:traefik:8080"}
+	if !reflect.DeepEqual(ctx.PortForwards, expectedForwards) {
+		t.Errorf("expected port-forwards slice %v but got %v", expectedForwards, ctx.PortForwards)
+	}
+	expectedBackends := []string{"env:PROD_", "vault:~/.islectl/prod.vault"}
+	if !reflect.DeepEqual(ctx.SecretBackends, expectedBackends) {
+		t.Errorf("expected secret-backends slice %v but got %v", expectedBackends, ctx.SecretBackends)
+	}
 }
 
 func TestGetInput(t *testing.T) {
@@ -136,3 +150,48 @@ func TestGetInput(t *testing.T) {
 		t.Fatalf("expected output to contain %q, got %q", expectedPrompt, output)
 	}
 }
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   bool
+		output string
+	}{
+		{input: "y\n", want: true, output: "Continue? [y/N]: "},
+		{input: "YES\n", want: true},
+		{input: "\n", want: false, output: "Cancelling..."},
+		{input: "no\n", want: false, output: "Cancelling..."},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.input), func(t *testing.T) {
+			inR, inW, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to create input pipe: %v", err)
+			}
+			inW.WriteString(tt.input)
+			inW.Close()
+			outR, outW, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to create output pipe: %v", err)
+			}
+			origStdin, origStdout := os.Stdin, os.Stdout
+			os.Stdin, os.Stdout = inR, outW
+			ok, err := Confirm("Continue?")
+			os.Stdin, os.Stdout = origStdin, origStdout
+			outW.Close()
+			if err != nil {
+				t.Fatalf("Confirm error: %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, ok)
+			}
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, outR); err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.output) {
+				t.Errorf("expected output to contain %q, got %q", tt.output, buf.String())
+			}
+		})
+	}
+}
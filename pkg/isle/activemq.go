@@ -0,0 +1,213 @@
+package isle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ActiveMQURL is the web console of the activemq service, reached through a Tunnel's HTTP client.
+const ActiveMQURL = "http://activemq:8161"
+
+// DeadLetterQueue is where ActiveMQ sends messages that failed to be delivered.
+const DeadLetterQueue = "ActiveMQ.DLQ"
+
+// Queue is an ActiveMQ queue and its counters.
+type Queue struct {
+	Name      string
+	Pending   int64
+	Consumers int64
+	Enqueued  int64
+	Dequeued  int64
+
+	mbean string
+}
+
+// Stalled reports whether messages are waiting without anything to consume them,
+// which is how stuck derivative generation usually shows up.
+func (q Queue) Stalled() bool {
+	return q.Pending > 0 && q.Consumers == 0
+}
+
+// IsIslandoraQueue reports whether a queue is used by Islandora, including dead letter queues.
+func IsIslandoraQueue(name string) bool {
+	return strings.HasPrefix(name, "islandora") || strings.Contains(name, "DLQ")
+}
+
+// ActiveMQ talks to the broker through its Jolokia API.
+type ActiveMQ struct {
+	Client   *http.Client
+	BaseURL  string
+	User     string
+	Password string
+}
+
+// NewActiveMQ returns a client for the context's activemq service using the web console credentials from the container.
+func NewActiveMQ(ctx context.Context, t *Tunnel) (*ActiveMQ, error) {
+	containerName, err := t.ContainerName("activemq")
+	if err != nil {
+		return nil, err
+	}
+	cli := t.Client()
+	user, err := GetConfigEnv(ctx, cli.CLI, containerName, "ACTIVEMQ_WEB_ADMIN_NAME")
+	if err != nil {
+		user = "admin"
+	}
+	password, err := GetSecret(ctx, cli.CLI, t.Context, containerName, "ACTIVEMQ_WEB_ADMIN_PASSWORD")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the activemq web console password: %v", err)
+	}
+
+	return &ActiveMQ{
+		Client:   t.HTTPClient(),
+		BaseURL:  ActiveMQURL,
+		User:     user,
+		Password: strings.TrimSpace(password),
+	}, nil
+}
+
+type jolokiaResponse struct {
+	Status int             `json:"status"`
+	Error  string          `json:"error"`
+	Value  json.RawMessage `json:"value"`
+}
+
+func (a *ActiveMQ) jolokia(ctx context.Context, request map[string]any) (json.RawMessage, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.BaseURL+"/api/jolokia/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(a.User, a.Password)
+	req.Header.Set("Content-Type", "application/json")
+	// jolokia's CORS checks reject requests without a local origin
+	req.Header.Set("Origin", "http://localhost")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach activemq: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("activemq returned %s", resp.Status)
+	}
+
+	var out jolokiaResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("unable to parse activemq response: %v", err)
+	}
+	if out.Status != http.StatusOK {
+		return nil, fmt.Errorf("activemq error: %s", out.Error)
+	}
+	return out.Value, nil
+}
+
+// Queues returns every queue on the broker sorted by name.
+func (a *ActiveMQ) Queues(ctx context.Context) ([]Queue, error) {
+	value, err := a.jolokia(ctx, map[string]any{
+		"type":      "read",
+		"mbean":     "org.apache.activemq:type=Broker,brokerName=*,destinationType=Queue,destinationName=*",
+		"attribute": []string{"QueueSize", "ConsumerCount", "EnqueueCount", "DequeueCount"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseJolokiaQueues(value)
+}
+
+// ParseJolokiaQueues parses a Jolokia read of the queue MBeans.
+func ParseJolokiaQueues(value []byte) ([]Queue, error) {
+	var mbeans map[string]struct {
+		QueueSize     int64
+		ConsumerCount int64
+		EnqueueCount  int64
+		DequeueCount  int64
+	}
+	if err := json.Unmarshal(value, &mbeans); err != nil {
+		return nil, fmt.Errorf("unable to parse activemq queues: %v", err)
+	}
+
+	queues := []Queue{}
+	for mbean, attrs := range mbeans {
+		name := mbeanProperty(mbean, "destinationName")
+		if name == "" {
+			continue
+		}
+		queues = append(queues, Queue{
+			Name:      name,
+			Pending:   attrs.QueueSize,
+			Consumers: attrs.ConsumerCount,
+			Enqueued:  attrs.EnqueueCount,
+			Dequeued:  attrs.DequeueCount,
+			mbean:     mbean,
+		})
+	}
+	slices.SortFunc(queues, func(a, b Queue) int { return strings.Compare(a.Name, b.Name) })
+
+	return queues, nil
+}
+
+func mbeanProperty(mbean, key string) string {
+	_, props, _ := strings.Cut(mbean, ":")
+	for _, prop := range strings.Split(props, ",") {
+		if k, v, ok := strings.Cut(prop, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+func (a *ActiveMQ) queue(ctx context.Context, name string) (Queue, error) {
+	queues, err := a.Queues(ctx)
+	if err != nil {
+		return Queue{}, err
+	}
+	for _, q := range queues {
+		if q.Name == name {
+			return q, nil
+		}
+	}
+	return Queue{}, fmt.Errorf("queue %q not found", name)
+}
+
+// Purge deletes every pending message in a queue and returns how many there were.
+func (a *ActiveMQ) Purge(ctx context.Context, name string) (int64, error) {
+	q, err := a.queue(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.jolokia(ctx, map[string]any{"type": "exec", "mbean": q.mbean, "operation": "purge()"}); err != nil {
+		return 0, fmt.Errorf("unable to purge %s: %v", name, err)
+	}
+	return q.Pending, nil
+}
+
+// Redrive sends the messages in a dead letter queue back to the queues they failed on
+// and returns how many were sent.
+func (a *ActiveMQ) Redrive(ctx context.Context, name string) (int64, error) {
+	q, err := a.queue(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	value, err := a.jolokia(ctx, map[string]any{"type": "exec", "mbean": q.mbean, "operation": "retryMessages()"})
+	if err != nil {
+		return 0, fmt.Errorf("unable to re-drive %s: %v", name, err)
+	}
+	var retried int64
+	if err := json.Unmarshal(value, &retried); err != nil {
+		return 0, fmt.Errorf("unexpected re-drive response %s: %v", value, err)
+	}
+	return retried, nil
+}
@@ -0,0 +1,103 @@
+package isle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testQueuesValue = `{
+  "org.apache.activemq:brokerName=localhost,destinationName=islandora-connector-houdini,destinationType=Queue,type=Broker": {"QueueSize": 12, "ConsumerCount": 0, "EnqueueCount": 40, "DequeueCount": 28},
+  "org.apache.activemq:brokerName=localhost,destinationName=ActiveMQ.DLQ,destinationType=Queue,type=Broker": {"QueueSize": 3, "ConsumerCount": 0, "EnqueueCount": 3, "DequeueCount": 0},
+  "org.apache.activemq:brokerName=localhost,destinationName=islandora-indexing-fcrepo-content,destinationType=Queue,type=Broker": {"QueueSize": 0, "ConsumerCount": 1, "EnqueueCount": 9, "DequeueCount": 9}
+}`
+
+func TestParseJolokiaQueues(t *testing.T) {
+	queues, err := ParseJolokiaQueues([]byte(testQueuesValue))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queues) != 3 {
+		t.Fatalf("expected 3 queues, got %d", len(queues))
+	}
+	if queues[0].Name != "ActiveMQ.DLQ" || queues[1].Name != "islandora-connector-houdini" {
+		t.Errorf("expected queues sorted by name, got %v", queues)
+	}
+	houdini := queues[1]
+	if houdini.Pending != 12 || houdini.Consumers != 0 || houdini.Enqueued != 40 || houdini.Dequeued != 28 {
+		t.Errorf("unexpected counters %+v", houdini)
+	}
+	if !houdini.Stalled() || queues[2].Stalled() {
+		t.Error("expected only the queue without consumers to be stalled")
+	}
+}
+
+func TestIsIslandoraQueue(t *testing.T) {
+	for name, want := range map[string]bool{
+		"islandora-connector-houdini":  true,
+		"ActiveMQ.DLQ":                 true,
+		"DLQ.islandora-indexing-solr":  true,
+		"some-other-application-queue": false,
+	} {
+		if got := IsIslandoraQueue(name); got != want {
+			t.Errorf("IsIslandoraQueue(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestActiveMQOperations(t *testing.T) {
+	operations := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Origin") == "" {
+			w.Write([]byte(`{"status": 403, "error": "Origin required"}`))
+			return
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		switch req["type"] {
+		case "read":
+			w.Write([]byte(`{"status": 200, "value": ` + testQueuesValue + `}`))
+		case "exec":
+			operations = append(operations, req["operation"].(string)+" "+mbeanProperty(req["mbean"].(string), "destinationName"))
+			if req["operation"] == "retryMessages()" {
+				w.Write([]byte(`{"status": 200, "value": 3}`))
+				return
+			}
+			w.Write([]byte(`{"status": 200, "value": null}`))
+		}
+	}))
+	defer server.Close()
+
+	a := &ActiveMQ{Client: server.Client(), BaseURL: server.URL, User: "admin", Password: "secret"}
+	ctx := context.Background()
+
+	purged, err := a.Purge(ctx, "islandora-connector-houdini")
+	if err != nil || purged != 12 {
+		t.Fatalf("Purge() = %d, %v", purged, err)
+	}
+	retried, err := a.Redrive(ctx, DeadLetterQueue)
+	if err != nil || retried != 3 {
+		t.Fatalf("Redrive() = %d, %v", retried, err)
+	}
+	if _, err := a.Purge(ctx, "missing"); err == nil {
+		t.Error("expected an error for a missing queue")
+	}
+	want := []string{"purge() islandora-connector-houdini", "retryMessages() ActiveMQ.DLQ"}
+	if len(operations) != 2 || operations[0] != want[0] || operations[1] != want[1] {
+		t.Errorf("operations = %v, want %v", operations, want)
+	}
+
+	a.Password = "wrong"
+	if _, err := a.Queues(ctx); err == nil {
+		t.Error("expected an error with the wrong password")
+	}
+}
@@ -20,28 +20,15 @@ func GetUris(c *config.Context) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
+	defer cli.Close()
 
-	containerName, err := cli.GetContainerName(c, "mariadb", false)
+	ctx := context.Background()
+	creds, err := cli.DBCredentials(ctx, c)
 	if err != nil {
 		return "", "", err
 	}
-	ctx := context.Background()
 
-	vars := []string{
-		"DB_ROOT_USER",
-		"DB_ROOT_PASSWORD",
-		"DB_MYSQL_HOST",
-		"DB_MYSQL_PORT",
-	}
-	envs := make(map[string]string, len(vars))
-	for _, v := range vars {
-		envs[v], err = GetSecret(ctx, cli.CLI, c, containerName, v)
-		if err != nil {
-			return "", "", err
-		}
-	}
-
-	mysqlUri := fmt.Sprintf("mysql://%s:%s@", envs["DB_ROOT_USER"], envs["DB_ROOT_PASSWORD"])
+	mysqlUri := fmt.Sprintf("mysql://%s:%s@", creds.User, creds.Password)
 	sshUri := fmt.Sprintf("ssh_host=%s&ssh_port=%d&ssh_user=%s", c.SSHHostname, c.SSHPort, c.SSHUser)
 	switch c.DockerHostType {
 	case config.ContextLocal:
@@ -54,11 +41,15 @@ func GetUris(c *config.Context) (string, string, error) {
 			return "", "", err
 		}
 		// for local contexts, we'll SSH into codeserver and use docker internal DNS to access mariadb
-		mysqlUri = mysqlUri + fmt.Sprintf("%s:%s/%s", envs["DB_MYSQL_HOST"], envs["DB_MYSQL_PORT"], fmt.Sprintf("drupal_%s", c.Site))
+		mysqlUri = mysqlUri + fmt.Sprintf("%s:%s/%s", creds.Host, creds.Port, creds.Database)
 		sshUri = sshUri + fmt.Sprintf("&ssh_password=%s", idePass)
 	case config.ContextRemote:
 		// on remote hosts we need to get the IP address
 		// mariadb is exposed at in the network namespace
+		containerName, err := cli.GetContainerName(c, "mariadb", false)
+		if err != nil {
+			return "", "", err
+		}
 		serviceIp, err := cli.GetServiceIp(ctx, c, containerName)
 		if err != nil {
 			return "", "", err
@@ -66,7 +57,7 @@ func GetUris(c *config.Context) (string, string, error) {
 
 		// for remote contexts, we'll SSH into the remote server
 		// and use the docker network namespace IP:port for mariadb
-		mysqlUri = mysqlUri + fmt.Sprintf("%s:%s/%s", serviceIp, envs["DB_MYSQL_PORT"], fmt.Sprintf("drupal_%s", c.Site))
+		mysqlUri = mysqlUri + fmt.Sprintf("%s:%s/%s", serviceIp, creds.Port, creds.Database)
 		sshUri = sshUri + fmt.Sprintf("&ssh_keyLocation=%s&ssh_keyLocationEnabled=1", c.SSHKeyPath)
 	}
 
@@ -0,0 +1,245 @@
+package isle
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// CertExpiryWarning is how long before a certificate expires to start warning about it.
+const CertExpiryWarning = 14 * 24 * time.Hour
+
+// DefaultACMEStorage is where traefik keeps ACME certificates when its resolver doesn't set a storage path.
+const DefaultACMEStorage = "acme.json"
+
+// CertFiles are the custom certificate and key traefik serves, relative to the project directory.
+type CertFiles struct {
+	Cert string
+	Key  string
+}
+
+// DefaultCertFiles are where isle-site-template keeps its custom certificate.
+var DefaultCertFiles = CertFiles{Cert: "certs/cert.pem", Key: "certs/privkey.pem"}
+
+// ServedCertificate is the certificate a server presents for a hostname.
+type ServedCertificate struct {
+	Host     string
+	Subject  string
+	Issuer   string
+	DNSNames []string
+	NotAfter time.Time
+	// VerifyErr is why the certificate isn't trusted for Host, e.g. it is self-signed
+	VerifyErr error
+}
+
+// Expired reports whether the certificate has expired.
+func (s ServedCertificate) Expired(now time.Time) bool {
+	return !now.Before(s.NotAfter)
+}
+
+// ExpiresSoon reports whether the certificate expires within CertExpiryWarning.
+func (s ServedCertificate) ExpiresSoon(now time.Time) bool {
+	return s.NotAfter.Sub(now) < CertExpiryWarning
+}
+
+// DialContextFunc opens a connection, like net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// FetchCertificate connects to addr and returns the certificate served for host.
+// Untrusted certificates are returned with VerifyErr set rather than failing.
+func FetchCertificate(ctx context.Context, dial DialContextFunc, addr, host string) (ServedCertificate, error) {
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return ServedCertificate{}, fmt.Errorf("unable to connect to %s: %v", addr, err)
+	}
+	defer conn.Close()
+
+	// verify separately so an untrusted certificate can still be inspected
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return ServedCertificate{}, fmt.Errorf("TLS handshake with %s failed: %v", addr, err)
+	}
+	chain := tlsConn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return ServedCertificate{}, fmt.Errorf("%s did not present a certificate", addr)
+	}
+
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, verifyErr := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+
+	return ServedCertificate{
+		Host:      host,
+		Subject:   leaf.Subject.CommonName,
+		Issuer:    leaf.Issuer.CommonName,
+		DNSNames:  leaf.DNSNames,
+		NotAfter:  leaf.NotAfter,
+		VerifyErr: verifyErr,
+	}, nil
+}
+
+// FetchSiteCertificate fetches the certificate served for host on port 443.
+// When the host can't be reached directly, or viaTunnel is set, traefik is asked for it through the tunnel instead.
+// It reports whether the tunnel was used.
+func FetchSiteCertificate(ctx context.Context, tunnel *Tunnel, host string, viaTunnel bool) (ServedCertificate, bool, error) {
+	addr := net.JoinHostPort(host, "443")
+	if !viaTunnel {
+		dialer := &net.Dialer{Timeout: 5 * time.Second}
+		cert, err := FetchCertificate(ctx, dialer.DialContext, addr, host)
+		if err == nil || tunnel == nil {
+			return cert, false, err
+		}
+		slog.Debug("Unable to fetch the certificate directly, trying the tunnel", "host", host, "err", err)
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return tunnel.DialService(ctx, "traefik", 443)
+	}
+	cert, err := FetchCertificate(ctx, dial, addr, host)
+	return cert, true, err
+}
+
+// CustomCertFiles returns the files the compose file gives traefik as the CERT_PUBLIC_KEY and CERT_PRIVATE_KEY secrets,
+// falling back to DefaultCertFiles.
+func CustomCertFiles(compose string) (CertFiles, error) {
+	files := DefaultCertFiles
+	secrets, err := ComposeSecretFiles(compose)
+	if err != nil {
+		return files, err
+	}
+	if file, ok := secrets["CERT_PUBLIC_KEY"]; ok {
+		files.Cert = path.Clean(file)
+	}
+	if file, ok := secrets["CERT_PRIVATE_KEY"]; ok {
+		files.Key = path.Clean(file)
+	}
+	return files, nil
+}
+
+// ValidateCertificate checks a PEM certificate chain and key belong together, haven't expired and cover hosts.
+// It returns the leaf certificate.
+func ValidateCertificate(certPEM, keyPEM []byte, hosts []string, now time.Time) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate or key: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate: %v", err)
+	}
+	if !now.Before(leaf.NotAfter) {
+		return nil, fmt.Errorf("the certificate expired on %s", leaf.NotAfter.Format(time.DateOnly))
+	}
+	uncovered := []string{}
+	for _, host := range hosts {
+		if err := leaf.VerifyHostname(host); err != nil {
+			uncovered = append(uncovered, host)
+		}
+	}
+	if len(uncovered) > 0 {
+		return nil, fmt.Errorf("the certificate is not valid for %s", strings.Join(uncovered, ", "))
+	}
+	return leaf, nil
+}
+
+// InstallCertificate writes a custom certificate and key into the project directory, over SFTP for remote contexts.
+func InstallCertificate(c *config.Context, files CertFiles, certPEM, keyPEM []byte) error {
+	if err := c.WriteSmallFile(path.Join(c.ProjectDir, files.Cert), string(certPEM)); err != nil {
+		return fmt.Errorf("unable to write %s: %v", files.Cert, err)
+	}
+	if err := c.WriteSmallFile(path.Join(c.ProjectDir, files.Key), string(keyPEM)); err != nil {
+		return fmt.Errorf("unable to write %s: %v", files.Key, err)
+	}
+	return nil
+}
+
+// ACMEStorage returns where traefik stores ACME certificates according to its command line arguments.
+// ok is false when no ACME certificate resolver is configured.
+func ACMEStorage(args []string) (storage string, ok bool) {
+	storage = DefaultACMEStorage
+	for _, arg := range args {
+		name, value, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		parts := strings.Split(strings.ToLower(name), ".")
+		if len(parts) < 4 || parts[0] != "certificatesresolvers" || parts[2] != "acme" {
+			continue
+		}
+		ok = true
+		if parts[3] == "storage" && value != "" {
+			storage = value
+		}
+	}
+	return storage, ok
+}
+
+// RemoveACMECertificates removes the certificates for hosts from the contents of traefik's acme.json,
+// so traefik requests new ones when it restarts. It returns the new contents and the main domains removed.
+func RemoveACMECertificates(data []byte, hosts []string) ([]byte, []string, error) {
+	var resolvers map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &resolvers); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse acme.json: %v", err)
+	}
+
+	removed := []string{}
+	for name, resolver := range resolvers {
+		raw, ok := resolver["Certificates"]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var certs []json.RawMessage
+		if err := json.Unmarshal(raw, &certs); err != nil {
+			return nil, nil, fmt.Errorf("unable to parse the certificates of resolver %s: %v", name, err)
+		}
+		kept := []json.RawMessage{}
+		for _, cert := range certs {
+			var entry struct {
+				Domain struct {
+					Main string   `json:"main"`
+					SANs []string `json:"sans"`
+				} `json:"domain"`
+			}
+			if err := json.Unmarshal(cert, &entry); err != nil {
+				return nil, nil, fmt.Errorf("unable to parse a certificate of resolver %s: %v", name, err)
+			}
+			domains := append([]string{entry.Domain.Main}, entry.Domain.SANs...)
+			if slices.ContainsFunc(domains, func(d string) bool { return slices.Contains(hosts, d) }) {
+				removed = append(removed, entry.Domain.Main)
+				continue
+			}
+			kept = append(kept, cert)
+		}
+		raw, err := json.Marshal(kept)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolver["Certificates"] = raw
+	}
+
+	output, err := json.MarshalIndent(resolvers, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(removed)
+	return output, removed, nil
+}
+
+// ProjectCertFiles returns the custom certificate files of the context's compose project.
+func ProjectCertFiles(c *config.Context) (CertFiles, error) {
+	compose, result := checkComposeFile(c)
+	if result.Status != CheckOK {
+		return DefaultCertFiles, fmt.Errorf("unable to read the compose file: %s", result.Detail)
+	}
+	return CustomCertFiles(compose)
+}
@@ -0,0 +1,193 @@
+package isle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func selfSignedCert(t *testing.T, notAfter time.Time, dnsNames ...string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestFetchCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+	cert, err := FetchCertificate(context.Background(), dial, "example.com:443", "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert.Host != "example.com" || !strings.Contains(strings.Join(cert.DNSNames, ","), "example.com") {
+		t.Errorf("unexpected certificate %+v", cert)
+	}
+	if cert.VerifyErr == nil {
+		t.Error("expected httptest's certificate not to be trusted")
+	}
+	if !server.Certificate().NotAfter.Equal(cert.NotAfter) {
+		t.Errorf("expected NotAfter %v, got %v", server.Certificate().NotAfter, cert.NotAfter)
+	}
+}
+
+func TestCustomCertFiles(t *testing.T) {
+	files, err := CustomCertFiles(`
+secrets:
+  CERT_PUBLIC_KEY:
+    file: ./certs/fullchain.pem
+  CERT_PRIVATE_KEY:
+    file: ./certs/key.pem
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files.Cert != "certs/fullchain.pem" || files.Key != "certs/key.pem" {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	files, err = CustomCertFiles("services: {}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files != DefaultCertFiles {
+		t.Errorf("expected the default files, got %+v", files)
+	}
+}
+
+func TestValidateCertificate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	certPEM, keyPEM := selfSignedCert(t, now.Add(90*24*time.Hour), "islandora.example.com", "*.example.com")
+	_, otherKeyPEM := selfSignedCert(t, now.Add(90*24*time.Hour), "islandora.example.com")
+	expiredPEM, expiredKeyPEM := selfSignedCert(t, now.Add(-time.Hour), "islandora.example.com")
+
+	tests := []struct {
+		name    string
+		cert    []byte
+		key     []byte
+		hosts   []string
+		wantErr string
+	}{
+		{name: "valid", cert: certPEM, key: keyPEM, hosts: []string{"islandora.example.com", "history.example.com"}},
+		{name: "mismatched key", cert: certPEM, key: otherKeyPEM, wantErr: "invalid certificate or key"},
+		{name: "expired", cert: expiredPEM, key: expiredKeyPEM, wantErr: "expired on 2025-02-28"},
+		{name: "wrong host", cert: certPEM, key: keyPEM, hosts: []string{"islandora.example.org"}, wantErr: "not valid for islandora.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf, err := ValidateCertificate(tt.cert, tt.key, tt.hosts, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if leaf.Subject.CommonName != "islandora.example.com" {
+				t.Errorf("unexpected leaf %v", leaf.Subject)
+			}
+		})
+	}
+}
+
+func TestACMEStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		storage string
+		ok      bool
+	}{
+		{name: "no resolver", args: []string{"--entryPoints.https.address=:443", "--providers.docker=true"}, storage: DefaultACMEStorage},
+		{name: "storage", args: []string{"--certificatesresolvers.myresolver.acme.email=admin@example.com", "--certificatesResolvers.myresolver.acme.storage=/acme/acme.json"}, storage: "/acme/acme.json", ok: true},
+		{name: "default storage", args: []string{"--certificatesresolvers.le.acme.httpchallenge=true"}, storage: DefaultACMEStorage, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, ok := ACMEStorage(tt.args)
+			if storage != tt.storage || ok != tt.ok {
+				t.Errorf("ACMEStorage() = %q, %v, want %q, %v", storage, ok, tt.storage, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRemoveACMECertificates(t *testing.T) {
+	acme := `{
+  "myresolver": {
+    "Account": {"Email": "admin@example.com"},
+    "Certificates": [
+      {"domain": {"main": "islandora.example.com"}, "certificate": "Y2VydA==", "key": "a2V5", "Store": "default"},
+      {"domain": {"main": "example.com", "sans": ["history.example.com"]}, "certificate": "Y2VydA==", "key": "a2V5", "Store": "default"},
+      {"domain": {"main": "other.example.org"}, "certificate": "Y2VydA==", "key": "a2V5", "Store": "default"}
+    ]
+  },
+  "empty": {"Account": null, "Certificates": null}
+}`
+	updated, removed, err := RemoveACMECertificates([]byte(acme), []string{"history.example.com", "islandora.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(removed, ",") != "example.com,islandora.example.com" {
+		t.Errorf("unexpected removed domains %v", removed)
+	}
+
+	var result map[string]struct {
+		Account      map[string]string
+		Certificates []struct {
+			Domain struct {
+				Main string `json:"main"`
+			} `json:"domain"`
+			Certificate string `json:"certificate"`
+		}
+	}
+	if err := json.Unmarshal(updated, &result); err != nil {
+		t.Fatalf("unable to parse the updated acme.json: %v", err)
+	}
+	certs := result["myresolver"].Certificates
+	if len(certs) != 1 || certs[0].Domain.Main != "other.example.org" || certs[0].Certificate != "Y2VydA==" {
+		t.Errorf("unexpected remaining certificates %+v", certs)
+	}
+	if result["myresolver"].Account["Email"] != "admin@example.com" {
+		t.Errorf("expected the account to be kept, got %+v", result["myresolver"].Account)
+	}
+
+	if _, _, err := RemoveACMECertificates([]byte("not json"), nil); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,128 @@
+package isle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// DrupalRoot is the composer project directory in the drupal container.
+const DrupalRoot = "/var/www/drupal"
+
+// OutdatedPackage is a package from composer outdated.
+type OutdatedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Latest  string `json:"latest"`
+	// Status is semver-safe-update, update-possible or up-to-date
+	Status string `json:"latest-status"`
+}
+
+// Advisory is a security advisory from composer audit.
+type Advisory struct {
+	Package          string `json:"packageName"`
+	Title            string `json:"title"`
+	CVE              string `json:"cve"`
+	Severity         string `json:"severity"`
+	Link             string `json:"link"`
+	AffectedVersions string `json:"affectedVersions"`
+}
+
+// ComposerReport is the outdated packages and security advisories of a context.
+type ComposerReport struct {
+	Outdated   []OutdatedPackage
+	Advisories []Advisory
+}
+
+// ParseComposerOutdated parses the output of composer outdated --format=json.
+func ParseComposerOutdated(data []byte) ([]OutdatedPackage, error) {
+	var out struct {
+		Installed []OutdatedPackage `json:"installed"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("unable to parse composer outdated output: %v", err)
+	}
+	return out.Installed, nil
+}
+
+// ParseComposerAudit parses the output of composer audit --format=json.
+func ParseComposerAudit(data []byte) ([]Advisory, error) {
+	var out struct {
+		Advisories json.RawMessage `json:"advisories"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("unable to parse composer audit output: %v", err)
+	}
+
+	// composer encodes no advisories as an empty list instead of an object
+	byPackage := map[string][]Advisory{}
+	if len(out.Advisories) > 0 && out.Advisories[0] == '{' {
+		if err := json.Unmarshal(out.Advisories, &byPackage); err != nil {
+			return nil, fmt.Errorf("unable to parse composer audit advisories: %v", err)
+		}
+	}
+
+	packages := make([]string, 0, len(byPackage))
+	for name := range byPackage {
+		packages = append(packages, name)
+	}
+	slices.Sort(packages)
+	advisories := []Advisory{}
+	for _, name := range packages {
+		for _, advisory := range byPackage[name] {
+			if advisory.Package == "" {
+				advisory.Package = name
+			}
+			advisories = append(advisories, advisory)
+		}
+	}
+
+	return advisories, nil
+}
+
+// ComposerReport runs composer outdated and composer audit in the drupal container.
+func (d *DockerClient) ComposerReport(c *config.Context) (ComposerReport, error) {
+	drupalContainer, err := d.drupalContainer(c)
+	if err != nil {
+		return ComposerReport{}, err
+	}
+
+	outdated, err := composerJSON(c, drupalContainer, "composer outdated --direct --format=json --no-interaction")
+	if err != nil {
+		return ComposerReport{}, err
+	}
+	// composer audit exits non-zero when it finds advisories
+	audit, err := composerJSON(c, drupalContainer, "composer audit --locked --format=json --no-interaction || true")
+	if err != nil {
+		return ComposerReport{}, err
+	}
+
+	var report ComposerReport
+	if report.Outdated, err = ParseComposerOutdated(outdated); err != nil {
+		return ComposerReport{}, err
+	}
+	if report.Advisories, err = ParseComposerAudit(audit); err != nil {
+		return ComposerReport{}, err
+	}
+
+	return report, nil
+}
+
+func composerJSON(c *config.Context, containerName, script string) ([]byte, error) {
+	cmd := exec.Command("docker", "exec", "-w", DrupalRoot, containerName, "sh", "-c", script)
+	cmd.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(cmd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip anything composer printed before the JSON, e.g. plugin warnings
+	if start := strings.Index(output, "{"); start > 0 {
+		output = output[start:]
+	}
+	return []byte(output), nil
+}
@@ -0,0 +1,105 @@
+package isle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseComposerOutdated(t *testing.T) {
+	data := []byte(`{
+    "installed": [
+        {
+            "name": "drupal/core-recommended",
+            "direct-dependency": true,
+            "homepage": null,
+            "source": "https://github.com/drupal/core-recommended/tree/10.2.1",
+            "version": "10.2.1",
+            "latest": "10.2.5",
+            "latest-status": "semver-safe-update",
+            "description": "Core and its dependencies with known-compatible minor versions.",
+            "abandoned": false
+        },
+        {
+            "name": "drupal/islandora",
+            "version": "2.8.0",
+            "latest": "3.0.0",
+            "latest-status": "update-possible"
+        }
+    ]
+}`)
+	packages, err := ParseComposerOutdated(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []OutdatedPackage{
+		{Name: "drupal/core-recommended", Version: "10.2.1", Latest: "10.2.5", Status: "semver-safe-update"},
+		{Name: "drupal/islandora", Version: "2.8.0", Latest: "3.0.0", Status: "update-possible"},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expected %+v, got %+v", expected, packages)
+	}
+
+	if _, err := ParseComposerOutdated([]byte("Composer could not find a composer.json")); err == nil {
+		t.Errorf("expected an error for non JSON output")
+	}
+}
+
+func TestParseComposerAudit(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Advisory
+	}{
+		{
+			name: "no advisories",
+			data: `{"advisories": [], "abandoned": []}`,
+			want: []Advisory{},
+		},
+		{
+			name: "advisories",
+			data: `{
+    "advisories": {
+        "drupal/core": [
+            {
+                "advisoryId": "SA-CORE-2024-001",
+                "packageName": "drupal/core",
+                "affectedVersions": ">=10.2.0 <10.2.2",
+                "title": "Drupal core - Moderately critical - Denial of Service",
+                "cve": "CVE-2024-1234",
+                "link": "https://www.drupal.org/sa-core-2024-001",
+                "severity": "medium"
+            }
+        ],
+        "drupal/captcha": [
+            {
+                "title": "CAPTCHA - Critical - Bypass",
+                "link": "https://www.drupal.org/sa-contrib-2024-002"
+            }
+        ]
+    }
+}`,
+			want: []Advisory{
+				{Package: "drupal/captcha", Title: "CAPTCHA - Critical - Bypass", Link: "https://www.drupal.org/sa-contrib-2024-002"},
+				{
+					Package:          "drupal/core",
+					Title:            "Drupal core - Moderately critical - Denial of Service",
+					CVE:              "CVE-2024-1234",
+					Severity:         "medium",
+					Link:             "https://www.drupal.org/sa-core-2024-001",
+					AffectedVersions: ">=10.2.0 <10.2.2",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseComposerAudit([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package isle
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+)
+
+// DBCredentials holds what is needed to connect to a context's Drupal database.
+type DBCredentials struct {
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Database string
+}
+
+// DSNFormats are the connection string formats supported by DBCredentials.DSN.
+var DSNFormats = []string{"uri", "jdbc", "go", "pdo", "mysql"}
+
+// DatabaseName returns the Drupal database name for a multisite.
+func DatabaseName(site string) string {
+	if site == "" {
+		site = "default"
+	}
+	return fmt.Sprintf("drupal_%s", site)
+}
+
+// DBCredentials discovers the database root credentials from the mariadb service
+// using its docker secrets or environment.
+func (d *DockerClient) DBCredentials(ctx context.Context, c *config.Context) (DBCredentials, error) {
+	containerName, err := d.GetContainerName(c, "mariadb", false)
+	if err != nil {
+		return DBCredentials{}, err
+	}
+	if containerName == "" {
+		return DBCredentials{}, fmt.Errorf("no running mariadb container found for context %q", c.Name)
+	}
+
+	vars := []string{
+		"DB_ROOT_USER",
+		"DB_ROOT_PASSWORD",
+		"DB_MYSQL_HOST",
+		"DB_MYSQL_PORT",
+	}
+	envs := make(map[string]string, len(vars))
+	for _, v := range vars {
+		envs[v], err = GetSecret(ctx, d.CLI, c, containerName, v)
+		if err != nil {
+			return DBCredentials{}, err
+		}
+	}
+
+	return DBCredentials{
+		User:     strings.TrimSpace(envs["DB_ROOT_USER"]),
+		Password: strings.TrimSpace(envs["DB_ROOT_PASSWORD"]),
+		Host:     envs["DB_MYSQL_HOST"],
+		Port:     strings.TrimSpace(envs["DB_MYSQL_PORT"]),
+		Database: DatabaseName(c.Site),
+	}, nil
+}
+
+// DSN renders the credentials as a connection string.
+// See DSNFormats for the supported formats.
+func (cr DBCredentials) DSN(format string) (string, error) {
+	hostPort := fmt.Sprintf("%s:%s", cr.Host, cr.Port)
+	switch format {
+	case "uri":
+		u := url.URL{
+			Scheme: "mysql",
+			User:   url.UserPassword(cr.User, cr.Password),
+			Host:   hostPort,
+			Path:   "/" + cr.Database,
+		}
+		if cr.Password == "" {
+			u.User = url.User(cr.User)
+		}
+		return u.String(), nil
+	case "jdbc":
+		q := url.Values{}
+		q.Set("user", cr.User)
+		q.Set("password", cr.Password)
+		return fmt.Sprintf("jdbc:mariadb://%s/%s?%s", hostPort, cr.Database, q.Encode()), nil
+	case "go":
+		return fmt.Sprintf("%s:%s@tcp(%s)/%s", cr.User, cr.Password, hostPort, cr.Database), nil
+	case "pdo":
+		return fmt.Sprintf("mysql:host=%s;port=%s;dbname=%s", cr.Host, cr.Port, cr.Database), nil
+	case "mysql":
+		// MYSQL_PWD keeps the password out of ps, unlike -p
+		return "MYSQL_PWD=" + shellquote.Join(cr.Password) + " " + shellquote.Join("mysql", "-h", cr.Host, "-P", cr.Port, "-u", cr.User, cr.Database), nil
+	}
+
+	return "", fmt.Errorf("unknown DSN format %q. Valid formats are %s", format, strings.Join(DSNFormats, ", "))
+}
+
+// mariadbScript runs the mariadb client inside the mariadb container with the root
+// credentials read from the container's own secrets (or env), so the password never
+// appears on the command line of the host or in islectl's logs.
+const mariadbScript = `export MYSQL_PWD="$(cat /run/secrets/DB_ROOT_PASSWORD 2>/dev/null || printf %s "$DB_ROOT_PASSWORD")"
+exec mariadb -u"$(cat /run/secrets/DB_ROOT_USER 2>/dev/null || printf %s "${DB_ROOT_USER:-root}")" "$@"`
+
+// MariaDBExecArgs returns the "docker exec" arguments to run the mariadb client
+// in containerName against database. Extra args are passed to the client.
+func MariaDBExecArgs(containerName, database string, interactive bool, args ...string) []string {
+	execFlag := "-i"
+	if interactive {
+		execFlag = "-it"
+	}
+	cmdArgs := []string{
+		"exec",
+		execFlag,
+		containerName,
+		"sh",
+		"-c",
+		mariadbScript,
+		"mariadb",
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	return append(cmdArgs, database)
+}
+
+// ParseBatchOutput parses the tab separated output of "mariadb --batch" into a header and rows.
+func ParseBatchOutput(output string) ([]string, [][]string) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil, nil
+	}
+
+	lines := strings.Split(output, "\n")
+	header := splitBatchLine(lines[0])
+	rows := make([][]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		rows = append(rows, splitBatchLine(line))
+	}
+
+	return header, rows
+}
+
+// batchUnescaper reverses the escaping mariadb --batch applies to values
+var batchUnescaper = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\0`, "\x00")
+
+func splitBatchLine(line string) []string {
+	fields := strings.Split(strings.TrimSuffix(line, "\r"), "\t")
+	for i, field := range fields {
+		fields[i] = batchUnescaper.Replace(field)
+	}
+	return fields
+}
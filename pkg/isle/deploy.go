@@ -0,0 +1,187 @@
+package isle
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// DeployFile is the optional file in the project directory defining the deploy pipeline.
+const DeployFile = "islectl-deploy.yml"
+
+// BackupDir is where the backup deploy step keeps database dumps, relative to the project directory.
+const BackupDir = ".islectl/backups"
+
+// DefaultDeploySteps is the pipeline used when neither the context nor the project define one.
+var DefaultDeploySteps = []string{
+	"maintenance-on",
+	SnapshotStep,
+	"git-pull",
+	"build",
+	"up",
+	"updb",
+	"cim",
+	"cr",
+	"maintenance-off",
+}
+
+// DeployStepNames are the built in deploy steps. Steps can also be
+// drush:ARGS, exec:SCRIPT (in the drupal container) or host:SCRIPT (in the project directory).
+var DeployStepNames = []string{
+	"backup",
+	"build",
+	"cim",
+	"cr",
+	"deploy",
+	"git-pull",
+	"maintenance-off",
+	"maintenance-on",
+	"pull",
+	SnapshotStep,
+	"up",
+	"updb",
+}
+
+// sqlDumpArgs are the drush arguments every database backup starts with,
+// leaving out the data of the cache and log tables.
+func sqlDumpArgs() []string {
+	return []string{
+		"sql-dump",
+		"--skip-tables-list=cache,cache_*,watchdog",
+		"--structure-tables-list=cache,cache_*,watchdog",
+	}
+}
+
+// SQLDumpArgs are the drush arguments to back up a site's database in the drupal container,
+// and the file the dump is written to.
+func SQLDumpArgs(site Site) ([]string, string) {
+	resultFile := "/tmp/db.tar.gz"
+	if site.Name != DefaultSite {
+		resultFile = fmt.Sprintf("/tmp/db-%s.tar.gz", site.Name)
+	}
+
+	return append(sqlDumpArgs(), "-y", "--debug", "--gzip", "--result-file="+resultFile), resultFile
+}
+
+// ComposeArgs are the docker arguments to run a docker compose command with the context's profile and env files.
+func ComposeArgs(c *config.Context, args ...string) []string {
+	cmdArgs := []string{"compose", "--profile", c.Profile}
+	for _, env := range c.EnvFile {
+		cmdArgs = append(cmdArgs, "--env-file", env)
+	}
+	return append(cmdArgs, args...)
+}
+
+// DrupalExecArgs are the docker arguments to run a shell script in the drupal service.
+func DrupalExecArgs(c *config.Context, script string) []string {
+	return ComposeArgs(c, "exec", "-T", fmt.Sprintf("drupal-%s", c.Profile), "bash", "-c", script)
+}
+
+// DeployCommand returns the command line for a deploy step.
+func DeployCommand(c *config.Context, site Site, step string) ([]string, error) {
+	docker := func(args ...string) []string {
+		return append([]string{"docker"}, ComposeArgs(c, args...)...)
+	}
+	inDrupal := func(script string) []string {
+		return append([]string{"docker"}, DrupalExecArgs(c, script)...)
+	}
+	drush := func(args ...string) []string {
+		return inDrupal(DrushCommand(c, site, args...))
+	}
+
+	kind, arg, hasArg := strings.Cut(step, ":")
+	if hasArg {
+		if strings.TrimSpace(arg) == "" {
+			return nil, fmt.Errorf("deploy step %q is missing its command", step)
+		}
+		switch kind {
+		case "drush":
+			args, err := shellquote.Split(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid deploy step %q: %v", step, err)
+			}
+			return drush(args...), nil
+		case "exec":
+			return inDrupal(arg), nil
+		case "host":
+			return []string{"sh", "-c", arg}, nil
+		}
+		return nil, fmt.Errorf("unknown deploy step %q", step)
+	}
+
+	switch step {
+	case "backup":
+		// dump to the host, the drupal container and its /tmp are replaced by the build and up steps
+		dir := path.Join(c.ProjectDir, BackupDir)
+		dest := shellquote.Join(path.Join(dir, site.Name)) + `-"$(date -u +%Y%m%d-%H%M%S)".sql.gz`
+		return []string{"sh", "-c", hostDumpScript(c, site, dir, dest)}, nil
+	case "build":
+		return docker("build", "--pull"), nil
+	case "cim":
+		return drush("config:import", "-y"), nil
+	case "cr":
+		return drush("cache:rebuild"), nil
+	case "deploy":
+		return drush("deploy", "-y"), nil
+	case "git-pull":
+		return []string{"git", "pull", "--ff-only"}, nil
+	case "maintenance-off":
+		return inDrupal(MaintenanceScript(c, site, false)), nil
+	case "maintenance-on":
+		return inDrupal(MaintenanceScript(c, site, true)), nil
+	case "pull":
+		return docker("pull"), nil
+	case SnapshotStep:
+		return nil, fmt.Errorf("the %s step is run by islectl, not in a shell", SnapshotStep)
+	case "up":
+		return docker("up", "-d", "--remove-orphans"), nil
+	case "updb":
+		return drush("updatedb", "-y"), nil
+	}
+
+	return nil, fmt.Errorf("unknown deploy step %q. Valid steps are %s, drush:ARGS, exec:SCRIPT and host:SCRIPT", step, strings.Join(DeployStepNames, ", "))
+}
+
+// ParseDeployFile parses the steps of a deploy file.
+func ParseDeployFile(data string) ([]string, error) {
+	var file struct {
+		Steps []string `yaml:"steps"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &file); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", DeployFile, err)
+	}
+	return file.Steps, nil
+}
+
+// DeploySteps returns the pipeline to run and where it came from.
+// The first of override, the context's deploy-steps, the project's deploy file and the default pipeline is used.
+func DeploySteps(c *config.Context, override []string) ([]string, string, error) {
+	if len(override) > 0 {
+		return override, "--steps", nil
+	}
+	if len(c.DeploySteps) > 0 {
+		return c.DeploySteps, fmt.Sprintf("context %s", c.Name), nil
+	}
+
+	files, err := c.ListDir(c.ProjectDir)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read project directory %s: %v", c.ProjectDir, err)
+	}
+	if slices.Contains(files, DeployFile) {
+		filename := path.Join(c.ProjectDir, DeployFile)
+		steps, err := ParseDeployFile(c.ReadSmallFile(filename))
+		if err != nil {
+			return nil, "", err
+		}
+		if len(steps) > 0 {
+			return steps, filename, nil
+		}
+	}
+
+	return DefaultDeploySteps, "default pipeline", nil
+}
@@ -0,0 +1,106 @@
+package isle
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestDeployCommand(t *testing.T) {
+	c := &config.Context{Profile: "prod", EnvFile: []string{".env.prod"}}
+	compose := []string{"docker", "compose", "--profile", "prod", "--env-file", ".env.prod"}
+	exec := append(compose, "exec", "-T", "drupal-prod", "bash", "-c")
+	with := func(base []string, args ...string) []string {
+		return append(append([]string{}, base...), args...)
+	}
+
+	tests := []struct {
+		step    string
+		want    []string
+		wantErr bool
+	}{
+		{step: "git-pull", want: []string{"git", "pull", "--ff-only"}},
+		{step: "build", want: with(compose, "build", "--pull")},
+		{step: "up", want: with(compose, "up", "-d", "--remove-orphans")},
+		{step: "updb", want: with(exec, `drush --uri="$DRUPAL_DRUSH_URI" updatedb -y`)},
+		{step: "maintenance-on", want: with(exec, `drush --uri="$DRUPAL_DRUSH_URI" state:set system.maintenance_mode 1 --input-format=integer && drush --uri="$DRUPAL_DRUSH_URI" cache:rebuild`)},
+		{step: "drush:search-api:index --batch-size=50", want: with(exec, `drush --uri="$DRUPAL_DRUSH_URI" search-api:index --batch-size=50`)},
+		{step: "exec:composer install --no-dev", want: with(exec, "composer install --no-dev")},
+		{step: "host:./scripts/notify.sh deployed", want: []string{"sh", "-c", "./scripts/notify.sh deployed"}},
+		{step: "migrate", wantErr: true},
+		{step: "drush:", wantErr: true},
+		{step: "ssh:whoami", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			got, err := DeployCommand(c, Site{Name: DefaultSite}, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeployCommand(%q) error = %v, wantErr %v", tt.step, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeployCommand(%q)\n got %q\nwant %q", tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployBackupStep(t *testing.T) {
+	c := &config.Context{Profile: "prod", ProjectDir: "/srv/isle"}
+	got, err := DeployCommand(c, Site{Name: DefaultSite}, "backup")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0] != "sh" {
+		t.Fatalf("expected a host script, got %q", got)
+	}
+	script := got[2]
+	for _, want := range []string{"sql-dump", "umask 077", `> /srv/isle/.islectl/backups/default-"$(date -u +%Y%m%d-%H%M%S)".sql.gz`} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected %q in %q", want, script)
+		}
+	}
+	if output, err := exec.Command("sh", "-n", "-c", script).CombinedOutput(); err != nil {
+		t.Errorf("invalid script %q: %v %s", script, err, output)
+	}
+}
+
+func TestDeploySteps(t *testing.T) {
+	projectDir := t.TempDir()
+	c := &config.Context{Name: "prod", DockerHostType: config.ContextLocal, ProjectDir: projectDir}
+
+	steps, source, err := DeploySteps(c, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(steps, DefaultDeploySteps) || source != "default pipeline" {
+		t.Errorf("expected the default pipeline, got %v from %s", steps, source)
+	}
+
+	deployFile := "steps:\n  - git-pull\n  - up\n  - drush:deploy -y\n"
+	if err := os.WriteFile(filepath.Join(projectDir, DeployFile), []byte(deployFile), 0644); err != nil {
+		t.Fatalf("unable to write deploy file: %v", err)
+	}
+	steps, _, err = DeploySteps(c, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(steps, []string{"git-pull", "up", "drush:deploy -y"}) {
+		t.Errorf("expected the project file's steps, got %v", steps)
+	}
+
+	c.DeploySteps = []string{"pull", "up"}
+	steps, _, _ = DeploySteps(c, nil)
+	if !reflect.DeepEqual(steps, c.DeploySteps) {
+		t.Errorf("expected the context's steps, got %v", steps)
+	}
+
+	steps, source, _ = DeploySteps(c, []string{"cr"})
+	if !reflect.DeepEqual(steps, []string{"cr"}) || source != "--steps" {
+		t.Errorf("expected the override, got %v from %s", steps, source)
+	}
+}
@@ -3,16 +3,22 @@ package isle
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/islandora-devops/islectl/pkg/config"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -20,16 +26,33 @@ import (
 type DockerAPI interface {
 	ContainerInspect(ctx context.Context, container string) (dockercontainer.InspectResponse, error)
 	ContainerList(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error)
+	ContainerCreate(ctx context.Context, config *dockercontainer.Config, hostConfig *dockercontainer.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (dockercontainer.CreateResponse, error)
+	ContainerStart(ctx context.Context, container string, options dockercontainer.StartOptions) error
+	ContainerRemove(ctx context.Context, container string, options dockercontainer.RemoveOptions) error
+	ContainerExecCreate(ctx context.Context, container string, options dockercontainer.ExecOptions) (dockercontainer.ExecCreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, config dockercontainer.ExecAttachOptions) (types.HijackedResponse, error)
+	ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error)
+	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+	CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, dockercontainer.PathStat, error)
+	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options dockercontainer.CopyToContainerOptions) error
 }
 
 type DockerClient struct {
 	CLI        DockerAPI
 	SshCli     *ssh.Client
 	httpClient *http.Client
+
+	relayMu    sync.Mutex
+	relayID    string
+	relayOwned bool
+	relayExecs map[string]struct{}
 }
 
 func (d *DockerClient) Close() error {
 	var firstErr error
+	if err := d.stopRelay(); err != nil {
+		firstErr = err
+	}
 	if d.SshCli != nil {
 		if err := d.SshCli.Close(); err != nil && firstErr == nil {
 			firstErr = err
@@ -81,6 +104,10 @@ func GetDockerCli(activeCtx *config.Context) (*DockerClient, error) {
 }
 
 func GetSecret(ctx context.Context, cli DockerAPI, c *config.Context, containerName, secretName string) (string, error) {
+	if value, ok := backendSecret(c, secretName); ok {
+		return value, nil
+	}
+
 	containerJSON, err := cli.ContainerInspect(ctx, containerName)
 	if err != nil {
 		return "", err
@@ -3,18 +3,24 @@ package isle
 import (
 	"context"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/islandora-devops/islectl/pkg/config"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // FakeDockerClient implements the DockerAPI interface for testing.
 type FakeDockerClient struct {
 	InspectFunc func(ctx context.Context, container string) (dockercontainer.InspectResponse, error)
+	ListFunc    func(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error)
+	NetworkFunc func(ctx context.Context, networkID string) (network.Inspect, error)
 }
 
 var _ DockerAPI = (*FakeDockerClient)(nil)
@@ -24,9 +30,51 @@ func (f *FakeDockerClient) ContainerInspect(ctx context.Context, container strin
 }
 
 func (f *FakeDockerClient) ContainerList(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error) {
+	if f.ListFunc != nil {
+		return f.ListFunc(ctx, options)
+	}
 	return nil, fmt.Errorf("Not implemented")
 }
 
+func (f *FakeDockerClient) ContainerCreate(ctx context.Context, config *dockercontainer.Config, hostConfig *dockercontainer.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (dockercontainer.CreateResponse, error) {
+	return dockercontainer.CreateResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) ContainerStart(ctx context.Context, container string, options dockercontainer.StartOptions) error {
+	return fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) ContainerRemove(ctx context.Context, container string, options dockercontainer.RemoveOptions) error {
+	return fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) ContainerExecCreate(ctx context.Context, container string, options dockercontainer.ExecOptions) (dockercontainer.ExecCreateResponse, error) {
+	return dockercontainer.ExecCreateResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) ContainerExecAttach(ctx context.Context, execID string, config dockercontainer.ExecAttachOptions) (types.HijackedResponse, error) {
+	return types.HijackedResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	if f.NetworkFunc != nil {
+		return f.NetworkFunc(ctx, networkID)
+	}
+	return network.Inspect{}, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) CopyFromContainer(ctx context.Context, container, srcPath string) (io.ReadCloser, dockercontainer.PathStat, error) {
+	return nil, dockercontainer.PathStat{}, fmt.Errorf("Not implemented")
+}
+
+func (f *FakeDockerClient) CopyToContainer(ctx context.Context, container, path string, content io.Reader, options dockercontainer.CopyToContainerOptions) error {
+	return fmt.Errorf("Not implemented")
+}
+
 func TestGetConfigEnv_VariableFound(t *testing.T) {
 	fake := &FakeDockerClient{
 		InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
@@ -138,6 +186,28 @@ func TestGetSecret_MountedSecret(t *testing.T) {
 	}
 }
 
+func TestGetSecret_Backend(t *testing.T) {
+	fake := &FakeDockerClient{
+		InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+			t.Fatal("the container should not be inspected when a backend has the secret")
+			return dockercontainer.InspectResponse{}, nil
+		},
+	}
+	t.Setenv("TEST_secretName", "backendSecret")
+	fakeConfig := &config.Context{
+		ProjectDir:     "/tmp/project",
+		ProjectName:    "test",
+		SecretBackends: []string{"env:TEST_"},
+	}
+	secret, err := GetSecret(context.Background(), fake, fakeConfig, "dummyContainer", "secretName")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret != "backendSecret" {
+		t.Errorf("expected %q, got %q", "backendSecret", secret)
+	}
+}
+
 func TestGetServiceIp(t *testing.T) {
 	fake := &FakeDockerClient{
 		InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
@@ -0,0 +1,526 @@
+package isle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/islandora-devops/islectl/internal/utils"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// CheckStatus is the outcome of a diagnostic check.
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckWarn    CheckStatus = "warn"
+	CheckFail    CheckStatus = "fail"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// CheckResult is the outcome of a diagnostic check and how to fix it.
+type CheckResult struct {
+	Name        string
+	Status      CheckStatus
+	Detail      string
+	Remediation string
+}
+
+// ComposeFiles are the names docker compose looks for in a project directory.
+var ComposeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// Disk and memory thresholds for the host checks.
+const (
+	diskWarnPercent     = 85
+	diskFailPercent     = 95
+	memoryWarnAvailable = 10
+)
+
+// checkTimeout bounds each check talking to docker or the sites, so an unresponsive
+// daemon fails its check instead of hanging doctor.
+var checkTimeout = 30 * time.Second
+
+// bounded runs check with its own checkTimeout.
+func bounded[T any](ctx context.Context, check func(context.Context) T) T {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	return check(ctx)
+}
+
+// Doctor runs diagnostics for a context. Checks that depend on a failed check are skipped,
+// as are the remaining checks once ctx is cancelled.
+func Doctor(ctx context.Context, c *config.Context, now time.Time) []CheckResult {
+	results := []CheckResult{}
+	add := func(r CheckResult) bool {
+		results = append(results, r)
+		return r.Status != CheckFail && r.Status != CheckSkipped
+	}
+	skip := func(reason string, names ...string) []CheckResult {
+		for _, name := range names {
+			results = append(results, CheckResult{Name: name, Status: CheckSkipped, Detail: reason})
+		}
+		return results
+	}
+	hostChecks := []string{"docker socket", "docker", "project dir", "compose file", "secrets", "services", "disk", "memory", "tls"}
+
+	var sshClient *ssh.Client
+	if c.DockerHostType == config.ContextRemote {
+		var result CheckResult
+		sshClient, result = checkSSH(c)
+		if !add(result) {
+			return skip("ssh failed", hostChecks...)
+		}
+		defer sshClient.Close()
+	}
+
+	dockerOK := add(checkDockerSocket(c, sshClient))
+	var cli *DockerClient
+	if dockerOK {
+		var result CheckResult
+		result = bounded(ctx, func(ctx context.Context) CheckResult {
+			cli, result = checkDocker(ctx, c)
+			return result
+		})
+		dockerOK = add(result)
+		if cli != nil {
+			defer cli.Close()
+		}
+	} else {
+		skip("docker socket failed", "docker")
+	}
+
+	if add(checkProjectDir(c)) {
+		compose, result := checkComposeFile(c)
+		if add(result) {
+			add(checkSecrets(c, compose))
+		} else {
+			skip("compose file failed", "secrets")
+		}
+	} else {
+		skip("project dir failed", "compose file", "secrets")
+	}
+
+	if dockerOK {
+		add(bounded(ctx, func(ctx context.Context) CheckResult { return checkServices(ctx, c, cli) }))
+	} else {
+		skip("docker failed", "services")
+	}
+
+	if ctx.Err() != nil {
+		return skip("interrupted", "disk", "memory", "tls")
+	}
+	add(checkDisk(c))
+	add(checkMemory(c))
+	for _, r := range checkCertificates(ctx, c, cli, now) {
+		add(r)
+	}
+
+	return results
+}
+
+func checkSSH(c *config.Context) (*ssh.Client, CheckResult) {
+	result := CheckResult{Name: "ssh"}
+	client, err := c.DialSSH()
+	if err == nil {
+		result.Status = CheckOK
+		result.Detail = fmt.Sprintf("connected to %s@%s:%d", c.SSHUser, c.SSHHostname, c.SSHPort)
+		return client, result
+	}
+
+	result.Status = CheckFail
+	result.Detail = err.Error()
+	var keyErr *knownhosts.KeyError
+	switch {
+	case errors.As(err, &keyErr) && len(keyErr.Want) == 0:
+		result.Remediation = fmt.Sprintf("The host key is not in known_hosts. Connect once with `ssh -p %d %s@%s` to verify and accept it", c.SSHPort, c.SSHUser, c.SSHHostname)
+	case errors.As(err, &keyErr):
+		result.Remediation = fmt.Sprintf("The host key changed. Verify the new key with the host's administrator, then run `ssh-keygen -R '[%s]:%d'` and connect once with ssh to accept it", c.SSHHostname, c.SSHPort)
+	case strings.Contains(err.Error(), "SSH key"):
+		result.Remediation = fmt.Sprintf("Check ssh-key in the context points at an unencrypted private key: islectl config set-context %s --ssh-key PATH", c.Name)
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		result.Remediation = fmt.Sprintf("The server rejected the key for %s. Check ssh-user and ssh-key in the context, or add the public key to the server with ssh-copy-id", c.SSHUser)
+	default:
+		result.Remediation = fmt.Sprintf("Check %s resolves and port %d is reachable from this machine", c.SSHHostname, c.SSHPort)
+	}
+	return nil, result
+}
+
+func checkDockerSocket(c *config.Context, sshClient *ssh.Client) CheckResult {
+	result := CheckResult{Name: "docker socket", Status: CheckOK, Detail: c.DockerSocket}
+	alive := false
+	if sshClient != nil {
+		if conn, err := sshClient.Dial("unix", c.DockerSocket); err == nil {
+			conn.Close()
+			alive = true
+		}
+	} else {
+		alive = config.IsDockerSocketAlive(c.DockerSocket)
+	}
+	if alive {
+		return result
+	}
+
+	result.Status = CheckFail
+	result.Detail = fmt.Sprintf("nothing is listening on %s", c.DockerSocket)
+	result.Remediation = fmt.Sprintf("Make sure docker is running, and that docker-socket in the context is right: islectl config set-context %s --docker-socket PATH", c.Name)
+	if sshClient != nil {
+		result.Remediation += fmt.Sprintf(". %s must be allowed to use the socket, e.g. by being in the docker group", c.SSHUser)
+	}
+	return result
+}
+
+func checkDocker(ctx context.Context, c *config.Context) (*DockerClient, CheckResult) {
+	result := CheckResult{Name: "docker"}
+	cli, err := GetDockerCli(c)
+	if err == nil {
+		_, err = cli.CLI.ContainerList(ctx, dockercontainer.ListOptions{Limit: 1})
+	}
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		result.Remediation = "The docker socket accepts connections but the docker API failed. Check the docker daemon's logs"
+		if cli != nil {
+			cli.Close()
+		}
+		return nil, result
+	}
+	result.Status = CheckOK
+	result.Detail = "docker API responded"
+	return cli, result
+}
+
+func checkProjectDir(c *config.Context) CheckResult {
+	result := CheckResult{Name: "project dir", Status: CheckOK, Detail: c.ProjectDir}
+	exists, err := c.ProjectDirExists()
+	if err != nil || !exists {
+		result.Status = CheckFail
+		result.Detail = fmt.Sprintf("%s does not exist", c.ProjectDir)
+		if err != nil {
+			result.Detail = err.Error()
+		}
+		result.Remediation = fmt.Sprintf("Set project-dir to the directory with the docker compose project: islectl config set-context %s --project-dir PATH", c.Name)
+	}
+	return result
+}
+
+func checkComposeFile(c *config.Context) (string, CheckResult) {
+	result := CheckResult{Name: "compose file"}
+	files, err := c.ListDir(c.ProjectDir)
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		return "", result
+	}
+	for _, name := range ComposeFiles {
+		if slices.Contains(files, name) {
+			result.Status = CheckOK
+			result.Detail = name
+			return c.ReadSmallFile(path.Join(c.ProjectDir, name)), result
+		}
+	}
+	result.Status = CheckFail
+	result.Detail = fmt.Sprintf("no docker-compose.yml in %s", c.ProjectDir)
+	result.Remediation = "Check project-dir is the root of your isle-site-template checkout"
+	return "", result
+}
+
+// ComposeSecretFiles returns the file of each secret defined in a compose file.
+// Secrets from the environment or external sources are left out.
+func ComposeSecretFiles(compose string) (map[string]string, error) {
+	var project struct {
+		Secrets map[string]struct {
+			File string `yaml:"file"`
+		} `yaml:"secrets"`
+	}
+	if err := yaml.Unmarshal([]byte(compose), &project); err != nil {
+		return nil, fmt.Errorf("unable to parse compose file: %v", err)
+	}
+	files := map[string]string{}
+	for name, secret := range project.Secrets {
+		if secret.File != "" {
+			files[name] = secret.File
+		}
+	}
+	return files, nil
+}
+
+// MissingSecrets returns the secrets whose file in the secrets directory doesn't exist, sorted by name.
+func MissingSecrets(files map[string]string, present []string) []string {
+	missing := []string{}
+	for name, file := range files {
+		if path.Dir(path.Clean(file)) != "secrets" {
+			continue
+		}
+		if !slices.Contains(present, path.Base(file)) {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+func checkSecrets(c *config.Context, compose string) CheckResult {
+	result := CheckResult{Name: "secrets"}
+	files, err := ComposeSecretFiles(compose)
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		return result
+	}
+	present, err := SecretNames(c)
+	if err != nil {
+		present = []string{}
+	}
+	missing := MissingSecrets(files, present)
+	if len(missing) > 0 {
+		result.Status = CheckFail
+		result.Detail = fmt.Sprintf("missing %s", strings.Join(missing, ", "))
+		result.Remediation = fmt.Sprintf("Create each missing secret with `islectl secrets set NAME --context %s`", c.Name)
+		return result
+	}
+	result.Status = CheckOK
+	result.Detail = fmt.Sprintf("%d secrets present", len(files))
+	return result
+}
+
+// ServiceProblems describes the containers that are not running or not healthy, sorted by service.
+func ServiceProblems(containers []dockercontainer.Summary) (problems []string, starting []string) {
+	for _, container := range containers {
+		if container.Labels["com.docker.compose.oneoff"] == "True" {
+			continue
+		}
+		service := container.Labels["com.docker.compose.service"]
+		switch {
+		case container.State != "running":
+			problems = append(problems, fmt.Sprintf("%s is %s", service, container.State))
+		case strings.Contains(container.Status, "(unhealthy)"):
+			problems = append(problems, service+" is unhealthy")
+		case strings.Contains(container.Status, "(health: starting)"):
+			starting = append(starting, service+" is starting")
+		}
+	}
+	slices.Sort(problems)
+	slices.Sort(starting)
+	return problems, starting
+}
+
+func checkServices(ctx context.Context, c *config.Context, cli *DockerClient) CheckResult {
+	result := CheckResult{Name: "services"}
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "com.docker.compose.project="+c.ProjectName)
+	containers, err := cli.CLI.ContainerList(ctx, dockercontainer.ListOptions{All: true, Filters: filterArgs})
+	if err != nil {
+		result.Status = CheckFail
+		result.Detail = err.Error()
+		return result
+	}
+	if len(containers) == 0 {
+		result.Status = CheckFail
+		result.Detail = fmt.Sprintf("no containers for project %s", c.ProjectName)
+		result.Remediation = fmt.Sprintf("Start the stack with `islectl compose up -d --context %s`, or check project-name matches COMPOSE_PROJECT_NAME", c.Name)
+		return result
+	}
+
+	problems, starting := ServiceProblems(containers)
+	switch {
+	case len(problems) > 0:
+		result.Status = CheckFail
+		result.Detail = strings.Join(problems, ", ")
+		result.Remediation = fmt.Sprintf("Check why with `islectl compose logs SERVICE --context %s`, then restart it with `islectl compose up -d SERVICE`", c.Name)
+	case len(starting) > 0:
+		result.Status = CheckWarn
+		result.Detail = strings.Join(starting, ", ")
+		result.Remediation = "Run islectl doctor again once the services have started"
+	default:
+		result.Status = CheckOK
+		result.Detail = fmt.Sprintf("%d containers running", len(containers))
+	}
+	return result
+}
+
+// ParseDiskFree returns the percentage used and bytes available from the output of df -Pk.
+func ParseDiskFree(output string) (int, int64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 6 {
+		return 0, 0, fmt.Errorf("unable to parse df output %q", output)
+	}
+	available, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse df output %q: %v", output, err)
+	}
+	used, err := strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse df output %q: %v", output, err)
+	}
+	return used, available * 1024, nil
+}
+
+func checkDisk(c *config.Context) CheckResult {
+	result := CheckResult{Name: "disk"}
+	command := exec.Command("df", "-Pk", c.ProjectDir)
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		result.Status = CheckSkipped
+		result.Detail = fmt.Sprintf("unable to run df: %v", err)
+		return result
+	}
+	used, available, err := ParseDiskFree(output)
+	if err != nil {
+		result.Status = CheckSkipped
+		result.Detail = err.Error()
+		return result
+	}
+
+	result.Detail = fmt.Sprintf("%d%% used, %s free", used, utils.FormatBytes(available))
+	switch {
+	case used >= diskFailPercent:
+		result.Status = CheckFail
+	case used >= diskWarnPercent:
+		result.Status = CheckWarn
+	default:
+		result.Status = CheckOK
+		return result
+	}
+	result.Remediation = "Free up space, e.g. remove unused images and build cache with `docker system prune` and old snapshots from " + SnapshotDir + " in the project"
+	return result
+}
+
+// ParseMemInfo returns the total and available bytes of memory from /proc/meminfo.
+func ParseMemInfo(output string) (int64, int64, error) {
+	values := map[string]int64{}
+	for _, line := range strings.Split(output, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[0], 10, 64)
+		if err == nil {
+			values[name] = kb * 1024
+		}
+	}
+	total, available := values["MemTotal"], values["MemAvailable"]
+	if total == 0 {
+		return 0, 0, fmt.Errorf("MemTotal not found in meminfo")
+	}
+	return total, available, nil
+}
+
+func checkMemory(c *config.Context) CheckResult {
+	result := CheckResult{Name: "memory"}
+	output, err := c.CaptureCommand(exec.Command("cat", "/proc/meminfo"), nil)
+	if err != nil {
+		result.Status = CheckSkipped
+		result.Detail = "/proc/meminfo is not available on this host"
+		return result
+	}
+	total, available, err := ParseMemInfo(output)
+	if err != nil {
+		result.Status = CheckSkipped
+		result.Detail = err.Error()
+		return result
+	}
+
+	result.Detail = fmt.Sprintf("%s of %s available", utils.FormatBytes(available), utils.FormatBytes(total))
+	if available*100/total < memoryWarnAvailable {
+		result.Status = CheckWarn
+		result.Remediation = "The host is low on memory. Check which containers use the most with `docker stats`, and consider lowering Solr's or Tomcat's heap sizes"
+		return result
+	}
+	result.Status = CheckOK
+	return result
+}
+
+// CertificateCheck reports whether a served certificate is trusted and not about to expire.
+func CertificateCheck(cert ServedCertificate, now time.Time) CheckResult {
+	result := CheckResult{Name: "tls " + cert.Host}
+	days := int(cert.NotAfter.Sub(now).Hours() / 24)
+	switch {
+	case cert.Expired(now):
+		result.Status = CheckFail
+		result.Detail = fmt.Sprintf("expired on %s", cert.NotAfter.Format(time.DateOnly))
+		result.Remediation = "Renew the certificate. With ACME, check Traefik's logs for why it wasn't renewed"
+	case cert.VerifyErr != nil:
+		result.Status = CheckWarn
+		result.Detail = fmt.Sprintf("not trusted: %v", cert.VerifyErr)
+		result.Remediation = "Expected for self-signed development certificates. Otherwise install a certificate for this hostname"
+	case cert.ExpiresSoon(now):
+		result.Status = CheckWarn
+		result.Detail = fmt.Sprintf("expires in %d days", days)
+		result.Remediation = "Renew the certificate soon. ACME certificates renew automatically 30 days before they expire, so this usually means renewal is failing"
+	default:
+		result.Status = CheckOK
+		result.Detail = fmt.Sprintf("expires in %d days", days)
+	}
+	return result
+}
+
+// SiteHostnames returns the hostnames of the context's sites from its UriMap, or the drupal container's DRUPAL_DRUSH_URI.
+func SiteHostnames(ctx context.Context, c *config.Context, cli *DockerClient) []string {
+	uris := []string{}
+	for _, uri := range c.UriMap {
+		uris = append(uris, uri)
+	}
+	if len(uris) == 0 && cli != nil {
+		if containerName, err := cli.drupalContainer(c); err == nil {
+			if uri, err := GetConfigEnv(ctx, cli.CLI, containerName, "DRUPAL_DRUSH_URI"); err == nil {
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	hosts := []string{}
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" || slices.Contains(hosts, u.Hostname()) {
+			continue
+		}
+		hosts = append(hosts, u.Hostname())
+	}
+	slices.Sort(hosts)
+	return hosts
+}
+
+func checkCertificates(ctx context.Context, c *config.Context, cli *DockerClient, now time.Time) []CheckResult {
+	hosts := bounded(ctx, func(ctx context.Context) []string { return SiteHostnames(ctx, c, cli) })
+	if len(hosts) == 0 {
+		return []CheckResult{{Name: "tls", Status: CheckSkipped, Detail: "no https site URLs found"}}
+	}
+
+	results := []CheckResult{}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	for _, host := range hosts {
+		fetchCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		cert, err := FetchCertificate(fetchCtx, dialer.DialContext, net.JoinHostPort(host, "443"), host)
+		cancel()
+		if err != nil {
+			results = append(results, CheckResult{
+				Name:        "tls " + host,
+				Status:      CheckFail,
+				Detail:      err.Error(),
+				Remediation: "Check traefik is running and port 443 is open to this machine",
+			})
+			continue
+		}
+		results = append(results, CertificateCheck(cert, now))
+	}
+	return results
+}
@@ -0,0 +1,174 @@
+package isle
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestMissingSecrets(t *testing.T) {
+	compose := `
+services:
+  drupal:
+    image: islandora/drupal
+secrets:
+  DB_ROOT_PASSWORD:
+    file: ./secrets/DB_ROOT_PASSWORD
+  JWT_PRIVATE_KEY:
+    file: secrets/JWT_PRIVATE_KEY
+  CERT_PUBLIC_KEY:
+    file: ./certs/cert.pem
+  FROM_ENV:
+    environment: SOME_VAR
+`
+	files, err := ComposeSecretFiles(compose)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Errorf("expected the file secrets, got %v", files)
+	}
+	missing := MissingSecrets(files, []string{"DB_ROOT_PASSWORD"})
+	if len(missing) != 1 || missing[0] != "JWT_PRIVATE_KEY" {
+		t.Errorf("expected JWT_PRIVATE_KEY to be missing, got %v", missing)
+	}
+
+	if _, err := ComposeSecretFiles("secrets: ["); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
+
+func TestServiceProblems(t *testing.T) {
+	container := func(service, state, status string) dockercontainer.Summary {
+		return dockercontainer.Summary{
+			Labels: map[string]string{"com.docker.compose.service": service},
+			State:  state,
+			Status: status,
+		}
+	}
+	oneOff := container("drupal-prod", "exited", "Exited (0) 1 hour ago")
+	oneOff.Labels["com.docker.compose.oneoff"] = "True"
+
+	problems, starting := ServiceProblems([]dockercontainer.Summary{
+		container("solr-prod", "running", "Up 2 hours (unhealthy)"),
+		container("mariadb-prod", "running", "Up 2 hours (healthy)"),
+		container("fcrepo-prod", "exited", "Exited (137) 5 minutes ago"),
+		container("drupal-prod", "running", "Up 10 seconds (health: starting)"),
+		oneOff,
+	})
+	if strings.Join(problems, ", ") != "fcrepo-prod is exited, solr-prod is unhealthy" {
+		t.Errorf("unexpected problems %v", problems)
+	}
+	if len(starting) != 1 || starting[0] != "drupal-prod is starting" {
+		t.Errorf("unexpected starting %v", starting)
+	}
+}
+
+func TestParseDiskFree(t *testing.T) {
+	output := `Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sda1         41152736 37037462   4115274      90% /
+`
+	used, available, err := ParseDiskFree(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if used != 90 || available != 4115274*1024 {
+		t.Errorf("ParseDiskFree() = %d, %d", used, available)
+	}
+	if _, _, err := ParseDiskFree("df: /missing: No such file or directory"); err == nil {
+		t.Error("expected an error for df's error message")
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	total, available, err := ParseMemInfo("MemTotal:       16318412 kB\nMemFree:          512000 kB\nMemAvailable:    8159206 kB\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 16318412*1024 || available != 8159206*1024 {
+		t.Errorf("ParseMemInfo() = %d, %d", total, available)
+	}
+	if _, _, err := ParseMemInfo(""); err == nil {
+		t.Error("expected an error without MemTotal")
+	}
+}
+
+func TestCertificateCheck(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cert := ServedCertificate{Host: "islandora.example.com", NotAfter: now.Add(60 * 24 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		modify func(*ServedCertificate)
+		status CheckStatus
+		detail string
+	}{
+		{name: "valid", modify: func(*ServedCertificate) {}, status: CheckOK, detail: "expires in 60 days"},
+		{name: "expiring", modify: func(c *ServedCertificate) { c.NotAfter = now.Add(3 * 24 * time.Hour) }, status: CheckWarn, detail: "expires in 3 days"},
+		{name: "expired", modify: func(c *ServedCertificate) { c.NotAfter = now.Add(-time.Hour) }, status: CheckFail, detail: "expired on 2025-02-28"},
+		{name: "untrusted", modify: func(c *ServedCertificate) { c.VerifyErr = errors.New("x509: certificate signed by unknown authority") }, status: CheckWarn, detail: "not trusted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cert
+			tt.modify(&c)
+			result := CertificateCheck(c, now)
+			if result.Name != "tls islandora.example.com" || result.Status != tt.status || !strings.Contains(result.Detail, tt.detail) {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+}
+
+func TestSiteHostnames(t *testing.T) {
+	c := &config.Context{UriMap: map[string]string{
+		"default": "https://islandora.example.com",
+		"history": "https://history.example.com",
+		"local":   "http://localhost:8080",
+	}}
+	hosts := SiteHostnames(context.Background(), c, nil)
+	if strings.Join(hosts, ",") != "history.example.com,islandora.example.com" {
+		t.Errorf("expected the https hostnames, got %v", hosts)
+	}
+}
+
+func TestBoundedCheck(t *testing.T) {
+	defer func(timeout time.Duration) { checkTimeout = timeout }(checkTimeout)
+	checkTimeout = 10 * time.Millisecond
+
+	// a check that never returns on its own is failed by its timeout
+	result := bounded(context.Background(), func(ctx context.Context) CheckResult {
+		<-ctx.Done()
+		return CheckResult{Name: "services", Status: CheckFail, Detail: ctx.Err().Error()}
+	})
+	if result.Status != CheckFail || !strings.Contains(result.Detail, "deadline exceeded") {
+		t.Errorf("expected the check to time out, got %+v", result)
+	}
+}
+
+func TestDoctorInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &config.Context{
+		Name:           "local",
+		DockerHostType: config.ContextLocal,
+		DockerSocket:   filepath.Join(t.TempDir(), "docker.sock"),
+		ProjectDir:     t.TempDir(),
+	}
+
+	statuses := map[string]CheckStatus{}
+	for _, r := range Doctor(ctx, c, time.Now()) {
+		statuses[r.Name] = r.Status
+	}
+	for _, name := range []string{"disk", "memory", "tls"} {
+		if statuses[name] != CheckSkipped {
+			t.Errorf("expected %s to be skipped after an interrupt, got %q", name, statuses[name])
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package isle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+)
+
+// ConfigSyncDir is where config is staged in the drupal container for export and import.
+const ConfigSyncDir = "/tmp/islectl-config"
+
+// ConfigSet is Drupal config keyed by its path in the sync directory without the .yml extension,
+// e.g. system.site, or language/fr/system.site for config in a collection, with the YAML as the value.
+type ConfigSet map[string][]byte
+
+// Names returns the sorted config names.
+func (cs ConfigSet) Names() []string {
+	names := make([]string, 0, len(cs))
+	for name := range cs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ConfigDiff is what importing one ConfigSet over another would change.
+type ConfigDiff struct {
+	Added   []string
+	Changed []string
+	Deleted []string
+}
+
+// Empty reports whether the config sets are identical.
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Deleted) == 0
+}
+
+// DiffConfig compares the source config with the active config it would be imported into.
+func DiffConfig(source, active ConfigSet) ConfigDiff {
+	diff := ConfigDiff{Added: []string{}, Changed: []string{}, Deleted: []string{}}
+	for _, name := range source.Names() {
+		current, ok := active[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !bytes.Equal(current, source[name]):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for _, name := range active.Names() {
+		if _, ok := source[name]; !ok {
+			diff.Deleted = append(diff.Deleted, name)
+		}
+	}
+
+	return diff
+}
+
+// DrushCommand is the shell command to run drush with args against a site.
+func DrushCommand(c *config.Context, site Site, args ...string) string {
+	uri := `"$DRUPAL_DRUSH_URI"`
+	if siteURI := site.URI(c); siteURI != "" {
+		uri = shellquote.Join(siteURI)
+	}
+	return fmt.Sprintf("drush --uri=%s %s", uri, shellquote.Join(args...))
+}
+
+// ReadConfigDir reads the config YAML files in dir, including the collections in its subdirectories.
+func ReadConfigDir(dir string) (ConfigSet, error) {
+	cs := ConfigSet{}
+	err := filepath.WalkDir(dir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yml") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		cs[strings.TrimSuffix(filepath.ToSlash(rel), ".yml")] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+// WriteConfigDir makes dir match the config set, removing config files that are not in it like drush cex does.
+func WriteConfigDir(dir string, cs ConfigSet) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	existing, err := ReadConfigDir(dir)
+	if err != nil {
+		return err
+	}
+	for name := range existing {
+		if _, ok := cs[name]; !ok {
+			if err := os.Remove(configFile(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	for name, data := range cs {
+		file := configFile(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func configFile(dir, name string) string {
+	return filepath.Join(dir, filepath.FromSlash(name)+".yml")
+}
+
+// readConfigTar reads the config YAML files from a docker archive of a directory.
+func readConfigTar(r io.Reader) (ConfigSet, error) {
+	cs := ConfigSet{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return cs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading config archive: %v", err)
+		}
+		// names are relative to the archived directory, e.g. islectl-config/language/fr/system.site.yml
+		_, rel, _ := strings.Cut(path.Clean(header.Name), "/")
+		name, ok := strings.CutSuffix(rel, ".yml")
+		if header.Typeflag != tar.TypeReg || !ok {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		cs[name] = data
+	}
+}
+
+// configTar archives a config set as a directory for docker to extract.
+func configTar(dir string, cs ConfigSet) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0777}); err != nil {
+		return nil, err
+	}
+	dirs := map[string]bool{dir: true}
+	for _, name := range cs.Names() {
+		// collections are in subdirectories, e.g. language/fr, which are added parents first
+		parents := []string{}
+		for parent := path.Dir(path.Join(dir, name)); !dirs[parent]; parent = path.Dir(parent) {
+			parents = append(parents, parent)
+			dirs[parent] = true
+		}
+		slices.Reverse(parents)
+		for _, parent := range parents {
+			if err := tw.WriteHeader(&tar.Header{Name: parent + "/", Typeflag: tar.TypeDir, Mode: 0777}); err != nil {
+				return nil, err
+			}
+		}
+		header := &tar.Header{
+			Name:     path.Join(dir, name+".yml"),
+			Typeflag: tar.TypeReg,
+			Mode:     0666,
+			Size:     int64(len(cs[name])),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(cs[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return &buf, nil
+}
+
+// ExportConfig runs drush config:export for a site and returns the exported config.
+func (d *DockerClient) ExportConfig(ctx context.Context, c *config.Context, site Site) (ConfigSet, error) {
+	drupalContainer, err := d.drupalContainer(c)
+	if err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf("rm -rf %s && %s", ConfigSyncDir, DrushCommand(c, site, "config:export", "--destination="+ConfigSyncDir, "-y"))
+	if err := runInContainer(c, drupalContainer, script); err != nil {
+		return nil, fmt.Errorf("unable to export config from %s: %v", c.Name, err)
+	}
+	defer d.cleanConfigSyncDir(c, drupalContainer)
+
+	archive, _, err := d.CLI.CopyFromContainer(ctx, drupalContainer, ConfigSyncDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy config from %s: %v", c.Name, err)
+	}
+	defer archive.Close()
+
+	return readConfigTar(archive)
+}
+
+// ImportConfig copies the config set to the drupal container and runs drush config:import for a site.
+func (d *DockerClient) ImportConfig(ctx context.Context, c *config.Context, site Site, cs ConfigSet) error {
+	drupalContainer, err := d.drupalContainer(c)
+	if err != nil {
+		return err
+	}
+
+	if err := runInContainer(c, drupalContainer, "rm -rf "+ConfigSyncDir); err != nil {
+		return err
+	}
+	defer d.cleanConfigSyncDir(c, drupalContainer)
+	archive, err := configTar(path.Base(ConfigSyncDir), cs)
+	if err != nil {
+		return err
+	}
+	if err := d.CLI.CopyToContainer(ctx, drupalContainer, path.Dir(ConfigSyncDir), archive, dockercontainer.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("unable to copy config to %s: %v", c.Name, err)
+	}
+
+	script := DrushCommand(c, site, "config:import", "--source="+ConfigSyncDir, "-y")
+	if err := runInContainer(c, drupalContainer, script); err != nil {
+		return fmt.Errorf("unable to import config on %s: %v", c.Name, err)
+	}
+
+	return nil
+}
+
+func (d *DockerClient) drupalContainer(c *config.Context) (string, error) {
+	name, err := d.GetContainerName(c, "drupal", false)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no running drupal container found for context %q", c.Name)
+	}
+	// the API wants the bare name
+	return strings.TrimPrefix(name, "/"), nil
+}
+
+func (d *DockerClient) cleanConfigSyncDir(c *config.Context, containerName string) {
+	_ = runInContainer(c, containerName, "rm -rf "+ConfigSyncDir)
+}
+
+// runInContainer runs a shell script in a container, passing its output through to stderr.
+func runInContainer(c *config.Context, containerName, script string) error {
+	cmd := exec.Command("docker", "exec", containerName, "sh", "-c", script)
+	cmd.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(cmd, nil)
+	if output != "" {
+		fmt.Fprint(os.Stderr, output)
+	}
+	return err
+}
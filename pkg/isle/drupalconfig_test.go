@@ -0,0 +1,114 @@
+package isle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestDiffConfig(t *testing.T) {
+	source := ConfigSet{
+		"system.site":         []byte("name: Prod\n"),
+		"views.view.content":  []byte("id: content\n"),
+		"field.storage.title": []byte("id: title\n"),
+	}
+	active := ConfigSet{
+		"system.site":         []byte("name: Local\n"),
+		"views.view.content":  []byte("id: content\n"),
+		"devel.settings":      []byte("page_alter: true\n"),
+		"field.storage.title": []byte("id: title\n"),
+	}
+
+	diff := DiffConfig(source, active)
+	expected := ConfigDiff{
+		Added:   []string{},
+		Changed: []string{"system.site"},
+		Deleted: []string{"devel.settings"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected %+v, got %+v", expected, diff)
+	}
+	if diff.Empty() {
+		t.Errorf("expected the diff to not be empty")
+	}
+
+	diff = DiffConfig(active, source)
+	if !reflect.DeepEqual(diff.Added, []string{"devel.settings"}) || len(diff.Deleted) != 0 {
+		t.Errorf("unexpected reverse diff %+v", diff)
+	}
+	if !DiffConfig(source, source).Empty() {
+		t.Errorf("expected identical config to have an empty diff")
+	}
+}
+
+func TestConfigDirRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sync")
+	cs := ConfigSet{
+		"system.site":              []byte("name: Prod\n"),
+		"views.view.content":       []byte("id: content\n"),
+		"language/fr/system.site":  []byte("name: Prod FR\n"),
+		"language/fr/views.view.x": []byte("label: X\n"),
+	}
+	if err := WriteConfigDir(dir, cs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".htaccess"), []byte("Deny from all"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "language", "fr", "system.site.yml")); err != nil || string(data) != "name: Prod FR\n" {
+		t.Fatalf("expected the collection's config in its subdirectory, got %q, %v", data, err)
+	}
+
+	// stale config is removed, other files are left alone
+	delete(cs, "views.view.content")
+	delete(cs, "language/fr/views.view.x")
+	if err := WriteConfigDir(dir, cs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read, err := ReadConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(read, cs) {
+		t.Errorf("expected %v, got %v", cs, read)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".htaccess")); err != nil {
+		t.Errorf("expected non config files to be kept: %v", err)
+	}
+}
+
+func TestConfigTarRoundTrip(t *testing.T) {
+	cs := ConfigSet{
+		"system.site":             []byte("name: Prod\n"),
+		"views.view.content":      []byte("id: content\n"),
+		"language/fr/system.site": []byte("name: Prod FR\n"),
+		"language/de/system.site": []byte("name: Prod DE\n"),
+	}
+	archive, err := configTar("islectl-config", cs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read, err := readConfigTar(archive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(read, cs) {
+		t.Errorf("expected %v, got %v", cs, read)
+	}
+}
+
+func TestDrushCommand(t *testing.T) {
+	c := &config.Context{UriMap: map[string]string{"history": "https://history.example.com"}}
+	got := DrushCommand(c, Site{Name: "default"}, "config:export", "-y")
+	if got != `drush --uri="$DRUPAL_DRUSH_URI" config:export -y` {
+		t.Errorf("unexpected command %q", got)
+	}
+	got = DrushCommand(c, Site{Name: "history"}, "cr")
+	if got != "drush --uri=https://history.example.com cr" {
+		t.Errorf("unexpected command %q", got)
+	}
+}
@@ -0,0 +1,248 @@
+package isle
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FcrepoURL is the REST API of the fcrepo service, reached through a Tunnel's HTTP client.
+const FcrepoURL = "http://fcrepo:8080/fcrepo/rest"
+
+// FcrepoDataDir holds fcrepo's OCFL storage in the fcrepo container.
+const FcrepoDataDir = "/data"
+
+// FcrepoIndexAction is the Drupal action that queues a node to be written to Fedora.
+const FcrepoIndexAction = "index_node_in_fedora"
+
+// FedoraPath is where Islandora stores a node in Fedora, a pairtree of its UUID.
+func FedoraPath(uuid string) string {
+	if len(uuid) < 8 {
+		return uuid
+	}
+	return strings.Join([]string{uuid[0:2], uuid[2:4], uuid[4:6], uuid[6:8], uuid}, "/")
+}
+
+// ParseDiskUsage returns the bytes used from the output of du -sk.
+func ParseDiskUsage(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unable to parse disk usage %q", output)
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse disk usage %q: %v", output, err)
+	}
+	return kb * 1024, nil
+}
+
+// FedoraJWT signs a short lived token with Drupal's JWT private key.
+// fcrepo's Syn valve accepts it as an admin, the same way it accepts Islandora's requests.
+func FedoraJWT(privateKeyPEM string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return "", fmt.Errorf("JWT private key is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("JWT private key is not an RSA key")
+		}
+		key = rsaKey
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("unable to parse JWT private key: %v", err)
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   "islectl",
+		"sub":   "admin",
+		"webid": 1,
+		"roles": []string{"fedoraAdmin"},
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign JWT: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Fcrepo talks to the fcrepo service's REST API.
+type Fcrepo struct {
+	Client  *http.Client
+	BaseURL string
+	// Token is a JWT sent as a bearer token, requests are anonymous without one
+	Token string
+}
+
+// NewFcrepo returns a client for the context's fcrepo service authenticated with Drupal's JWT key.
+// Without the key, requests are anonymous, which fcrepo only allows for reads.
+func NewFcrepo(ctx context.Context, t *Tunnel) (*Fcrepo, error) {
+	f := &Fcrepo{Client: t.HTTPClient(), BaseURL: FcrepoURL}
+	drupalContainer, err := t.ContainerName("drupal")
+	if err != nil {
+		return f, nil
+	}
+	key, err := GetSecret(ctx, t.Client().CLI, t.Context, drupalContainer, jwtPrivateKey)
+	if err != nil || strings.TrimSpace(key) == "" {
+		return f, nil
+	}
+	f.Token, err = FedoraJWT(key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fcrepo) do(ctx context.Context, method, path string, params url.Values) (*http.Response, error) {
+	u := f.BaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach fcrepo: %v", err)
+	}
+	return resp, nil
+}
+
+// Ping checks the repository root responds and returns how long it took.
+func (f *Fcrepo) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	resp, err := f.do(ctx, http.MethodHead, "/", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fcrepo returned %s", resp.Status)
+	}
+	return time.Since(start), nil
+}
+
+// ResourceCount returns the number of resources in the repository from fcrepo's search index.
+func (f *Fcrepo) ResourceCount(ctx context.Context) (int64, error) {
+	params := url.Values{"condition": {"fedora_id=*"}, "max_results": {"1"}}
+	resp, err := f.do(ctx, http.MethodGet, "/fcr:search", params)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fcrepo search returned %s", resp.Status)
+	}
+	var out struct {
+		Pagination struct {
+			TotalResults int64 `json:"totalResults"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return 0, fmt.Errorf("unable to parse fcrepo search response: %v", err)
+	}
+	return out.Pagination.TotalResults, nil
+}
+
+// Exists reports whether a node has been written to Fedora. Deleted resources don't exist.
+func (f *Fcrepo) Exists(ctx context.Context, uuid string) (bool, error) {
+	resp, err := f.do(ctx, http.MethodHead, "/"+FedoraPath(uuid), nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusGone:
+		return false, nil
+	}
+	return false, fmt.Errorf("fcrepo returned %s for %s", resp.Status, FedoraPath(uuid))
+}
+
+// MissingNodes checks the nodes with a number of concurrent requests and returns the ones not in Fedora in order.
+func (f *Fcrepo) MissingNodes(ctx context.Context, nodes []DrupalNode, workers int) ([]DrupalNode, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	missing := make([]bool, len(nodes))
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for range max(1, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				exists, err := f.Exists(ctx, nodes[i].UUID)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("unable to check node %d: %v", nodes[i].ID, err)
+					}
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				missing[i] = !exists
+			}
+		}()
+	}
+	for i := range nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	out := []DrupalNode{}
+	for i, node := range nodes {
+		if missing[i] {
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
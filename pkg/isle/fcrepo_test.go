@@ -0,0 +1,138 @@
+package isle
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFedoraPath(t *testing.T) {
+	uuid := "a1b2c3d4-5e6f-7a8b-9c0d-112233445566"
+	if got := FedoraPath(uuid); got != "a1/b2/c3/d4/"+uuid {
+		t.Errorf("FedoraPath() = %q", got)
+	}
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	if n, err := ParseDiskUsage("2048\t/data\n"); err != nil || n != 2048*1024 {
+		t.Errorf("ParseDiskUsage() = %d, %v", n, err)
+	}
+	if _, err := ParseDiskUsage("du: /data: No such file or directory"); err == nil {
+		t.Error("expected an error for du's error message")
+	}
+}
+
+func TestFedoraJWT(t *testing.T) {
+	private, public, err := generateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	token, err := FedoraJWT(private, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a header, claims and signature, got %q", token)
+	}
+
+	block, _ := pem.Decode([]byte(public))
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("signature does not verify with the public key: %v", err)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Roles []string `json:"roles"`
+		Exp   int64    `json:"exp"`
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != "fedoraAdmin" || claims.Exp != now.Add(time.Hour).Unix() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := FedoraJWT("not a key", now); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestFcrepo(t *testing.T) {
+	present := "a1b2c3d4-0000-0000-0000-000000000001"
+	deleted := "a1b2c3d4-0000-0000-0000-000000000003"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/fcrepo/rest/":
+		case "/fcrepo/rest/fcr:search":
+			if r.URL.Query().Get("condition") != "fedora_id=*" {
+				t.Errorf("unexpected search %s", r.URL)
+			}
+			w.Write([]byte(`{"pagination": {"totalResults": 42}, "items": []}`))
+		case "/fcrepo/rest/" + FedoraPath(present):
+		case "/fcrepo/rest/" + FedoraPath(deleted):
+			w.WriteHeader(http.StatusGone)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	f := &Fcrepo{Client: server.Client(), BaseURL: server.URL + "/fcrepo/rest", Token: "token"}
+	ctx := context.Background()
+
+	if _, err := f.Ping(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n, err := f.ResourceCount(ctx); err != nil || n != 42 {
+		t.Errorf("ResourceCount() = %d, %v", n, err)
+	}
+
+	nodes := []DrupalNode{
+		{ID: 1, UUID: present},
+		{ID: 2, UUID: "a1b2c3d4-0000-0000-0000-000000000002"},
+		{ID: 3, UUID: deleted},
+	}
+	missing, err := f.MissingNodes(ctx, nodes, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(missing) != 2 || missing[0].ID != 2 || missing[1].ID != 3 {
+		t.Errorf("expected nodes 2 and 3 to be missing, got %+v", missing)
+	}
+
+	f.Token = ""
+	if _, err := f.Ping(ctx); err == nil {
+		t.Error("expected an error without a token")
+	}
+	if _, err := f.MissingNodes(ctx, nodes, 2); err == nil {
+		t.Error("expected an error when fcrepo rejects the checks")
+	}
+}
@@ -0,0 +1,157 @@
+package isle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ForwardSpec describes a local port forwarded to a port on a docker compose service.
+// A LocalPort of 0 means a free local port is allocated when the forward is started
+// and a RemotePort of 0 means the service's exposed ports should be discovered.
+type ForwardSpec struct {
+	LocalPort  int
+	Service    string
+	RemotePort int
+	// Fallback allows a free local port when LocalPort is in use,
+	// for presets whose local port was not chosen by the user.
+	Fallback bool
+}
+
+func (s ForwardSpec) String() string {
+	if s.RemotePort == 0 {
+		return s.Service
+	}
+	if s.LocalPort == 0 {
+		return fmt.Sprintf("%s:%d", s.Service, s.RemotePort)
+	}
+	return fmt.Sprintf("%d:%s:%d", s.LocalPort, s.Service, s.RemotePort)
+}
+
+// Presets are the well-known ISLE services that can be forwarded by name
+// e.g. "islectl port-forward solr traefik"
+var Presets = map[string]ForwardSpec{
+	"activemq":   {LocalPort: 8161, Service: "activemq", RemotePort: 8161},
+	"blazegraph": {LocalPort: 8082, Service: "blazegraph", RemotePort: 8080},
+	"cantaloupe": {LocalPort: 8182, Service: "cantaloupe", RemotePort: 8182},
+	"fcrepo":     {LocalPort: 8081, Service: "fcrepo", RemotePort: 8080},
+	"mariadb":    {LocalPort: 3306, Service: "mariadb", RemotePort: 3306},
+	"solr":       {LocalPort: 8983, Service: "solr", RemotePort: 8983},
+	"traefik":    {LocalPort: 8080, Service: "traefik", RemotePort: 8080},
+}
+
+// PresetNames returns the preset names in sorted order.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseForwardSpec parses a port-forward argument. Supported formats are
+//
+//	PRESET                    a name from Presets e.g. solr
+//	SERVICE                   forward every port the service exposes to free local ports
+//	SERVICE:REMOTE-PORT       forward the service port to a free local port
+//	LOCAL-PORT:SERVICE:REMOTE-PORT
+func ParseForwardSpec(arg string) (ForwardSpec, error) {
+	parts := strings.Split(arg, ":")
+	switch len(parts) {
+	case 1:
+		if arg == "" {
+			return ForwardSpec{}, fmt.Errorf("invalid port forwarding spec '%s': service is required", arg)
+		}
+		if preset, ok := Presets[arg]; ok {
+			preset.Fallback = true
+			return preset, nil
+		}
+		return ForwardSpec{Service: arg}, nil
+	case 2:
+		remotePort, err := parsePort(parts[1])
+		if err != nil {
+			return ForwardSpec{}, fmt.Errorf("invalid remote port '%s': %v", parts[1], err)
+		}
+		return ForwardSpec{Service: parts[0], RemotePort: remotePort}, nil
+	case 3:
+		localPort := 0
+		if parts[0] != "" {
+			p, err := parsePort(parts[0])
+			if err != nil {
+				return ForwardSpec{}, fmt.Errorf("invalid local port '%s': %v", parts[0], err)
+			}
+			localPort = p
+		}
+		remotePort, err := parsePort(parts[2])
+		if err != nil {
+			return ForwardSpec{}, fmt.Errorf("invalid remote port '%s': %v", parts[2], err)
+		}
+		return ForwardSpec{LocalPort: localPort, Service: parts[1], RemotePort: remotePort}, nil
+	}
+
+	return ForwardSpec{}, fmt.Errorf("invalid port forwarding spec '%s': expected format [LOCAL-PORT:]SERVICE[:REMOTE-PORT]", arg)
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("must be an integer")
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("must be between 1 and 65535")
+	}
+	return port, nil
+}
+
+// ExposedPorts returns the TCP ports a container exposes, sorted ascending.
+func (d *DockerClient) ExposedPorts(ctx context.Context, containerName string) ([]int, error) {
+	containerJSON, err := d.CLI.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting container %q: %v", containerName, err)
+	}
+	if containerJSON.Config == nil {
+		return nil, nil
+	}
+
+	ports := []int{}
+	for port := range containerJSON.Config.ExposedPorts {
+		if port.Proto() != "tcp" {
+			continue
+		}
+		ports = append(ports, port.Int())
+	}
+	slices.Sort(ports)
+
+	return ports, nil
+}
+
+// ListenLocal opens a listener on localhost for the given port.
+// When port is 0 a free port is allocated by the OS.
+func ListenLocal(port int) (net.Listener, int, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		if port == 0 {
+			return nil, 0, fmt.Errorf("unable to listen on a free local port: %v", err)
+		}
+		return nil, 0, fmt.Errorf("local port %d appears to be in use: %v", port, err)
+	}
+
+	return listener, listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Listen opens the local listener for the spec, falling back to a free port
+// when the spec allows it and its local port is in use.
+func (s ForwardSpec) Listen() (net.Listener, int, error) {
+	listener, port, err := ListenLocal(s.LocalPort)
+	if err == nil || !s.Fallback || s.LocalPort == 0 {
+		return listener, port, err
+	}
+
+	slog.Warn("Local port in use, using a free port instead", "service", s.Service, "port", s.LocalPort, "err", err)
+	return ListenLocal(0)
+}
@@ -0,0 +1,116 @@
+package isle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestParseForwardSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    ForwardSpec
+		wantErr bool
+	}{
+		{"full spec", "8983:solr:8983", ForwardSpec{LocalPort: 8983, Service: "solr", RemotePort: 8983}, false},
+		{"preset", "fcrepo", ForwardSpec{LocalPort: 8081, Service: "fcrepo", RemotePort: 8080, Fallback: true}, false},
+		{"service and remote port", "solr:8983", ForwardSpec{Service: "solr", RemotePort: 8983}, false},
+		{"empty local port", ":solr:8983", ForwardSpec{Service: "solr", RemotePort: 8983}, false},
+		{"service only", "matomo", ForwardSpec{Service: "matomo"}, false},
+		{"empty", "", ForwardSpec{}, true},
+		{"bad local port", "abc:solr:8983", ForwardSpec{}, true},
+		{"bad remote port", "solr:abc", ForwardSpec{}, true},
+		{"port out of range", "70000:solr:8983", ForwardSpec{}, true},
+		{"too many parts", "1:2:3:4", ForwardSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseForwardSpec(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardSpecStringRoundTrip(t *testing.T) {
+	for _, arg := range []string{"8983:solr:8983", "solr:8983", "matomo"} {
+		spec, err := ParseForwardSpec(arg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if spec.String() != arg {
+			t.Errorf("expected %q, got %q", arg, spec.String())
+		}
+	}
+}
+
+func TestExposedPorts(t *testing.T) {
+	fake := &FakeDockerClient{
+		InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+			return dockercontainer.InspectResponse{
+				Config: &dockercontainer.Config{
+					ExposedPorts: nat.PortSet{
+						"8983/tcp":  struct{}{},
+						"61616/tcp": struct{}{},
+						"8161/tcp":  struct{}{},
+						"53/udp":    struct{}{},
+					},
+				},
+			}, nil
+		},
+	}
+	dClient := &DockerClient{CLI: fake}
+	ports, err := dClient.ExposedPorts(context.Background(), "dummyContainer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []int{8161, 8983, 61616}
+	if !reflect.DeepEqual(ports, expected) {
+		t.Errorf("expected %v, got %v", expected, ports)
+	}
+}
+
+func TestListenLocalAllocatesFreePort(t *testing.T) {
+	listener, port, err := ListenLocal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	if port == 0 {
+		t.Fatal("expected a port to be allocated")
+	}
+
+	if _, _, err := ListenLocal(port); err == nil {
+		t.Fatalf("expected an error listening on in use port %d", port)
+	}
+}
+
+func TestForwardSpecListen(t *testing.T) {
+	inUse, port, err := ListenLocal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer inUse.Close()
+
+	if _, _, err := (ForwardSpec{LocalPort: port, Service: "solr", RemotePort: 8983}).Listen(); err == nil {
+		t.Errorf("expected an error for an explicit local port %d in use", port)
+	}
+
+	listener, got, err := (ForwardSpec{LocalPort: port, Service: "solr", RemotePort: 8983, Fallback: true}).Listen()
+	if err != nil {
+		t.Fatalf("expected a preset to fall back to a free port, got %v", err)
+	}
+	defer listener.Close()
+	if got == 0 || got == port {
+		t.Errorf("expected a free port other than %d, got %d", port, got)
+	}
+}
@@ -0,0 +1,412 @@
+package isle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// CantaloupeURL is the cantaloupe service, reached through a Tunnel's HTTP client.
+// Traefik strips the /cantaloupe prefix of public URLs before they reach it.
+const CantaloupeURL = "http://cantaloupe:8182"
+
+// DrupalURL is the drupal service, reached through a Tunnel's HTTP client.
+const DrupalURL = "http://drupal:80"
+
+// CantaloupeCacheDir is where cantaloupe's FilesystemCache stores its files by default.
+const CantaloupeCacheDir = "/data"
+
+// IIIFManifestPath is the default path of a node's IIIF manifest, {nid} is replaced with the node ID.
+const IIIFManifestPath = "/node/{nid}/book-manifest"
+
+// Cantaloupe cache purge tasks.
+const (
+	PurgeCache            = "PurgeCache"
+	PurgeInvalidFromCache = "PurgeInvalidFromCache"
+	PurgeItemFromCache    = "PurgeItemFromCache"
+)
+
+// CacheUsage is the size of a directory of cantaloupe's cache, e.g. image, info or source.
+type CacheUsage struct {
+	Name  string
+	Bytes int64
+	Files int64
+}
+
+// CacheStatsScript prints the name, size in KB and number of files of each directory in the cache.
+func CacheStatsScript(dir string) string {
+	return fmt.Sprintf(`cd %s || exit 1
+for d in */; do
+  [ -d "$d" ] || continue
+  d="${d%%/}"
+  echo "$d $(du -sk "$d" | cut -f1) $(find "$d" -type f | wc -l)"
+done`, shellquote.Join(dir))
+}
+
+// CachePurgeScript deletes everything in the cache directory, for stacks where cantaloupe's API is disabled.
+func CachePurgeScript(dir string) string {
+	return fmt.Sprintf("find %s -mindepth 1 -delete", shellquote.Join(dir))
+}
+
+// ParseCacheStats parses the output of CacheStatsScript.
+func ParseCacheStats(output string) ([]CacheUsage, error) {
+	usage := []CacheUsage{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unable to parse cache usage %q", line)
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cache usage %q: %v", line, err)
+		}
+		files, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cache usage %q: %v", line, err)
+		}
+		usage = append(usage, CacheUsage{Name: fields[0], Bytes: kb * 1024, Files: files})
+	}
+	return usage, nil
+}
+
+// Cantaloupe talks to the cantaloupe service's IIIF image and admin APIs.
+type Cantaloupe struct {
+	Client  *http.Client
+	BaseURL string
+	// User and Secret authenticate to the API, which is only available when APIEnabled
+	User       string
+	Secret     string
+	APIEnabled bool
+	// Container and CacheDir locate the cache, for tasks the API can't do
+	Container string
+	CacheDir  string
+}
+
+// NewCantaloupe returns a client for the context's cantaloupe service configured from the container's environment.
+func NewCantaloupe(ctx context.Context, t *Tunnel) (*Cantaloupe, error) {
+	containerName, err := t.ContainerName("cantaloupe")
+	if err != nil {
+		return nil, err
+	}
+	cli := t.Client()
+	env := func(name, fallback string) string {
+		value, err := GetConfigEnv(ctx, cli.CLI, containerName, name)
+		if err != nil || value == "" {
+			return fallback
+		}
+		return value
+	}
+
+	c := &Cantaloupe{
+		Client:     t.HTTPClient(),
+		BaseURL:    CantaloupeURL,
+		User:       env("CANTALOUPE_ENDPOINT_API_USERNAME", "admin"),
+		APIEnabled: strings.EqualFold(env("CANTALOUPE_ENDPOINT_API_ENABLED", "false"), "true"),
+		Container:  strings.TrimPrefix(containerName, "/"),
+		CacheDir:   env("CANTALOUPE_FILESYSTEMCACHE_PATHNAME", CantaloupeCacheDir),
+	}
+	if c.APIEnabled {
+		secret, err := GetSecret(ctx, cli.CLI, t.Context, containerName, "CANTALOUPE_ENDPOINT_API_SECRET")
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the cantaloupe API secret: %v", err)
+		}
+		c.Secret = strings.TrimSpace(secret)
+	}
+	return c, nil
+}
+
+// Purge runs a cache purge task with the API and waits for it to finish.
+// The identifier is only used by PurgeItemFromCache.
+func (c *Cantaloupe) Purge(ctx context.Context, verb, identifier string, poll time.Duration) error {
+	if !c.APIEnabled {
+		return fmt.Errorf("cantaloupe's API is disabled. Set CANTALOUPE_ENDPOINT_API_ENABLED=true on the cantaloupe service to use it")
+	}
+	task := map[string]string{"verb": verb}
+	if identifier != "" {
+		task["identifier"] = identifier
+	}
+	body, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/tasks", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.User, c.Secret)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach cantaloupe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("cantaloupe returned %s for %s", resp.Status, verb)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		// nothing to follow, the task was accepted
+		return nil
+	}
+
+	// the Location points at the public URL, ask the service directly
+	statusPath := location.Path
+	if i := strings.Index(statusPath, "/tasks/"); i >= 0 {
+		statusPath = statusPath[i:]
+	}
+	statusURL := c.BaseURL + statusPath
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(c.User, c.Secret)
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			return fmt.Errorf("unable to reach cantaloupe: %v", err)
+		}
+		var status struct {
+			Status       string `json:"status"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("unable to parse the status of %s: %v", verb, err)
+		}
+		switch status.Status {
+		case "SUCCEEDED":
+			return nil
+		case "FAILED":
+			return fmt.Errorf("%s failed: %s", verb, status.ErrorMessage)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// ServiceURL maps the public URL of an image service in a manifest to the cantaloupe service.
+func (c *Cantaloupe) ServiceURL(id string) (string, error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("invalid image service %q: %v", id, err)
+	}
+	// keep the escaped identifier, it is usually an encoded URL
+	p := u.EscapedPath()
+	i := strings.Index(p, "/iiif/")
+	if i < 0 {
+		return "", fmt.Errorf("image service %q is not a IIIF image API URL", id)
+	}
+	return c.BaseURL + p[i:], nil
+}
+
+// ImageCheck is the result of requesting an image's info.json and first tile.
+type ImageCheck struct {
+	Width  int
+	Height int
+	Tile   string
+}
+
+// CheckImage requests an image service's info.json and its first tile.
+func (c *Cantaloupe) CheckImage(ctx context.Context, id string) (ImageCheck, error) {
+	base, err := c.ServiceURL(id)
+	if err != nil {
+		return ImageCheck{}, err
+	}
+	var info struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+		Tiles  []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"tiles"`
+	}
+	data, err := c.get(ctx, base+"/info.json")
+	if err != nil {
+		return ImageCheck{}, fmt.Errorf("info.json: %v", err)
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return ImageCheck{}, fmt.Errorf("info.json: unable to parse: %v", err)
+	}
+	if info.Width == 0 || info.Height == 0 {
+		return ImageCheck{}, fmt.Errorf("info.json: image has no dimensions")
+	}
+
+	tileWidth, tileHeight := 512, 512
+	if len(info.Tiles) > 0 && info.Tiles[0].Width > 0 {
+		tileWidth = info.Tiles[0].Width
+		tileHeight = info.Tiles[0].Height
+		if tileHeight == 0 {
+			tileHeight = tileWidth
+		}
+	}
+	tileWidth, tileHeight = min(tileWidth, info.Width), min(tileHeight, info.Height)
+	tile := fmt.Sprintf("%s/0,0,%d,%d/%d,/0/default.jpg", base, tileWidth, tileHeight, tileWidth)
+	if _, err := c.get(ctx, tile); err != nil {
+		return ImageCheck{}, fmt.Errorf("tile: %v", err)
+	}
+	return ImageCheck{Width: info.Width, Height: info.Height, Tile: tile}, nil
+}
+
+func (c *Cantaloupe) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach cantaloupe: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cantaloupe returned %s", resp.Status)
+	}
+	return data, nil
+}
+
+// ManifestImage is an image on a canvas of a IIIF manifest.
+type ManifestImage struct {
+	Canvas  string
+	Service string
+}
+
+// ParseManifestImages returns the image services of a IIIF presentation 2 or 3 manifest.
+func ParseManifestImages(data []byte) ([]ManifestImage, error) {
+	var manifest struct {
+		Sequences []struct {
+			Canvases []struct {
+				Label  json.RawMessage `json:"label"`
+				Images []struct {
+					Resource struct {
+						Service iiifServices `json:"service"`
+					} `json:"resource"`
+				} `json:"images"`
+			} `json:"canvases"`
+		} `json:"sequences"`
+		Items []struct {
+			Label json.RawMessage `json:"label"`
+			Items []struct {
+				Items []struct {
+					Body struct {
+						Service iiifServices `json:"service"`
+					} `json:"body"`
+				} `json:"items"`
+			} `json:"items"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse IIIF manifest: %v", err)
+	}
+
+	images := []ManifestImage{}
+	for _, sequence := range manifest.Sequences {
+		for _, canvas := range sequence.Canvases {
+			for _, image := range canvas.Images {
+				for _, service := range image.Resource.Service {
+					images = append(images, ManifestImage{Canvas: iiifLabel(canvas.Label), Service: service})
+				}
+			}
+		}
+	}
+	for _, canvas := range manifest.Items {
+		for _, page := range canvas.Items {
+			for _, annotation := range page.Items {
+				for _, service := range annotation.Body.Service {
+					images = append(images, ManifestImage{Canvas: iiifLabel(canvas.Label), Service: service})
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
+// iiifServices are the IDs of a service property, which may be a single service or a list of them.
+type iiifServices []string
+
+func (s *iiifServices) UnmarshalJSON(data []byte) error {
+	type service struct {
+		ID       string `json:"id"`
+		LegacyID string `json:"@id"`
+	}
+	var list []service
+	if err := json.Unmarshal(data, &list); err != nil {
+		var single service
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		list = []service{single}
+	}
+	for _, svc := range list {
+		if svc.ID != "" {
+			*s = append(*s, svc.ID)
+		} else if svc.LegacyID != "" {
+			*s = append(*s, svc.LegacyID)
+		}
+	}
+	return nil
+}
+
+// iiifLabel renders a presentation 2 string label or a presentation 3 language map.
+func iiifLabel(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var languages map[string][]string
+	if json.Unmarshal(raw, &languages) == nil {
+		for _, values := range languages {
+			if len(values) > 0 {
+				return values[0]
+			}
+		}
+	}
+	return ""
+}
+
+// FetchManifest requests a node's IIIF manifest from the drupal service with the site's hostname.
+func FetchManifest(ctx context.Context, client *http.Client, siteURL, manifestPath string, nid int) ([]byte, error) {
+	p := strings.ReplaceAll(manifestPath, "{nid}", strconv.Itoa(nid))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, DrupalURL+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(siteURL); err == nil && u.Host != "" {
+		// Drupal's trusted host patterns only allow the site's hostname
+		req.Host = u.Host
+		// behind Traefik, HTTPS is signalled with a header
+		req.Header.Set("X-Forwarded-Proto", u.Scheme)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach drupal: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("drupal returned %s for %s", resp.Status, p)
+	}
+	return data, nil
+}
@@ -0,0 +1,159 @@
+package isle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCacheStats(t *testing.T) {
+	usage, err := ParseCacheStats("image 2048 10\ninfo 8 10\nsource 0 0\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(usage) != 3 || usage[0].Name != "image" || usage[0].Bytes != 2048*1024 || usage[0].Files != 10 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if usage, err := ParseCacheStats(""); err != nil || len(usage) != 0 {
+		t.Errorf("expected an empty cache, got %+v %v", usage, err)
+	}
+	if _, err := ParseCacheStats("sh: cd: can't cd to /data"); err == nil {
+		t.Error("expected an error for a shell error")
+	}
+}
+
+func TestParseManifestImages(t *testing.T) {
+	v2 := `{"sequences": [{"canvases": [
+  {"label": "Page 1", "images": [{"resource": {"service": {"@id": "https://islandora.dev/cantaloupe/iiif/2/a.jp2"}}}]},
+  {"label": "Page 2", "images": [{"resource": {"service": {"@id": "https://islandora.dev/cantaloupe/iiif/2/b.jp2"}}}]}
+]}]}`
+	images, err := ParseManifestImages([]byte(v2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(images) != 2 || images[1].Canvas != "Page 2" || images[1].Service != "https://islandora.dev/cantaloupe/iiif/2/b.jp2" {
+		t.Errorf("unexpected v2 images %+v", images)
+	}
+
+	v3 := `{"items": [{"label": {"en": ["Front"]}, "items": [{"items": [{"body": {"service": [{"id": "https://islandora.dev/cantaloupe/iiif/3/c.jp2"}]}}]}]}]}`
+	images, err = ParseManifestImages([]byte(v3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(images) != 1 || images[0].Canvas != "Front" || images[0].Service != "https://islandora.dev/cantaloupe/iiif/3/c.jp2" {
+		t.Errorf("unexpected v3 images %+v", images)
+	}
+
+	if _, err := ParseManifestImages([]byte("<html>")); err == nil {
+		t.Error("expected an error for HTML")
+	}
+}
+
+func TestCantaloupeServiceURL(t *testing.T) {
+	c := &Cantaloupe{BaseURL: CantaloupeURL}
+	got, err := c.ServiceURL("https://islandora.dev/cantaloupe/iiif/2/https%3A%2F%2Fislandora.dev%2Fsystem%2Ffiles%2Fa.jp2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "http://cantaloupe:8182/iiif/2/https%3A%2F%2Fislandora.dev%2Fsystem%2Ffiles%2Fa.jp2" {
+		t.Errorf("expected the encoded identifier to be kept, got %q", got)
+	}
+	if _, err := c.ServiceURL("https://islandora.dev/node/1"); err == nil {
+		t.Error("expected an error for a URL that isn't an image service")
+	}
+}
+
+func TestCantaloupe(t *testing.T) {
+	tasks := []map[string]string{}
+	statusCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tasks" && r.Method == http.MethodPost:
+			if user, secret, _ := r.BasicAuth(); user != "admin" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			task := map[string]string{}
+			if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+				t.Errorf("invalid task: %v", err)
+			}
+			tasks = append(tasks, task)
+			w.Header().Set("Location", "https://islandora.dev/cantaloupe/tasks/1")
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/tasks/1":
+			statusCalls++
+			if statusCalls == 1 {
+				w.Write([]byte(`{"status": "RUNNING"}`))
+				return
+			}
+			w.Write([]byte(`{"status": "SUCCEEDED"}`))
+		case r.URL.Path == "/iiif/2/a.jp2/info.json":
+			w.Write([]byte(`{"width": 1000, "height": 800, "tiles": [{"width": 256, "scaleFactors": [1, 2]}]}`))
+		case r.URL.Path == "/iiif/2/a.jp2/0,0,256,256/256,/0/default.jpg":
+			w.Write([]byte("jpeg"))
+		case r.URL.Path == "/iiif/2/broken.jp2/info.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Cantaloupe{Client: server.Client(), BaseURL: server.URL, User: "admin", Secret: "secret", APIEnabled: true}
+	ctx := context.Background()
+
+	if err := c.Purge(ctx, PurgeItemFromCache, "a.jp2", time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0]["verb"] != PurgeItemFromCache || tasks[0]["identifier"] != "a.jp2" || statusCalls != 2 {
+		t.Errorf("unexpected tasks %v after %d polls", tasks, statusCalls)
+	}
+
+	check, err := c.CheckImage(ctx, "https://islandora.dev/cantaloupe/iiif/2/a.jp2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.Width != 1000 || !strings.HasSuffix(check.Tile, "/0,0,256,256/256,/0/default.jpg") {
+		t.Errorf("unexpected check %+v", check)
+	}
+	if _, err := c.CheckImage(ctx, "https://islandora.dev/cantaloupe/iiif/2/broken.jp2"); err == nil || !strings.HasPrefix(err.Error(), "info.json") {
+		t.Errorf("expected info.json to fail, got %v", err)
+	}
+
+	c.APIEnabled = false
+	if err := c.Purge(ctx, PurgeCache, "", time.Millisecond); err == nil {
+		t.Error("expected an error with the API disabled")
+	}
+}
+
+func TestFetchManifest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "islandora.dev" || r.Header.Get("X-Forwarded-Proto") != "https" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path != "/node/42/book-manifest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"sequences": []}`))
+	}))
+	defer server.Close()
+
+	// send requests for the drupal service to the test server
+	client := &http.Client{Transport: &http.Transport{
+		Proxy: func(r *http.Request) (*url.URL, error) { return url.Parse(server.URL) },
+	}}
+	data, err := FetchManifest(context.Background(), client, "https://islandora.dev", IIIFManifestPath, 42)
+	if err != nil || string(data) != `{"sequences": []}` {
+		t.Errorf("FetchManifest() = %s, %v", data, err)
+	}
+	if _, err := FetchManifest(context.Background(), client, "https://islandora.dev", IIIFManifestPath, 1); err == nil {
+		t.Error("expected an error for a missing manifest")
+	}
+}
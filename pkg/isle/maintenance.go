@@ -0,0 +1,206 @@
+package isle
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+)
+
+// MaintenancePageImage serves the stack wide maintenance page.
+const MaintenancePageImage = "nginx:alpine"
+
+// DefaultMaintenancePage is served by the maintenance page container unless a custom page is given.
+const DefaultMaintenancePage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Down for maintenance</title>
+  <style>body{font-family:sans-serif;max-width:40em;margin:4em auto;padding:0 1em;color:#333}</style>
+</head>
+<body>
+  <h1>Down for maintenance</h1>
+  <p>We are performing scheduled maintenance and will be back shortly. Thank you for your patience.</p>
+</body>
+</html>
+`
+
+// maintenanceNginxConf answers every request with the maintenance page and a 503.
+const maintenanceNginxConf = `server {
+  listen 80 default_server;
+  root /usr/share/nginx/html;
+  error_page 503 /maintenance.html;
+  location = /maintenance.html {
+    internal;
+    add_header Retry-After 3600 always;
+  }
+  location / {
+    return 503;
+  }
+}
+`
+
+// MaintenanceScript is the shell command turning a site's maintenance mode on or off.
+func MaintenanceScript(c *config.Context, site Site, on bool) string {
+	mode := "0"
+	if on {
+		mode = "1"
+	}
+	return DrushCommand(c, site, "state:set", "system.maintenance_mode", mode, "--input-format=integer") +
+		" && " + DrushCommand(c, site, "cache:rebuild")
+}
+
+// ParseMaintenanceMode parses the output of drush state:get system.maintenance_mode.
+func ParseMaintenanceMode(output string) (bool, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	switch value := strings.TrimSpace(lines[len(lines)-1]); value {
+	case "1", "true":
+		return true, nil
+	case "0", "false", "":
+		// an unset state means maintenance mode was never turned on
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected maintenance mode %q", value)
+	}
+}
+
+// MaintenanceContainerName is the name of the container serving the maintenance page for a context.
+func MaintenanceContainerName(c *config.Context) string {
+	return fmt.Sprintf("%s-islectl-maintenance", c.ProjectName)
+}
+
+// SiteRouters are the entrypoints and cert resolver of the site's Traefik routers.
+// The maintenance page's routers copy them, so they answer on the same entrypoints
+// with the same certificates as the site.
+type SiteRouters struct {
+	EntryPoints    string
+	TLSEntryPoints string
+	CertResolver   string
+}
+
+// ParseSiteRouters reads SiteRouters from a container's Traefik labels.
+// The first router by name of each kind wins when there are several.
+func ParseSiteRouters(labels map[string]string) SiteRouters {
+	type router struct {
+		entryPoints, certResolver string
+		tls                       bool
+	}
+	routers := map[string]*router{}
+	for label, value := range labels {
+		rest, ok := strings.CutPrefix(label, "traefik.http.routers.")
+		if !ok {
+			continue
+		}
+		name, key, ok := strings.Cut(rest, ".")
+		if !ok {
+			continue
+		}
+		r := routers[name]
+		if r == nil {
+			r = &router{}
+			routers[name] = r
+		}
+		switch key {
+		case "entrypoints":
+			r.entryPoints = value
+		case "tls":
+			r.tls = value == "true"
+		case "tls.certresolver":
+			r.tls = true
+			r.certResolver = value
+		}
+	}
+
+	var site SiteRouters
+	for _, name := range slices.Sorted(maps.Keys(routers)) {
+		r := routers[name]
+		switch {
+		case r.tls && site.TLSEntryPoints == "" && site.CertResolver == "":
+			site.TLSEntryPoints = r.entryPoints
+			site.CertResolver = r.certResolver
+		case !r.tls && site.EntryPoints == "":
+			site.EntryPoints = r.entryPoints
+		}
+	}
+	return site
+}
+
+// SiteRouters returns the Traefik routers of the context's drupal container.
+// They are empty when drupal isn't running.
+func (d *DockerClient) SiteRouters(ctx context.Context, c *config.Context) (SiteRouters, error) {
+	containerName, err := d.GetContainerName(c, "drupal", false)
+	if err != nil || containerName == "" {
+		return SiteRouters{}, err
+	}
+	container, err := d.CLI.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return SiteRouters{}, fmt.Errorf("error inspecting %s: %v", containerName, err)
+	}
+	if container.Config == nil {
+		return SiteRouters{}, nil
+	}
+	return ParseSiteRouters(container.Config.Labels), nil
+}
+
+// MaintenancePageCommand is the host command (re)starting the maintenance page container.
+// Its Traefik routers have the highest priority so they catch every request to the stack.
+func MaintenancePageCommand(c *config.Context, page string, routers SiteRouters) []string {
+	name := MaintenanceContainerName(c)
+	networkName := fmt.Sprintf("%s_default", c.ProjectName)
+	labels := []string{
+		"traefik.enable=true",
+		"traefik.docker.network=" + networkName,
+		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port=80", name),
+		fmt.Sprintf("traefik.http.routers.%s.rule=PathPrefix(`/`)", name),
+		fmt.Sprintf("traefik.http.routers.%s.priority=%d", name, 1<<30),
+		fmt.Sprintf("traefik.http.routers.%s.service=%s", name, name),
+		fmt.Sprintf("traefik.http.routers.%s-tls.rule=PathPrefix(`/`)", name),
+		fmt.Sprintf("traefik.http.routers.%s-tls.priority=%d", name, 1<<30),
+		fmt.Sprintf("traefik.http.routers.%s-tls.service=%s", name, name),
+		fmt.Sprintf("traefik.http.routers.%s-tls.tls=true", name),
+		"islectl.maintenance=" + c.ProjectName,
+	}
+	if routers.EntryPoints != "" {
+		labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.entrypoints=%s", name, routers.EntryPoints))
+	}
+	if routers.TLSEntryPoints != "" {
+		labels = append(labels, fmt.Sprintf("traefik.http.routers.%s-tls.entrypoints=%s", name, routers.TLSEntryPoints))
+	}
+	if routers.CertResolver != "" {
+		labels = append(labels, fmt.Sprintf("traefik.http.routers.%s-tls.tls.certresolver=%s", name, routers.CertResolver))
+	}
+
+	args := []string{"run", "--detach", "--name", name, "--network", networkName, "--restart", "unless-stopped"}
+	for _, label := range labels {
+		args = append(args, "--label", label)
+	}
+	script := `printf '%s' "$MAINTENANCE_CONF" > /etc/nginx/conf.d/default.conf && ` +
+		`printf '%s' "$MAINTENANCE_PAGE" > /usr/share/nginx/html/maintenance.html && ` +
+		`exec nginx -g 'daemon off;'`
+	args = append(args,
+		"--env", "MAINTENANCE_CONF="+maintenanceNginxConf,
+		"--env", "MAINTENANCE_PAGE="+page,
+		MaintenancePageImage, "sh", "-c", script)
+
+	// replace a container left over from an earlier maintenance window
+	return []string{"sh", "-c", fmt.Sprintf("docker rm --force %s >/dev/null 2>&1; docker %s", name, shellquote.Join(args...))}
+}
+
+// MaintenancePageRunning reports whether the maintenance page container is serving the stack.
+func (d *DockerClient) MaintenancePageRunning(ctx context.Context, c *config.Context) (bool, error) {
+	name := MaintenanceContainerName(c)
+	container, err := d.CLI.ContainerInspect(ctx, name)
+	if cerrdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error inspecting maintenance container %q: %v", name, err)
+	}
+	return container.State != nil && container.State.Running, nil
+}
@@ -0,0 +1,137 @@
+package isle
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestParseMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    bool
+		wantErr bool
+	}{
+		{output: "1\n", want: true},
+		{output: "0\n", want: false},
+		{output: "", want: false},
+		{output: " [warning] Something noisy\n1\n", want: true},
+		{output: "maybe", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMaintenanceMode(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseMaintenanceMode(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseMaintenanceMode(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenancePageCommand(t *testing.T) {
+	c := &config.Context{ProjectName: "isle"}
+	cmd := MaintenancePageCommand(c, "<h1>Back soon</h1>", SiteRouters{
+		EntryPoints:    "http",
+		TLSEntryPoints: "https",
+		CertResolver:   "myresolver",
+	})
+	if len(cmd) != 3 || cmd[0] != "sh" {
+		t.Fatalf("expected a shell command, got %q", cmd)
+	}
+	script := cmd[2]
+	for _, want := range []string{
+		"docker rm --force isle-islectl-maintenance",
+		"--network isle_default",
+		"traefik.http.routers.isle-islectl-maintenance-tls.tls=true",
+		"traefik.http.routers.isle-islectl-maintenance.entrypoints=http",
+		"traefik.http.routers.isle-islectl-maintenance-tls.entrypoints=https",
+		"traefik.http.routers.isle-islectl-maintenance-tls.tls.certresolver=myresolver",
+		"Back soon",
+		MaintenancePageImage,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected %q in %q", want, script)
+		}
+	}
+}
+
+func TestParseSiteRouters(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   SiteRouters
+	}{
+		{
+			name: "site template",
+			labels: map[string]string{
+				"traefik.enable": "true",
+				"traefik.http.routers.drupal_http.entrypoints":       "http",
+				"traefik.http.routers.drupal_http.rule":              "Host(`islandora.dev`)",
+				"traefik.http.routers.drupal_https.entrypoints":      "https",
+				"traefik.http.routers.drupal_https.tls":              "true",
+				"traefik.http.routers.drupal_https.tls.certresolver": "myresolver",
+			},
+			want: SiteRouters{EntryPoints: "http", TLSEntryPoints: "https", CertResolver: "myresolver"},
+		},
+		{
+			name: "tls without a resolver",
+			labels: map[string]string{
+				"traefik.http.routers.drupal.entrypoints": "websecure",
+				"traefik.http.routers.drupal.tls":         "true",
+			},
+			want: SiteRouters{TLSEntryPoints: "websecure"},
+		},
+		{
+			name:   "no traefik labels",
+			labels: map[string]string{"com.docker.compose.service": "drupal"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSiteRouters(tt.labels); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMaintenancePageRunning(t *testing.T) {
+	c := &config.Context{ProjectName: "isle"}
+	tests := []struct {
+		name    string
+		inspect func(ctx context.Context, container string) (dockercontainer.InspectResponse, error)
+		want    bool
+	}{
+		{
+			name: "running",
+			inspect: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+				return dockercontainer.InspectResponse{ContainerJSONBase: &dockercontainer.ContainerJSONBase{State: &dockercontainer.State{Running: true}}}, nil
+			},
+			want: true,
+		},
+		{
+			name: "missing",
+			inspect: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+				return dockercontainer.InspectResponse{}, cerrdefs.ErrNotFound
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DockerClient{CLI: &FakeDockerClient{InspectFunc: tt.inspect}}
+			got, err := d.MaintenancePageRunning(context.Background(), c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MaintenancePageRunning() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package isle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+)
+
+// MigrateUploadDir is where migration source files are uploaded to in the drupal container.
+const MigrateUploadDir = "/var/www/drupal/private/migrate"
+
+// Migration is a row of drush migrate:status.
+type Migration struct {
+	Group        string     `json:"group"`
+	ID           string     `json:"id"`
+	Status       string     `json:"status"`
+	Total        drushCount `json:"total"`
+	Imported     drushCount `json:"imported"`
+	Unprocessed  drushCount `json:"unprocessed"`
+	LastImported string     `json:"last_imported"`
+}
+
+// drushCount is a count drush reports as a number, a numeric string or N/A.
+type drushCount string
+
+func (v *drushCount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = drushCount(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		if string(data) == "null" {
+			*v = ""
+			return nil
+		}
+		return err
+	}
+	*v = drushCount(n.String())
+	return nil
+}
+
+// Int returns the count, or 0 when drush could not count the items.
+func (v drushCount) Int() int {
+	n, _ := strconv.Atoi(string(v))
+	return n
+}
+
+// ParseMigrateStatus parses the output of drush migrate:status --format=json.
+func ParseMigrateStatus(data []byte) ([]Migration, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return []Migration{}, nil
+	}
+
+	migrations := []Migration{}
+	if data[0] == '{' {
+		// drush keys the rows when the table has an index
+		keyed := map[string]Migration{}
+		if err := json.Unmarshal(data, &keyed); err != nil {
+			return nil, fmt.Errorf("unable to parse migrate:status output: %v", err)
+		}
+		for _, m := range keyed {
+			migrations = append(migrations, m)
+		}
+		slices.SortFunc(migrations, func(a, b Migration) int {
+			return strings.Compare(a.Group+a.ID, b.Group+b.ID)
+		})
+	} else if err := json.Unmarshal(data, &migrations); err != nil {
+		return nil, fmt.Errorf("unable to parse migrate:status output: %v", err)
+	}
+
+	return migrations, nil
+}
+
+// MigrateArgs are the drush arguments to import or roll back migrations by ID, group or all of them.
+func MigrateArgs(command string, ids []string, group string, all bool, extra ...string) ([]string, error) {
+	selected := 0
+	for _, set := range []bool{len(ids) > 0, group != "", all} {
+		if set {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return nil, fmt.Errorf("pass migration IDs, --group or --all")
+	}
+
+	args := []string{command}
+	switch {
+	case len(ids) > 0:
+		args = append(args, strings.Join(ids, ","))
+	case group != "":
+		args = append(args, "--group="+group)
+	case all:
+		args = append(args, "--all")
+	}
+	return append(args, extra...), nil
+}
+
+var migrateProgressRE = regexp.MustCompile(`Processed (\d+) items? \((\d+) created, (\d+) updated, (\d+) failed, (\d+) ignored\).* - (continuing|done) with '([^']+)'`)
+
+// MigrateProgress is the running count of items a migration processed.
+type MigrateProgress struct {
+	Migration string
+	Processed int
+	Created   int
+	Updated   int
+	Failed    int
+	Ignored   int
+	Done      bool
+}
+
+// MigrateTracker adds up the progress messages drush migrate:import --feedback prints,
+// which only count the items since the previous message.
+type MigrateTracker struct {
+	// Totals are the items each migration is expected to process
+	Totals   map[string]int
+	progress map[string]*MigrateProgress
+}
+
+// NewMigrateTracker returns a tracker expecting the unprocessed items of migrations,
+// or all their items when they are being updated.
+func NewMigrateTracker(migrations []Migration, update bool) *MigrateTracker {
+	totals := map[string]int{}
+	for _, m := range migrations {
+		if update {
+			totals[m.ID] = m.Total.Int()
+		} else {
+			totals[m.ID] = m.Unprocessed.Int()
+		}
+	}
+	return &MigrateTracker{Totals: totals, progress: map[string]*MigrateProgress{}}
+}
+
+// Update parses a line of drush output and returns the migration's progress if it was a progress message.
+func (t *MigrateTracker) Update(line string) (MigrateProgress, bool) {
+	match := migrateProgressRE.FindStringSubmatch(line)
+	if match == nil {
+		return MigrateProgress{}, false
+	}
+	name := match[7]
+	p, ok := t.progress[name]
+	if !ok {
+		p = &MigrateProgress{Migration: name}
+		t.progress[name] = p
+	}
+	counts := []*int{&p.Processed, &p.Created, &p.Updated, &p.Failed, &p.Ignored}
+	for i, count := range counts {
+		n, _ := strconv.Atoi(match[i+1])
+		*count += n
+	}
+	p.Done = match[6] == "done"
+
+	return *p, true
+}
+
+// Format renders progress as a status line, with a percentage when the total is known.
+func (t *MigrateTracker) Format(p MigrateProgress) string {
+	done := fmt.Sprintf("%d", p.Processed)
+	if total := t.Totals[p.Migration]; total > 0 {
+		percent := min(100, p.Processed*100/total)
+		done = fmt.Sprintf("%d/%d (%d%%)", p.Processed, total, percent)
+	}
+	state := "importing"
+	if p.Done {
+		state = "done"
+	}
+	return fmt.Sprintf("%s: %s %s, %d created, %d updated, %d failed, %d ignored",
+		p.Migration, state, done, p.Created, p.Updated, p.Failed, p.Ignored)
+}
+
+// UploadMigrationFiles copies local files and directories into dest in the drupal container.
+func (d *DockerClient) UploadMigrationFiles(ctx context.Context, c *config.Context, dest string, paths []string) ([]string, error) {
+	archive, names, err := filesTar(paths)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	drupalContainer, err := d.drupalContainer(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := runInContainer(c, drupalContainer, "mkdir -p "+shellquote.Join(dest)); err != nil {
+		return nil, fmt.Errorf("unable to create %s: %v", dest, err)
+	}
+	if err := d.CLI.CopyToContainer(ctx, drupalContainer, dest, archive, dockercontainer.CopyToContainerOptions{}); err != nil {
+		return nil, fmt.Errorf("unable to copy files to %s: %v", c.Name, err)
+	}
+
+	uploaded := make([]string, len(names))
+	for i, name := range names {
+		uploaded[i] = path.Join(dest, name)
+	}
+	return uploaded, nil
+}
+
+// filesTar streams a tar archive of local files, and the contents of local directories,
+// by their base name. The files are listed up front so missing paths fail before anything
+// is sent, then read as the archive is consumed. Closing the reader stops the archive.
+func filesTar(paths []string) (io.ReadCloser, []string, error) {
+	entries, err := tarEntries(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.header.Typeflag == tar.TypeReg {
+			names = append(names, entry.header.Name)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, entries))
+	}()
+	return pr, names, nil
+}
+
+type tarEntry struct {
+	file   string
+	header *tar.Header
+}
+
+func tarEntries(paths []string) ([]tarEntry, error) {
+	entries := []tarEntry{}
+	for _, p := range paths {
+		root := filepath.Dir(filepath.Clean(p))
+		err := filepath.WalkDir(p, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			// readable by the web server whoever owned it locally
+			header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+			header.Mode = 0644
+			if entry.IsDir() {
+				header.Name += "/"
+				header.Mode = 0755
+			}
+			entries = append(entries, tarEntry{file: file, header: header})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %v", p, err)
+		}
+	}
+	return entries, nil
+}
+
+func writeTar(w io.Writer, entries []tarEntry) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		if err := tw.WriteHeader(entry.header); err != nil {
+			return err
+		}
+		if entry.header.Typeflag != tar.TypeReg {
+			continue
+		}
+		f, err := os.Open(entry.file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", entry.file, err)
+		}
+	}
+	return tw.Close()
+}
@@ -0,0 +1,156 @@
+package isle
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMigrateStatus(t *testing.T) {
+	output := []byte(`[
+  {"group": "Islandora (islandora)", "id": "islandora_objects", "status": "Idle", "total": 250, "imported": 100, "unprocessed": 150, "last_imported": "2025-03-01 12:00:00"},
+  {"group": "Islandora (islandora)", "id": "islandora_media", "status": "Importing", "total": "N/A", "imported": "0", "unprocessed": null, "last_imported": ""}
+]`)
+	migrations, err := ParseMigrateStatus(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Total.Int() != 250 || migrations[0].Unprocessed.Int() != 150 {
+		t.Errorf("unexpected counts %+v", migrations[0])
+	}
+	if migrations[1].Total != "N/A" || migrations[1].Total.Int() != 0 || migrations[1].Unprocessed != "" {
+		t.Errorf("unexpected counts %+v", migrations[1])
+	}
+
+	keyed := []byte(`{"b": {"group": "g", "id": "b"}, "a": {"group": "g", "id": "a"}}`)
+	migrations, err = ParseMigrateStatus(keyed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].ID != "a" {
+		t.Errorf("expected keyed rows sorted by ID, got %+v", migrations)
+	}
+
+	if _, err := ParseMigrateStatus([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid output")
+	}
+}
+
+func TestMigrateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []string
+		group   string
+		all     bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "ids", ids: []string{"a", "b"}, want: []string{"migrate:import", "a,b", "--feedback=50"}},
+		{name: "group", group: "islandora", want: []string{"migrate:import", "--group=islandora", "--feedback=50"}},
+		{name: "all", all: true, want: []string{"migrate:import", "--all", "--feedback=50"}},
+		{name: "none", wantErr: true},
+		{name: "both", ids: []string{"a"}, all: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MigrateArgs("migrate:import", tt.ids, tt.group, tt.all, "--feedback=50")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrateArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MigrateArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateTracker(t *testing.T) {
+	tracker := NewMigrateTracker([]Migration{{ID: "islandora_objects", Unprocessed: "150", Total: "250"}}, false)
+
+	if _, ok := tracker.Update(" [notice] Some other message"); ok {
+		t.Error("expected a non progress line to be ignored")
+	}
+
+	p, ok := tracker.Update(" [notice] Processed 100 items (98 created, 0 updated, 2 failed, 0 ignored) in 12.3 seconds (487.8/min) - continuing with 'islandora_objects'")
+	if !ok || p.Processed != 100 || p.Failed != 2 || p.Done {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+	p, ok = tracker.Update(" [notice] Processed 50 items (50 created, 0 updated, 0 failed, 0 ignored) - done with 'islandora_objects'")
+	if !ok || p.Processed != 150 || p.Created != 148 || !p.Done {
+		t.Fatalf("expected the counts to add up, got %+v", p)
+	}
+	want := "islandora_objects: done 150/150 (100%), 148 created, 0 updated, 2 failed, 0 ignored"
+	if got := tracker.Format(p); got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+
+	p, _ = tracker.Update("Processed 1 item (1 created, 0 updated, 0 failed, 0 ignored) - done with 'unknown'")
+	if got := tracker.Format(p); got != "unknown: done 1, 1 created, 0 updated, 0 failed, 0 ignored" {
+		t.Errorf("unexpected format without a total %q", got)
+	}
+}
+
+func TestFilesTar(t *testing.T) {
+	dir := t.TempDir()
+	batch := filepath.Join(dir, "batch")
+	if err := os.MkdirAll(filepath.Join(batch, "files"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		filepath.Join(dir, "objects.csv"):          "id,title\n",
+		filepath.Join(batch, "media.csv"):          "id,file\n",
+		filepath.Join(batch, "files", "image.jpg"): "jpg",
+	} {
+		if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, names, err := filesTar([]string{filepath.Join(dir, "objects.csv"), batch})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Close()
+	want := []string{"objects.csv", "batch/files/image.jpg", "batch/media.csv"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg && header.Mode != 0644 {
+			t.Errorf("expected %s to be world readable, got %o", header.Name, header.Mode)
+		}
+	}
+
+	if _, _, err := filesTar([]string{filepath.Join(dir, "missing.csv")}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	// files are read as the archive streams, so one removed in between fails the read
+	archive, _, err = filesTar([]string{filepath.Join(dir, "objects.csv")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Close()
+	if err := os.Remove(filepath.Join(dir, "objects.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(archive); err == nil {
+		t.Error("expected an error reading a file removed after it was listed")
+	}
+}
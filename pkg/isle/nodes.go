@@ -0,0 +1,169 @@
+package isle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// DrupalNode is a node Islandora indexes in Fedora and the triplestore.
+type DrupalNode struct {
+	ID   int    `json:"nid"`
+	UUID string `json:"uuid"`
+	Type string `json:"type"`
+}
+
+// DrupalNodesScript prints every node's ID, UUID and content type as JSON.
+// It is run with drush php:eval.
+const DrupalNodesScript = `$out = [];
+foreach (\Drupal::database()->query('SELECT nid, uuid, type FROM {node} ORDER BY nid') as $row) {
+  $out[] = ['nid' => (int) $row->nid, 'uuid' => $row->uuid, 'type' => $row->type];
+}
+echo json_encode($out);`
+
+// ParseDrupalNodes parses the output of DrupalNodesScript, keeping the nodes of the given content types.
+// Every node is kept when no types are given.
+func ParseDrupalNodes(output string, types []string) ([]DrupalNode, error) {
+	// the JSON is a single line, skip any warnings drush printed before it
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	nodes := []DrupalNode{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &nodes); err != nil {
+		return nil, fmt.Errorf("unable to parse drupal nodes: %v", err)
+	}
+	if len(types) == 0 {
+		return nodes, nil
+	}
+	return slices.DeleteFunc(nodes, func(n DrupalNode) bool { return !slices.Contains(types, n.Type) }), nil
+}
+
+// DrupalNodes returns a site's nodes of the given content types, or every node when no types are given.
+func DrupalNodes(c *config.Context, site Site, types []string) ([]DrupalNode, error) {
+	command := exec.Command("docker", DrupalExecArgs(c, DrushCommand(c, site, "php:eval", DrupalNodesScript))...)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDrupalNodes(output, types)
+}
+
+// EntityIDsScript prints the IDs of every entity of a type, e.g. media or taxonomy_term, as JSON.
+// It is run with drush php:eval.
+func EntityIDsScript(entityType string) string {
+	return fmt.Sprintf(`$ids = \Drupal::entityQuery(%s)->accessCheck(FALSE)->execute();
+echo json_encode(array_map('intval', array_values($ids)));`, phpString(entityType))
+}
+
+// EntityIDs returns the IDs of a site's entities of a type.
+func EntityIDs(c *config.Context, site Site, entityType string) ([]int, error) {
+	command := exec.Command("docker", DrupalExecArgs(c, DrushCommand(c, site, "php:eval", EntityIDsScript(entityType)))...)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	ids := []int{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &ids); err != nil {
+		return nil, fmt.Errorf("unable to parse the IDs of %s entities: %v", entityType, err)
+	}
+	return ids, nil
+}
+
+// MissingActionsScript prints the actions that don't exist, one per line.
+// It is run with drush php:eval.
+func MissingActionsScript(actions []string) string {
+	quoted := make([]string, len(actions))
+	for i, action := range actions {
+		quoted[i] = phpString(action)
+	}
+	return fmt.Sprintf(`$storage = \Drupal::entityTypeManager()->getStorage('action');
+foreach ([%s] as $id) {
+  if (!$storage->load($id)) {
+    echo "$id\n";
+  }
+}`, strings.Join(quoted, ", "))
+}
+
+// MissingActions returns which of the Drupal actions don't exist on a site.
+func MissingActions(c *config.Context, site Site, actions []string) ([]string, error) {
+	command := exec.Command("docker", DrupalExecArgs(c, DrushCommand(c, site, "php:eval", MissingActionsScript(actions)))...)
+	command.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+	missing := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); slices.Contains(actions, line) {
+			missing = append(missing, line)
+		}
+	}
+	return missing, nil
+}
+
+// NodeActionScript runs a Drupal action on nodes, e.g. FcrepoIndexAction to queue them for Fedora.
+// It is run with drush php:eval and prints how many nodes the action ran on.
+func NodeActionScript(action string, ids []int) string {
+	return EntityActionScript("node", action, ids)
+}
+
+// EntityActionScript runs a Drupal action on entities of a type.
+// It is run with drush php:eval and prints how many entities the action ran on.
+func EntityActionScript(entityType, action string, ids []int) string {
+	nids := make([]string, len(ids))
+	for i, id := range ids {
+		nids[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf(`$id = %s;
+$action = \Drupal::entityTypeManager()->getStorage('action')->load($id);
+if (!$action) {
+  throw new \Exception("action $id not found");
+}
+$entities = \Drupal::entityTypeManager()->getStorage(%s)->loadMultiple([%s]);
+foreach ($entities as $entity) {
+  $action->execute([$entity]);
+}
+echo count($entities);`, phpString(action), phpString(entityType), strings.Join(nids, ", "))
+}
+
+func phpString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// RunNodeAction runs a Drupal action on nodes in batches, calling progress with the running total.
+// It returns how many nodes the action ran on.
+func RunNodeAction(c *config.Context, site Site, action string, ids []int, progress func(done int)) (int, error) {
+	return RunEntityAction(c, site, "node", action, ids, progress)
+}
+
+// RunEntityAction runs a Drupal action on entities of a type in batches, calling progress with the running total.
+// It returns how many entities the action ran on.
+func RunEntityAction(c *config.Context, site Site, entityType, action string, ids []int, progress func(done int)) (int, error) {
+	done := 0
+	// keep the script passed to bash a reasonable size on big repositories
+	for batch := range slices.Chunk(ids, 500) {
+		script := DrushCommand(c, site, "php:eval", EntityActionScript(entityType, action, batch))
+		command := exec.Command("docker", DrupalExecArgs(c, script)...)
+		command.Dir = c.ProjectDir
+		output, err := c.CaptureCommand(command, nil)
+		if err != nil {
+			return done, fmt.Errorf("unable to run %s: %v", action, err)
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		n, err := strconv.Atoi(strings.TrimSpace(lines[len(lines)-1]))
+		if err != nil {
+			return done, fmt.Errorf("unexpected output from %s: %s", action, output)
+		}
+		done += n
+		if progress != nil {
+			progress(done)
+		}
+	}
+	return done, nil
+}
@@ -0,0 +1,49 @@
+package isle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDrupalNodes(t *testing.T) {
+	output := ` [warning] Some module is deprecated
+[{"nid":1,"uuid":"a1b2c3d4-0000-0000-0000-000000000001","type":"islandora_object"},{"nid":2,"uuid":"a1b2c3d4-0000-0000-0000-000000000002","type":"page"}]`
+	nodes, err := ParseDrupalNodes(output, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 || nodes[1].ID != 2 || nodes[1].Type != "page" {
+		t.Fatalf("unexpected nodes %+v", nodes)
+	}
+	nodes, err = ParseDrupalNodes(output, []string{"islandora_object"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].ID != 1 {
+		t.Errorf("expected only the islandora_object, got %+v", nodes)
+	}
+	if _, err := ParseDrupalNodes("Command php:eval failed", nil); err == nil {
+		t.Error("expected an error for output without JSON")
+	}
+}
+
+func TestNodeActionScript(t *testing.T) {
+	script := NodeActionScript(`it's`, []int{3, 5})
+	if !strings.Contains(script, `$id = 'it\'s';`) || !strings.Contains(script, "loadMultiple([3, 5])") {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+}
+
+func TestEntityActionScript(t *testing.T) {
+	script := EntityActionScript("taxonomy_term", TriplestoreTermIndexAction, []int{7})
+	if !strings.Contains(script, "getStorage('taxonomy_term')->loadMultiple([7])") || !strings.Contains(script, "$id = 'index_taxonomy_term_in_triplestore';") {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+}
+
+func TestMissingActionsScript(t *testing.T) {
+	script := MissingActionsScript([]string{TriplestoreIndexAction, TriplestoreMediaIndexAction})
+	if !strings.Contains(script, "foreach (['index_node_in_triplestore', 'index_media_in_triplestore'] as $id)") {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+}
@@ -0,0 +1,279 @@
+package isle
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	socksVersion       = 0x05
+	socksNoAuth        = 0x00
+	socksNoAcceptable  = 0xff
+	socksCmdConnect    = 0x01
+	socksAddrIPv4      = 0x01
+	socksAddrDomain    = 0x03
+	socksAddrIPv6      = 0x04
+	socksReplySuccess  = 0x00
+	socksReplyFailure  = 0x01
+	socksReplyNotAllow = 0x07
+)
+
+// DialHost connects to host:port through the tunnel.
+// Hosts that are docker compose service names (e.g. solr, fcrepo, mariadb), bare or qualified with
+// the project name (e.g. solr.isle), are resolved to their container IP on the project network.
+// Anything else is dialed as-is from the docker host so regular websites keep working through the proxy.
+func (t *Tunnel) DialHost(ctx context.Context, host string, port int) (net.Conn, error) {
+	if service, ok := ServiceHost(host, t.Context.ProjectName); ok {
+		if _, err := t.ServiceIP(ctx, service, false); err == nil {
+			return t.DialService(ctx, service, port)
+		}
+	}
+
+	return t.dialAddr(net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// ServiceHost returns the compose service a hostname may name: a bare name like solr, or
+// service.project. Other hostnames, e.g. solr.example.org, are never services.
+func ServiceHost(host, project string) (string, bool) {
+	if net.ParseIP(host) != nil {
+		return "", false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	service, domain, qualified := strings.Cut(host, ".")
+	if service == "" || (qualified && (project == "" || domain != strings.ToLower(project))) {
+		return "", false
+	}
+	return service, true
+}
+
+// Proxy serves SOCKS5 and HTTP proxies whose connections go through a Tunnel.
+type Proxy struct {
+	Tunnel *Tunnel
+}
+
+// ServeSOCKS5 accepts SOCKS5 clients until the listener is closed.
+func (p *Proxy) ServeSOCKS5(ctx context.Context, listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			if err := p.handleSOCKS5(ctx, conn); err != nil {
+				slog.Debug("SOCKS5 connection failed", "client", conn.RemoteAddr(), "err", err)
+			}
+		}()
+	}
+}
+
+func (p *Proxy) handleSOCKS5(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// greeting: VER NMETHODS METHODS...
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fmt.Errorf("error reading greeting: %w", err)
+	}
+	if header[0] != socksVersion {
+		return fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(reader, methods); err != nil {
+		return fmt.Errorf("error reading auth methods: %w", err)
+	}
+	if !slices.Contains(methods, socksNoAuth) {
+		_, _ = conn.Write([]byte{socksVersion, socksNoAcceptable})
+		return fmt.Errorf("client does not support unauthenticated connections")
+	}
+	if _, err := conn.Write([]byte{socksVersion, socksNoAuth}); err != nil {
+		return err
+	}
+
+	// request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(reader, request); err != nil {
+		return fmt.Errorf("error reading request: %w", err)
+	}
+	if request[1] != socksCmdConnect {
+		_ = writeSOCKS5Reply(conn, socksReplyNotAllow)
+		return fmt.Errorf("unsupported SOCKS command %d", request[1])
+	}
+
+	var host string
+	switch request[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		size := net.IPv4len
+		if request[3] == socksAddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(reader, ip); err != nil {
+			return fmt.Errorf("error reading address: %w", err)
+		}
+		host = net.IP(ip).String()
+	case socksAddrDomain:
+		size, err := reader.ReadByte()
+		if err != nil {
+			return fmt.Errorf("error reading domain length: %w", err)
+		}
+		domain := make([]byte, size)
+		if _, err := io.ReadFull(reader, domain); err != nil {
+			return fmt.Errorf("error reading domain: %w", err)
+		}
+		host = string(domain)
+	default:
+		_ = writeSOCKS5Reply(conn, socksReplyNotAllow)
+		return fmt.Errorf("unsupported address type %d", request[3])
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(reader, portBytes); err != nil {
+		return fmt.Errorf("error reading port: %w", err)
+	}
+	port := int(binary.BigEndian.Uint16(portBytes))
+
+	remote, err := p.Tunnel.DialHost(ctx, host, port)
+	if err != nil {
+		_ = writeSOCKS5Reply(conn, socksReplyFailure)
+		return fmt.Errorf("error dialing %s:%d: %w", host, port, err)
+	}
+	defer remote.Close()
+	if err := writeSOCKS5Reply(conn, socksReplySuccess); err != nil {
+		return err
+	}
+
+	slog.Debug("SOCKS5 proxying", "host", host, "port", port)
+	Pipe(&bufferedConn{Conn: conn, reader: reader}, remote, nil, nil)
+	return nil
+}
+
+func writeSOCKS5Reply(conn net.Conn, status byte) error {
+	// bound address is not meaningful for a tunnel, so always report 0.0.0.0:0
+	_, err := conn.Write([]byte{socksVersion, status, 0x00, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// ServeHTTPProxy accepts HTTP proxy clients until the listener is closed.
+// CONNECT requests are tunnelled and plain http:// requests are forwarded.
+func (p *Proxy) ServeHTTPProxy(ctx context.Context, listener net.Listener) error {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, portStr, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return nil, err
+			}
+			return p.Tunnel.DialHost(ctx, host, port)
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodConnect {
+				p.handleConnect(ctx, w, r)
+				return
+			}
+			if r.URL.Host == "" {
+				http.Error(w, "islectl proxy only accepts proxy requests", http.StatusBadRequest)
+				return
+			}
+
+			r.RequestURI = ""
+			r.Header.Del("Proxy-Connection")
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(resp.StatusCode)
+			if _, err := io.Copy(w, resp.Body); err != nil {
+				slog.Debug("error copying proxied response", "url", r.URL, "err", err)
+			}
+		}),
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (p *Proxy) handleConnect(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	host, portStr, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	remote, err := p.Tunnel.DialHost(ctx, host, port)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer remote.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		slog.Debug("unable to hijack connection", "err", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+
+	Pipe(&bufferedConn{Conn: conn, reader: buf.Reader}, remote, nil, nil)
+}
+
+// bufferedConn reads through a bufio.Reader so bytes buffered
+// while parsing the proxy handshake are not lost.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
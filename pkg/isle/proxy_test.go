@@ -0,0 +1,160 @@
+package isle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+// startEcho starts a TCP server that echoes back the first line it receives.
+func startEcho(t *testing.T) net.Listener {
+	t.Helper()
+	echo, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("unable to start echo server: %v", err)
+	}
+	t.Cleanup(func() { echo.Close() })
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				fmt.Fprint(conn, line)
+			}()
+		}
+	}()
+	return echo
+}
+
+func TestProxySOCKS5ResolvesServiceNames(t *testing.T) {
+	echo := startEcho(t)
+	dialed := ""
+	cli := fakeServiceClient(func() string { return "10.0.0.5" })
+	tunnel := newTestTunnel(cli, func(d *DockerClient, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return net.Dial("tcp", echo.Addr().String())
+	})
+
+	listener, port, err := ListenLocal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	proxy := &Proxy{Tunnel: tunnel}
+	go func() { _ = proxy.ServeSOCKS5(context.Background(), listener) }()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		t.Fatalf("unable to connect to proxy: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{socksVersion, 1, socksNoAuth}); err != nil {
+		t.Fatalf("unable to write greeting: %v", err)
+	}
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Fatalf("unable to read greeting: %v", err)
+	}
+	if greeting[1] != socksNoAuth {
+		t.Fatalf("expected no auth method, got %d", greeting[1])
+	}
+
+	host := "solr"
+	request := []byte{socksVersion, socksCmdConnect, 0x00, socksAddrDomain, byte(len(host))}
+	request = append(request, host...)
+	request = append(request, 0x23, 0x17) // 8983
+	if _, err := conn.Write(request); err != nil {
+		t.Fatalf("unable to write request: %v", err)
+	}
+	reply := make([]byte, 10)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("unable to read reply: %v", err)
+	}
+	if reply[1] != socksReplySuccess {
+		t.Fatalf("expected success reply, got %d", reply[1])
+	}
+
+	fmt.Fprint(conn, "ping\n")
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unable to read from tunnel: %v", err)
+	}
+	if line != "ping\n" {
+		t.Errorf("expected %q, got %q", "ping\n", line)
+	}
+	if dialed != "10.0.0.5:8983" {
+		t.Errorf("expected the proxy to dial the service IP, got %q", dialed)
+	}
+}
+
+func TestProxyHTTPConnect(t *testing.T) {
+	echo := startEcho(t)
+	cli := fakeServiceClient(func() string { return "10.0.0.5" })
+	tunnel := newTestTunnel(cli, func(d *DockerClient, network, addr string) (net.Conn, error) {
+		return net.Dial("tcp", echo.Addr().String())
+	})
+
+	listener, port, err := ListenLocal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proxy := &Proxy{Tunnel: tunnel}
+	go func() { _ = proxy.ServeHTTPProxy(ctx, listener) }()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		t.Fatalf("unable to connect to proxy: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "CONNECT fcrepo:8080 HTTP/1.1\r\nHost: fcrepo:8080\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("unable to read CONNECT response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	fmt.Fprint(conn, "ping\n")
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("unable to read from tunnel: %v", err)
+	}
+	if line != "ping\n" {
+		t.Errorf("expected %q, got %q", "ping\n", line)
+	}
+}
+
+func TestServiceHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		service string
+		ok      bool
+	}{
+		{host: "solr", service: "solr", ok: true},
+		{host: "solr.isle", service: "solr", ok: true},
+		{host: "Drupal.ISLE.", service: "drupal", ok: true},
+		{host: "drupal.example.edu"},
+		{host: "solr.isle.example.org"},
+		{host: "10.0.0.5"},
+		{host: "::1"},
+	}
+	for _, tt := range tests {
+		service, ok := ServiceHost(tt.host, "isle")
+		if service != tt.service || ok != tt.ok {
+			t.Errorf("ServiceHost(%q) = %q, %v, want %q, %v", tt.host, service, ok, tt.service, tt.ok)
+		}
+	}
+}
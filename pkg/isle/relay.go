@@ -0,0 +1,231 @@
+package isle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// RelayImage is the image used for the helper container that relays
+// connections into the docker network when container IPs are not routable
+// from the host, e.g. Docker Desktop on Mac OS. It is pinned so every
+// islectl process sharing a relay runs the same socat.
+const RelayImage = "alpine/socat:1.8.0.1"
+
+// RelayContainerName is the name of the relay helper container for a context.
+func RelayContainerName(c *config.Context) string {
+	return fmt.Sprintf("%s-islectl-relay", c.ProjectName)
+}
+
+// StartRelay ensures the relay helper container is running on the project network.
+// It is reused across connections, and by other islectl processes for the same project.
+func (d *DockerClient) StartRelay(ctx context.Context, c *config.Context) error {
+	d.relayMu.Lock()
+	defer d.relayMu.Unlock()
+	if d.relayID != "" {
+		return nil
+	}
+
+	name := RelayContainerName(c)
+	existing, err := d.CLI.ContainerInspect(ctx, name)
+	switch {
+	case err == nil && existing.State != nil && existing.State.Running:
+		// another islectl process already started a relay, share it
+		d.relayID = existing.ID
+		return nil
+	case err == nil:
+		if err := d.CLI.ContainerRemove(ctx, existing.ID, dockercontainer.RemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("error removing stopped relay container %q: %v", name, err)
+		}
+	case !cerrdefs.IsNotFound(err):
+		return fmt.Errorf("error inspecting relay container %q: %v", name, err)
+	}
+
+	networkName := fmt.Sprintf("%s_default", c.ProjectName)
+	containerConfig := &dockercontainer.Config{
+		Image:      RelayImage,
+		Entrypoint: []string{"tail", "-f", "/dev/null"},
+		Labels: map[string]string{
+			"islectl.relay": c.ProjectName,
+		},
+	}
+	hostConfig := &dockercontainer.HostConfig{AutoRemove: true}
+	networkConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			networkName: {},
+		},
+	}
+
+	resp, err := d.CLI.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, name)
+	if cerrdefs.IsNotFound(err) {
+		slog.Info("Pulling relay image", "image", RelayImage)
+		if err := d.pullImage(ctx, RelayImage); err != nil {
+			return err
+		}
+		resp, err = d.CLI.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, name)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating relay container: %v", err)
+	}
+	if err := d.CLI.ContainerStart(ctx, resp.ID, dockercontainer.StartOptions{}); err != nil {
+		return fmt.Errorf("error starting relay container: %v", err)
+	}
+	slog.Debug("Started relay container", "name", name, "id", resp.ID)
+	d.relayID = resp.ID
+	d.relayOwned = true
+
+	return nil
+}
+
+func (d *DockerClient) pullImage(ctx context.Context, ref string) error {
+	out, err := d.CLI.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("error pulling image %s: %v", ref, err)
+	}
+	defer out.Close()
+
+	// the pull only finishes once the progress stream is consumed
+	_, err = io.Copy(io.Discard, out)
+	return err
+}
+
+// stopRelay removes the relay container if this client started it and
+// no other islectl process still has connections running through it.
+func (d *DockerClient) stopRelay() error {
+	d.relayMu.Lock()
+	defer d.relayMu.Unlock()
+	if !d.relayOwned {
+		return nil
+	}
+	relayID := d.relayID
+	execs := d.relayExecs
+	d.relayID = ""
+	d.relayOwned = false
+	d.relayExecs = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	inspect, err := d.CLI.ContainerInspect(ctx, relayID)
+	if cerrdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error inspecting relay container: %v", err)
+	}
+	for _, id := range inspect.ExecIDs {
+		if _, ok := execs[id]; !ok {
+			// the next process to start a relay reuses it
+			slog.Debug("Leaving relay container running for other clients", "id", relayID)
+			return nil
+		}
+	}
+
+	err = d.CLI.ContainerRemove(ctx, relayID, dockercontainer.RemoveOptions{Force: true})
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("error removing relay container: %v", err)
+	}
+
+	return nil
+}
+
+// DialRelay connects to addr on the project network by running socat
+// in the relay container and piping the connection over the exec's stdio.
+func (d *DockerClient) DialRelay(ctx context.Context, c *config.Context, addr string) (net.Conn, error) {
+	execOptions := dockercontainer.ExecOptions{
+		Cmd:          []string{"socat", "-", "TCP:" + addr},
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	exec, err := d.relayExec(ctx, c, execOptions)
+	if cerrdefs.IsNotFound(err) {
+		// a shared relay was removed when the process that started it exited
+		d.relayMu.Lock()
+		d.relayID = ""
+		d.relayMu.Unlock()
+		exec, err = d.relayExec(ctx, c, execOptions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating relay exec for %s: %v", addr, err)
+	}
+	hijacked, err := d.CLI.ContainerExecAttach(ctx, exec.ID, dockercontainer.ExecAttachOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error attaching to relay exec for %s: %v", addr, err)
+	}
+
+	// without a TTY docker multiplexes stdout and stderr on the same stream
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(writer, &slogWriter{addr: addr}, hijacked.Reader)
+		writer.CloseWithError(err)
+	}()
+
+	return &relayConn{Conn: hijacked.Conn, reader: reader}, nil
+}
+
+func (d *DockerClient) relayExec(ctx context.Context, c *config.Context, options dockercontainer.ExecOptions) (dockercontainer.ExecCreateResponse, error) {
+	if err := d.StartRelay(ctx, c); err != nil {
+		return dockercontainer.ExecCreateResponse{}, err
+	}
+
+	d.relayMu.Lock()
+	relayID := d.relayID
+	d.relayMu.Unlock()
+
+	exec, err := d.CLI.ContainerExecCreate(ctx, relayID, options)
+	if err != nil {
+		return exec, err
+	}
+
+	// remember our own execs so stopRelay can tell them apart from other clients'
+	d.relayMu.Lock()
+	if d.relayExecs == nil {
+		d.relayExecs = map[string]struct{}{}
+	}
+	d.relayExecs[exec.ID] = struct{}{}
+	d.relayMu.Unlock()
+
+	return exec, nil
+}
+
+// relayConn is a net.Conn whose reads come from the demultiplexed exec stdout.
+type relayConn struct {
+	net.Conn
+	reader *io.PipeReader
+}
+
+func (c *relayConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *relayConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func (c *relayConn) Close() error {
+	c.reader.Close()
+	return c.Conn.Close()
+}
+
+// slogWriter logs the relay's stderr so connection errors from socat are visible.
+type slogWriter struct {
+	addr string
+}
+
+func (w *slogWriter) Write(p []byte) (int, error) {
+	slog.Debug("relay", "addr", w.addr, "stderr", string(p))
+	return len(p), nil
+}
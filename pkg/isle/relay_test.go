@@ -0,0 +1,144 @@
+package isle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/islandora-devops/islectl/pkg/config"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeRelayClient simulates the docker API for the relay container.
+// Exec sessions echo back the first line written to them over a multiplexed stream.
+type fakeRelayClient struct {
+	FakeDockerClient
+	created []string
+	removed []string
+	execCmd []string
+	// execIDs are the execs running in the relay, including other processes'
+	execIDs []string
+}
+
+func (f *fakeRelayClient) ContainerInspect(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+	if len(f.created) == 0 || len(f.removed) > 0 {
+		return dockercontainer.InspectResponse{}, cerrdefs.ErrNotFound
+	}
+	return dockercontainer.InspectResponse{
+		ContainerJSONBase: &dockercontainer.ContainerJSONBase{ID: "relay-id", ExecIDs: f.execIDs, State: &dockercontainer.State{Running: true}},
+	}, nil
+}
+
+func (f *fakeRelayClient) ContainerCreate(ctx context.Context, config *dockercontainer.Config, hostConfig *dockercontainer.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (dockercontainer.CreateResponse, error) {
+	if _, ok := networkingConfig.EndpointsConfig["test_default"]; !ok {
+		return dockercontainer.CreateResponse{}, fmt.Errorf("relay not attached to the project network")
+	}
+	f.created = append(f.created, containerName)
+	return dockercontainer.CreateResponse{ID: "relay-id"}, nil
+}
+
+func (f *fakeRelayClient) ContainerStart(ctx context.Context, container string, options dockercontainer.StartOptions) error {
+	return nil
+}
+
+func (f *fakeRelayClient) ContainerRemove(ctx context.Context, container string, options dockercontainer.RemoveOptions) error {
+	f.removed = append(f.removed, container)
+	return nil
+}
+
+func (f *fakeRelayClient) ContainerExecCreate(ctx context.Context, container string, options dockercontainer.ExecOptions) (dockercontainer.ExecCreateResponse, error) {
+	f.execCmd = options.Cmd
+	id := fmt.Sprintf("exec-%d", len(f.execIDs))
+	f.execIDs = append(f.execIDs, id)
+	return dockercontainer.ExecCreateResponse{ID: id}, nil
+}
+
+func (f *fakeRelayClient) ContainerExecAttach(ctx context.Context, execID string, config dockercontainer.ExecAttachOptions) (types.HijackedResponse, error) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		line, _ := bufio.NewReader(server).ReadString('\n')
+		fmt.Fprint(stdcopy.NewStdWriter(server, stdcopy.Stderr), "socat connected\n")
+		fmt.Fprint(stdcopy.NewStdWriter(server, stdcopy.Stdout), line)
+	}()
+	return types.HijackedResponse{Conn: client, Reader: bufio.NewReader(client)}, nil
+}
+
+func TestDialRelay(t *testing.T) {
+	fake := &fakeRelayClient{}
+	d := &DockerClient{CLI: fake}
+	c := &config.Context{ProjectName: "test"}
+
+	conn, err := d.DialRelay(context.Background(), c, "10.0.0.5:8983")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fmt.Fprint(conn, "ping\n")
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("unable to read from relay: %v", err)
+	}
+	conn.Close()
+
+	if string(reply) != "ping\n" {
+		t.Errorf("expected stdout only %q, got %q", "ping\n", reply)
+	}
+	if fmt.Sprint(fake.execCmd) != "[socat - TCP:10.0.0.5:8983]" {
+		t.Errorf("unexpected relay command %v", fake.execCmd)
+	}
+
+	// the relay container is reused across connections
+	if _, err := d.DialRelay(context.Background(), c, "10.0.0.5:8983"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.created) != 1 || fake.created[0] != "test-islectl-relay" {
+		t.Errorf("expected a single relay container, got %v", fake.created)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected error closing client: %v", err)
+	}
+	if len(fake.removed) != 1 || fake.removed[0] != "relay-id" {
+		t.Errorf("expected relay container to be removed on close, got %v", fake.removed)
+	}
+}
+
+func TestStopRelayShared(t *testing.T) {
+	fake := &fakeRelayClient{}
+	d := &DockerClient{CLI: fake}
+	c := &config.Context{ProjectName: "test"}
+
+	if _, err := d.DialRelay(context.Background(), c, "10.0.0.5:8983"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// another islectl process is relaying through the same container
+	fake.execIDs = append(fake.execIDs, "other-exec")
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("unexpected error closing client: %v", err)
+	}
+	if len(fake.removed) != 0 {
+		t.Errorf("expected the relay to be left for the other process, got removed %v", fake.removed)
+	}
+
+	// a client that only shares the relay never removes it
+	shared := &DockerClient{CLI: fake}
+	if err := shared.StartRelay(context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake.execIDs = nil
+	if err := shared.Close(); err != nil {
+		t.Fatalf("unexpected error closing client: %v", err)
+	}
+	if len(fake.removed) != 0 {
+		t.Errorf("expected a shared relay not to be removed, got %v", fake.removed)
+	}
+}
@@ -0,0 +1,190 @@
+package isle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// ReverseSpec describes a port on the docker network forwarded back to the workstation.
+type ReverseSpec struct {
+	RemotePort int
+	LocalHost  string
+	LocalPort  int
+}
+
+func (s ReverseSpec) String() string {
+	return fmt.Sprintf("%d:%s:%d", s.RemotePort, s.LocalHost, s.LocalPort)
+}
+
+// ParseReverseSpec parses a reverse port-forward argument. Supported formats are
+//
+//	PORT                              same port on both ends e.g. 9003 for xdebug
+//	REMOTE-PORT:LOCAL-PORT
+//	REMOTE-PORT:LOCAL-HOST:LOCAL-PORT
+func ParseReverseSpec(arg string) (ReverseSpec, error) {
+	spec := ReverseSpec{LocalHost: "localhost"}
+	parts := strings.Split(arg, ":")
+	var err error
+	switch len(parts) {
+	case 1:
+		spec.RemotePort, err = parsePort(parts[0])
+		spec.LocalPort = spec.RemotePort
+	case 2:
+		spec.RemotePort, err = parsePort(parts[0])
+		if err == nil {
+			spec.LocalPort, err = parsePort(parts[1])
+		}
+	case 3:
+		spec.RemotePort, err = parsePort(parts[0])
+		if err == nil {
+			spec.LocalPort, err = parsePort(parts[2])
+		}
+		if parts[1] != "" {
+			spec.LocalHost = parts[1]
+		}
+	default:
+		return ReverseSpec{}, fmt.Errorf("invalid reverse port forwarding spec '%s': expected format REMOTE-PORT[:LOCAL-HOST]:LOCAL-PORT", arg)
+	}
+	if err != nil {
+		return ReverseSpec{}, fmt.Errorf("invalid reverse port forwarding spec '%s': port %v", arg, err)
+	}
+
+	return spec, nil
+}
+
+// NetworkGateway returns the gateway IP of the project network.
+// Containers on the network reach the docker host at this address.
+func (d *DockerClient) NetworkGateway(ctx context.Context, c *config.Context) (string, error) {
+	networkName := fmt.Sprintf("%s_default", c.ProjectName)
+	resource, err := d.CLI.NetworkInspect(ctx, networkName, network.InspectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error inspecting network %q: %v", networkName, err)
+	}
+	for _, cfg := range resource.IPAM.Config {
+		if cfg.Gateway != "" && net.ParseIP(cfg.Gateway).To4() != nil {
+			return cfg.Gateway, nil
+		}
+	}
+
+	return "", fmt.Errorf("network %q does not have an IPv4 gateway", networkName)
+}
+
+// ListenRemote listens on addr on the docker host.
+// Remote contexts ask the SSH server to listen (tcpip-forward), which requires
+// GatewayPorts clientspecified in the server's sshd_config to bind to a non-loopback address.
+func (t *Tunnel) ListenRemote(addr string) (net.Listener, error) {
+	cli := t.Client()
+	if cli.SshCli != nil {
+		return cli.SshCli.Listen("tcp", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// ReverseForward accepts connections on the docker host and proxies them to the workstation.
+type ReverseForward struct {
+	Spec       ReverseSpec
+	RemoteAddr string
+	Stats      ForwardStats
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   chan struct{}
+}
+
+// NewReverseForward listens on bindIP:RemotePort on the docker host.
+func NewReverseForward(t *Tunnel, bindIP string, spec ReverseSpec) (*ReverseForward, error) {
+	addr := net.JoinHostPort(bindIP, strconv.Itoa(spec.RemotePort))
+	listener, err := t.ListenRemote(addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on %s: %v", addr, err)
+	}
+
+	return &ReverseForward{
+		Spec:       spec,
+		RemoteAddr: addr,
+		listener:   listener,
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+// Serve accepts connections until Close is called.
+// If the SSH connection drops the tunnel is reconnected and the remote listener re-created.
+func (r *ReverseForward) Serve(ctx context.Context, t *Tunnel) {
+	localAddr := net.JoinHostPort(r.Spec.LocalHost, strconv.Itoa(r.Spec.LocalPort))
+	for {
+		r.mu.Lock()
+		listener := r.listener
+		r.mu.Unlock()
+		remoteConn, err := listener.Accept()
+		if err == nil {
+			go r.handle(remoteConn, localAddr)
+			continue
+		}
+
+		select {
+		case <-r.closed:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		slog.Warn("Reverse forward listener stopped, re-creating it", "addr", r.RemoteAddr, "err", err)
+		if err := t.ensureAlive(ctx); err != nil {
+			slog.Error("Unable to reconnect", "err", err)
+			return
+		}
+		listener, err = t.ListenRemote(r.RemoteAddr)
+		if err != nil {
+			slog.Error("Unable to re-create reverse forward", "addr", r.RemoteAddr, "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(t.reconnectDelay):
+			}
+			continue
+		}
+		r.mu.Lock()
+		r.listener = listener
+		r.mu.Unlock()
+	}
+}
+
+func (r *ReverseForward) handle(remoteConn net.Conn, localAddr string) {
+	defer remoteConn.Close()
+	r.Stats.Connections.Add(1)
+
+	localConn, err := net.DialTimeout("tcp", localAddr, 5*time.Second)
+	if err != nil {
+		r.Stats.Failures.Add(1)
+		slog.Error("Failed to reach local address", "addr", localAddr, "err", err)
+		return
+	}
+	defer localConn.Close()
+
+	r.Stats.Active.Add(1)
+	defer r.Stats.Active.Add(-1)
+	Pipe(remoteConn, localConn, &r.Stats.BytesIn, &r.Stats.BytesOut)
+}
+
+// Close stops the remote listener.
+func (r *ReverseForward) Close() error {
+	close(r.closed)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.listener.Close()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
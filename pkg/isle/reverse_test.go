@@ -0,0 +1,108 @@
+package isle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/docker/docker/api/types/network"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestParseReverseSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    ReverseSpec
+		wantErr bool
+	}{
+		{"single port", "9003", ReverseSpec{RemotePort: 9003, LocalHost: "localhost", LocalPort: 9003}, false},
+		{"remote and local port", "9000:9003", ReverseSpec{RemotePort: 9000, LocalHost: "localhost", LocalPort: 9003}, false},
+		{"local host", "9000:127.0.0.1:9003", ReverseSpec{RemotePort: 9000, LocalHost: "127.0.0.1", LocalPort: 9003}, false},
+		{"bad port", "xdebug", ReverseSpec{}, true},
+		{"bad local port", "9000:abc", ReverseSpec{}, true},
+		{"too many parts", "1:2:3:4", ReverseSpec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReverseSpec(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkGateway(t *testing.T) {
+	fake := &FakeDockerClient{
+		NetworkFunc: func(ctx context.Context, networkID string) (network.Inspect, error) {
+			if networkID != "test_default" {
+				return network.Inspect{}, fmt.Errorf("network %s not found", networkID)
+			}
+			return network.Inspect{
+				IPAM: network.IPAM{
+					Config: []network.IPAMConfig{
+						{Subnet: "fd00::/64", Gateway: "fd00::1"},
+						{Subnet: "172.18.0.0/16", Gateway: "172.18.0.1"},
+					},
+				},
+			}, nil
+		},
+	}
+	d := &DockerClient{CLI: fake}
+	gateway, err := d.NetworkGateway(context.Background(), &config.Context{ProjectName: "test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gateway != "172.18.0.1" {
+		t.Errorf("expected %q, got %q", "172.18.0.1", gateway)
+	}
+}
+
+func TestReverseForward(t *testing.T) {
+	echo := startEcho(t)
+	_, echoPort, err := net.SplitHostPort(echo.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	localPort, err := strconv.Atoi(echoPort)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tunnel := newTestTunnel(&DockerClient{}, nil)
+	spec := ReverseSpec{LocalHost: "localhost", LocalPort: localPort}
+	fwd, err := NewReverseForward(tunnel, "127.0.0.1", spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go fwd.Serve(ctx, tunnel)
+	defer fwd.Close()
+
+	// a container connecting to the docker host port reaches the local echo server
+	conn, err := net.Dial("tcp", fwd.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to connect to reverse forward: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "xdebug\n")
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unable to read reply: %v", err)
+	}
+	if line != "xdebug\n" {
+		t.Errorf("expected %q, got %q", "xdebug\n", line)
+	}
+	if got := fwd.Stats.Connections.Load(); got != 1 {
+		t.Errorf("expected 1 connection, got %d", got)
+	}
+}
@@ -0,0 +1,287 @@
+package isle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/kballard/go-shellquote"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// SnapshotDir is where deploy snapshots are kept, relative to the project directory.
+const SnapshotDir = ".islectl/snapshots"
+
+// SnapshotStep is the deploy step that records a snapshot to roll back to.
+const SnapshotStep = "snapshot"
+
+// snapshotIDFormat sorts snapshots oldest first.
+const snapshotIDFormat = "20060102-150405"
+
+// ServiceImage is the image a compose service was running when a snapshot was taken.
+type ServiceImage struct {
+	// Image is the reference from the compose file, e.g. islandora/drupal:4
+	Image string `yaml:"image"`
+	// ID is the content addressable image ID the reference resolved to
+	ID string `yaml:"id"`
+}
+
+// Snapshot records the images and database of a context before a deploy.
+type Snapshot struct {
+	ID          string                  `yaml:"id"`
+	Context     string                  `yaml:"context"`
+	Created     time.Time               `yaml:"created"`
+	Site        string                  `yaml:"site"`
+	ComposeFile []string                `yaml:"compose-files"`
+	Services    map[string]ServiceImage `yaml:"services"`
+}
+
+// NewSnapshotID returns the ID of a snapshot taken at t.
+func NewSnapshotID(t time.Time) string {
+	return t.UTC().Format(snapshotIDFormat)
+}
+
+// Manifest is the path of the snapshot's manifest.
+func (s Snapshot) Manifest(c *config.Context) string {
+	return path.Join(c.ProjectDir, SnapshotDir, s.ID+".yml")
+}
+
+// DatabaseDump is the path of the snapshot's gzipped database dump.
+func (s Snapshot) DatabaseDump(c *config.Context) string {
+	return path.Join(c.ProjectDir, SnapshotDir, s.ID+".sql.gz")
+}
+
+// OverrideFile is the path of the compose file pinning the services to the snapshot's images.
+func (s Snapshot) OverrideFile(c *config.Context) string {
+	return path.Join(c.ProjectDir, SnapshotDir, s.ID+".compose.yml")
+}
+
+// ComposeOverride is a compose file pinning every service to the image ID it ran when the snapshot was taken.
+func (s Snapshot) ComposeOverride() (string, error) {
+	type service struct {
+		Image      string `yaml:"image"`
+		PullPolicy string `yaml:"pull_policy"`
+	}
+	services := map[string]service{}
+	for name, image := range s.Services {
+		services[name] = service{Image: image.ID, PullPolicy: "never"}
+	}
+	out, err := yaml.Marshal(map[string]any{"services": services})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ParseSnapshot parses a snapshot manifest.
+func ParseSnapshot(data string) (Snapshot, error) {
+	var s Snapshot
+	if err := yaml.Unmarshal([]byte(data), &s); err != nil {
+		return Snapshot{}, fmt.Errorf("unable to parse snapshot: %v", err)
+	}
+	if s.ID == "" || len(s.Services) == 0 {
+		return Snapshot{}, fmt.Errorf("snapshot is missing its id or services")
+	}
+	return s, nil
+}
+
+// ListSnapshots returns the snapshots of a context, newest first.
+func ListSnapshots(c *config.Context) ([]Snapshot, error) {
+	files, err := c.ListDir(path.Join(c.ProjectDir, SnapshotDir))
+	if errors.Is(err, os.ErrNotExist) {
+		// no deploy has been snapshotted yet
+		return []Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list snapshots: %v", err)
+	}
+
+	ids := []string{}
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file, ".yml")
+		if ok && !strings.HasSuffix(id, ".compose") {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	slices.Reverse(ids)
+
+	snapshots := []Snapshot{}
+	for _, id := range ids {
+		s, err := ParseSnapshot(c.ReadSmallFile(Snapshot{ID: id}.Manifest(c)))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %v", id, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+// FindSnapshot returns the snapshot with id, or the newest snapshot when id is empty.
+func FindSnapshot(c *config.Context, id string) (Snapshot, error) {
+	snapshots, err := ListSnapshots(c)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return Snapshot{}, fmt.Errorf("no snapshots found for context %q. Snapshots are taken by the %s deploy step", c.Name, SnapshotStep)
+	}
+	if id == "" {
+		return snapshots[0], nil
+	}
+	for _, s := range snapshots {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return Snapshot{}, fmt.Errorf("snapshot %q not found for context %q", id, c.Name)
+}
+
+// ServiceImages returns the images of the running compose services and the compose files they were started from.
+func (d *DockerClient) ServiceImages(ctx context.Context, c *config.Context) (map[string]ServiceImage, []string, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "com.docker.compose.project="+c.ProjectName)
+	containers, err := d.CLI.ContainerList(ctx, dockercontainer.ListOptions{Filters: filterArgs})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing containers: %v", err)
+	}
+
+	services := map[string]ServiceImage{}
+	composeFiles := []string{}
+	for _, container := range containers {
+		service := container.Labels["com.docker.compose.service"]
+		if service == "" {
+			continue
+		}
+		inspect, err := d.CLI.ContainerInspect(ctx, container.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error inspecting container %s: %v", container.ID, err)
+		}
+		image := ServiceImage{ID: inspect.Image}
+		if inspect.Config != nil {
+			image.Image = inspect.Config.Image
+		}
+		services[service] = image
+
+		if files := container.Labels["com.docker.compose.project.config_files"]; files != "" && len(composeFiles) == 0 {
+			composeFiles = strings.Split(files, ",")
+		}
+	}
+	if len(services) == 0 {
+		return nil, nil, fmt.Errorf("no running containers found for project %q", c.ProjectName)
+	}
+
+	return services, composeFiles, nil
+}
+
+// SnapshotDumpCommand is the host command writing a gzipped dump of the site's database to the snapshot.
+func SnapshotDumpCommand(c *config.Context, s Snapshot, site Site) []string {
+	dir := path.Join(c.ProjectDir, SnapshotDir)
+	return []string{"sh", "-c", hostDumpScript(c, site, dir, shellquote.Join(s.DatabaseDump(c)))}
+}
+
+// hostDumpScript streams a gzipped dump of the site's database to dest on the docker host, so it outlives
+// the drupal container. dest is a shell word in dir, which is created only readable by its owner and
+// ignored by git, as the dumps hold production data.
+func hostDumpScript(c *config.Context, site Site, dir, dest string) string {
+	dump := DrushCommand(c, site, sqlDumpArgs()...)
+	inDrupal := DrupalExecArgs(c, "set -o pipefail; "+dump+" | gzip")
+	gitignore := path.Join(dir, ".gitignore")
+	return fmt.Sprintf("umask 077 && mkdir -p %s && chmod 700 %s && { [ -e %s ] || echo '*' > %s; } && docker %s > %s",
+		shellquote.Join(dir),
+		shellquote.Join(dir),
+		shellquote.Join(gitignore),
+		shellquote.Join(gitignore),
+		shellquote.Join(inDrupal...),
+		dest)
+}
+
+// RollbackCommands are the host commands re-pinning the services to the snapshot's images
+// and restoring its database.
+func RollbackCommands(c *config.Context, s Snapshot, site Site) [][]string {
+	compose := append([]string{"docker"}, ComposeArgs(c)...)
+	for _, file := range s.ComposeFile {
+		compose = append(compose, "-f", file)
+	}
+	compose = append(compose, "-f", s.OverrideFile(c))
+
+	inDrupal := func(script string) []string {
+		return append(slices.Clone(compose), "exec", "-T", fmt.Sprintf("drupal-%s", c.Profile), "bash", "-c", script)
+	}
+	restore := fmt.Sprintf("gunzip -c %s | %s",
+		shellquote.Join(s.DatabaseDump(c)),
+		shellquote.Join(inDrupal(DrushCommand(c, site, "sql-cli"))...))
+
+	return [][]string{
+		// check the dump before anything changes, so a corrupt or missing dump can't leave the database empty
+		{"gzip", "-t", s.DatabaseDump(c)},
+		append(slices.Clone(compose), "up", "-d"),
+		inDrupal(DrushCommand(c, site, "sql-drop", "-y")),
+		{"sh", "-c", restore},
+		inDrupal(DrushCommand(c, site, "cache:rebuild")),
+	}
+}
+
+// TakeSnapshot records the images of the running services and dumps the site's database.
+func (d *DockerClient) TakeSnapshot(ctx context.Context, c *config.Context, site Site) (Snapshot, error) {
+	services, composeFiles, err := d.ServiceImages(ctx, c)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	now := time.Now()
+	s := Snapshot{
+		ID:          NewSnapshotID(now),
+		Context:     c.Name,
+		Created:     now.UTC().Truncate(time.Second),
+		Site:        site.Name,
+		ComposeFile: composeFiles,
+		Services:    services,
+	}
+
+	dump := SnapshotDumpCommand(c, s, site)
+	cmd := exec.Command(dump[0], dump[1:]...)
+	cmd.Dir = c.ProjectDir
+	if _, err := c.RunCommand(cmd); err != nil {
+		return Snapshot{}, fmt.Errorf("unable to dump the %s database: %v", site.Name, err)
+	}
+
+	override, err := s.ComposeOverride()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := c.WriteSmallFile(s.OverrideFile(c), override); err != nil {
+		return Snapshot{}, fmt.Errorf("unable to write %s: %v", s.OverrideFile(c), err)
+	}
+	manifest, err := yaml.Marshal(s)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := c.WriteSmallFile(s.Manifest(c), string(manifest)); err != nil {
+		return Snapshot{}, fmt.Errorf("unable to write %s: %v", s.Manifest(c), err)
+	}
+
+	return s, nil
+}
+
+// PruneSnapshotsCommand is the host command removing all but the newest keep snapshots,
+// or nil when there is nothing to remove.
+func PruneSnapshotsCommand(c *config.Context, snapshots []Snapshot, keep int) []string {
+	if keep < 1 || len(snapshots) <= keep {
+		return nil
+	}
+	files := []string{}
+	for _, s := range snapshots[keep:] {
+		files = append(files, s.Manifest(c), s.DatabaseDump(c), s.OverrideFile(c))
+	}
+	return append([]string{"rm", "-f"}, files...)
+}
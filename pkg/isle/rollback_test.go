@@ -0,0 +1,184 @@
+package isle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestServiceImages(t *testing.T) {
+	composeLabels := func(service string) map[string]string {
+		return map[string]string{
+			"com.docker.compose.service":              service,
+			"com.docker.compose.project.config_files": "/srv/isle/docker-compose.yml,/srv/isle/docker-compose.override.yml",
+		}
+	}
+	fakeCli := &FakeDockerClient{
+		ListFunc: func(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error) {
+			return []dockercontainer.Summary{
+				{ID: "c1", Labels: composeLabels("drupal-prod")},
+				{ID: "c2", Labels: composeLabels("mariadb")},
+				{ID: "c3", Labels: map[string]string{}},
+			}, nil
+		},
+		InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+			images := map[string]string{"c1": "islandora/drupal:4", "c2": "islandora/mariadb:4"}
+			return dockercontainer.InspectResponse{
+				ContainerJSONBase: &dockercontainer.ContainerJSONBase{Image: "sha256:" + container},
+				Config:            &dockercontainer.Config{Image: images[container]},
+			}, nil
+		},
+	}
+	d := &DockerClient{CLI: fakeCli}
+
+	services, files, err := d.ServiceImages(context.Background(), &config.Context{ProjectName: "isle"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]ServiceImage{
+		"drupal-prod": {Image: "islandora/drupal:4", ID: "sha256:c1"},
+		"mariadb":     {Image: "islandora/mariadb:4", ID: "sha256:c2"},
+	}
+	if !reflect.DeepEqual(services, want) {
+		t.Errorf("services = %v, want %v", services, want)
+	}
+	if !reflect.DeepEqual(files, []string{"/srv/isle/docker-compose.yml", "/srv/isle/docker-compose.override.yml"}) {
+		t.Errorf("unexpected compose files %v", files)
+	}
+}
+
+func TestComposeOverride(t *testing.T) {
+	s := Snapshot{Services: map[string]ServiceImage{
+		"drupal-prod": {Image: "islandora/drupal:4", ID: "sha256:abc"},
+	}}
+	out, err := s.ComposeOverride()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got map[string]map[string]map[string]string
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("override is not valid YAML: %v", err)
+	}
+	want := map[string]string{"image": "sha256:abc", "pull_policy": "never"}
+	if !reflect.DeepEqual(got["services"]["drupal-prod"], want) {
+		t.Errorf("override = %v, want %v", got, want)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	projectDir := t.TempDir()
+	c := &config.Context{Name: "prod", DockerHostType: config.ContextLocal, ProjectDir: projectDir}
+
+	snapshots, err := ListSnapshots(c)
+	if err != nil || len(snapshots) != 0 {
+		t.Fatalf("expected no snapshots, got %v, %v", snapshots, err)
+	}
+	if _, err := FindSnapshot(c, ""); err == nil {
+		t.Error("expected an error without snapshots")
+	}
+
+	dir := filepath.Join(projectDir, SnapshotDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"20250101-120000", "20250301-120000", "20250201-120000"} {
+		s := Snapshot{ID: id, Context: "prod", Created: time.Now(), Site: DefaultSite, Services: map[string]ServiceImage{"drupal-prod": {ID: "sha256:" + id}}}
+		data, err := yaml.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(s.Manifest(c), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(s.OverrideFile(c), []byte("services: {}\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err = ListSnapshots(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"20250301-120000", "20250201-120000", "20250101-120000"}) {
+		t.Errorf("expected newest first, got %v", ids)
+	}
+
+	latest, err := FindSnapshot(c, "")
+	if err != nil || latest.ID != "20250301-120000" {
+		t.Errorf("expected the latest snapshot, got %v, %v", latest.ID, err)
+	}
+	if _, err := FindSnapshot(c, "20240101-000000"); err == nil {
+		t.Error("expected an error for a missing snapshot")
+	}
+
+	// only a missing directory means there are no snapshots
+	broken := &config.Context{Name: "prod", DockerHostType: config.ContextLocal, ProjectDir: t.TempDir()}
+	if err := os.MkdirAll(filepath.Join(broken.ProjectDir, ".islectl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken.ProjectDir, SnapshotDir), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListSnapshots(broken); err == nil {
+		t.Error("expected an error when the snapshot directory can't be read")
+	}
+
+	prune := PruneSnapshotsCommand(c, snapshots, 2)
+	if len(prune) != 5 || prune[2] != snapshots[2].Manifest(c) {
+		t.Errorf("expected the oldest snapshot to be pruned, got %v", prune)
+	}
+	if PruneSnapshotsCommand(c, snapshots, 3) != nil {
+		t.Error("expected nothing to prune")
+	}
+}
+
+func TestRollbackCommands(t *testing.T) {
+	c := &config.Context{ProjectName: "isle", ProjectDir: "/srv/isle", Profile: "prod"}
+	s := Snapshot{ID: "20250301-120000", ComposeFile: []string{"/srv/isle/docker-compose.yml"}}
+
+	commands := RollbackCommands(c, s, Site{Name: DefaultSite})
+	up := []string{"docker", "compose", "--profile", "prod",
+		"-f", "/srv/isle/docker-compose.yml", "-f", "/srv/isle/.islectl/snapshots/20250301-120000.compose.yml", "up", "-d"}
+	check := []string{"gzip", "-t", "/srv/isle/.islectl/snapshots/20250301-120000.sql.gz"}
+	if !reflect.DeepEqual(commands[0], check) {
+		t.Errorf("the dump must be checked first, got %q", commands[0])
+	}
+	if !reflect.DeepEqual(commands[1], up) {
+		t.Errorf("up = %q, want %q", commands[1], up)
+	}
+	if drop := commands[2][len(commands[2])-1]; !strings.Contains(drop, "sql-drop") {
+		t.Errorf("expected the database to be dropped after the check, got %q", commands[2])
+	}
+	restore := commands[3][2]
+	if !strings.HasPrefix(restore, "gunzip -c /srv/isle/.islectl/snapshots/20250301-120000.sql.gz | ") || !strings.Contains(restore, "sql-cli") {
+		t.Errorf("unexpected restore command %q", restore)
+	}
+}
+
+func TestSnapshotDumpCommand(t *testing.T) {
+	c := &config.Context{ProjectName: "isle", ProjectDir: "/srv/isle", Profile: "prod"}
+	script := SnapshotDumpCommand(c, Snapshot{ID: "20250301-120000"}, Site{Name: DefaultSite})[2]
+	for _, want := range []string{
+		"umask 077 && ",
+		"chmod 700 /srv/isle/.islectl/snapshots",
+		"echo '*' > /srv/isle/.islectl/snapshots/.gitignore",
+		"> /srv/isle/.islectl/snapshots/20250301-120000.sql.gz",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected %q in %q", want, script)
+		}
+	}
+}
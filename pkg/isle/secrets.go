@@ -0,0 +1,264 @@
+package isle
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+const (
+	jwtPrivateKey = "JWT_PRIVATE_KEY"
+	jwtPublicKey  = "JWT_PUBLIC_KEY"
+
+	passwordChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	passwordLength = 32
+	saltBytes      = 55
+)
+
+var secretNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// SecretsDir is where isle-site-template keeps the docker compose secret files.
+// Remote project directories are always on a unix host, so use forward slashes.
+func SecretsDir(c *config.Context) string {
+	return path.Join(c.ProjectDir, "secrets")
+}
+
+// ValidateSecretName makes sure a secret name can't escape the secrets directory.
+func ValidateSecretName(name string) error {
+	if !secretNameRe.MatchString(name) || name == ".." {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	return nil
+}
+
+// SecretNames lists the secret files in the context's secrets directory.
+func SecretNames(c *config.Context) ([]string, error) {
+	files, err := c.ListDir(SecretsDir(c))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list secrets in %s: %v", SecretsDir(c), err)
+	}
+
+	names := []string{}
+	for _, name := range files {
+		if !strings.HasPrefix(name, ".") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// ReadSecret returns the value of a secret from the context's secret backends, or its secret file,
+// the same way commands using the secret look it up.
+func ReadSecret(c *config.Context, name string) (string, error) {
+	if value, ok := backendSecret(c, name); ok {
+		return value, nil
+	}
+
+	names, err := SecretNames(c)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(names, name) {
+		return "", fmt.Errorf("secret %q not found in %s", name, SecretsDir(c))
+	}
+
+	return c.ReadSmallFile(path.Join(SecretsDir(c), name)), nil
+}
+
+// ReadSecrets returns the values of every secret in the secrets directory, keyed by name.
+// Like ReadSecret, the context's secret backends take precedence over the files.
+func ReadSecrets(c *config.Context) (map[string]string, error) {
+	names, err := SecretNames(c)
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]string{}
+	for _, name := range names {
+		value, ok := backendSecret(c, name)
+		if !ok {
+			value = c.ReadSmallFile(path.Join(SecretsDir(c), name))
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// DiffSecrets compares the secrets of two contexts without revealing their values.
+// Added are only in source, Deleted only in target and Changed have different values.
+func DiffSecrets(source, target map[string]string) ConfigDiff {
+	set := func(secrets map[string]string) ConfigSet {
+		cs := ConfigSet{}
+		for name, value := range secrets {
+			// editors add a trailing newline, which the services ignore
+			cs[name] = []byte(strings.TrimRight(value, "\r\n"))
+		}
+		return cs
+	}
+	return DiffConfig(set(source), set(target))
+}
+
+// backendSecret looks a secret up in the context's secret backends. Failing backends are logged
+// rather than returned, so callers fall back to the project's secrets.
+func backendSecret(c *config.Context, name string) (string, bool) {
+	value, ok, err := c.LookupSecret(name)
+	if !ok && err != nil {
+		slog.Warn("Unable to read secret from the context's backends, using the project's", "secret", name, "err", err)
+	}
+	return value, ok
+}
+
+// CheckSecretsWritable makes sure none of the secrets are read from a backend WriteSecret can't update,
+// so a secret isn't half changed.
+func CheckSecretsWritable(c *config.Context, names ...string) error {
+	for _, name := range names {
+		if err := c.CheckSecretWritable(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSecret replaces the value of a secret file. When the context reads the secret from
+// its vault, the vault is updated too so the context keeps using the same value as the services.
+func WriteSecret(c *config.Context, name, value string) error {
+	if err := ValidateSecretName(name); err != nil {
+		return err
+	}
+	if err := c.CheckSecretWritable(name); err != nil {
+		return err
+	}
+	filename := path.Join(SecretsDir(c), name)
+	if err := c.WriteSmallFile(filename, value); err != nil {
+		return fmt.Errorf("unable to write secret %s: %v", filename, err)
+	}
+	backend, err := c.UpdateSecret(name, value)
+	if err != nil {
+		return err
+	}
+	if backend != "" {
+		slog.Info("Updated the secret in the context's backend too", "secret", name, "backend", backend)
+	}
+	return nil
+}
+
+// GenerateSecret returns new values for a secret, keyed by secret name.
+// Rotating either JWT key regenerates the pair so they keep matching.
+// Secrets ending in _SALT get a Drupal hash salt, everything else a random password.
+func GenerateSecret(name string) (map[string]string, error) {
+	switch {
+	case name == jwtPrivateKey || name == jwtPublicKey:
+		private, public, err := generateKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			jwtPrivateKey: private,
+			jwtPublicKey:  public,
+		}, nil
+	case strings.HasSuffix(name, "_SALT"):
+		salt := make([]byte, saltBytes)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		return map[string]string{name: base64.RawURLEncoding.EncodeToString(salt)}, nil
+	}
+
+	password, err := generatePassword(passwordLength)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{name: password}, nil
+}
+
+func generatePassword(length int) (string, error) {
+	password := make([]byte, length)
+	for i := range password {
+		b := make([]byte, 1)
+		// rejection sampling keeps every character equally likely
+		for {
+			if _, err := rand.Read(b); err != nil {
+				return "", err
+			}
+			if int(b[0]) < 256-256%len(passwordChars) {
+				break
+			}
+		}
+		password[i] = passwordChars[int(b[0])%len(passwordChars)]
+	}
+
+	return string(password), nil
+}
+
+func generateKeyPair() (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to generate RSA key: %v", err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})), nil
+}
+
+// StoredInDatabase reports whether a secret is a database password, which the database keeps its own copy of.
+// Changing only the file locks the services using it out of the database.
+func StoredInDatabase(name string) bool {
+	return name == "DB_ROOT_PASSWORD" || strings.HasSuffix(name, "_DB_PASSWORD")
+}
+
+// RotationWarning explains any manual step needed when a secret's value is also stored elsewhere.
+func RotationWarning(name string) string {
+	switch {
+	case StoredInDatabase(name):
+		return fmt.Sprintf("%s is also stored in the database. Change the database user's password to the new value before restarting, or the services using it will not be able to connect", name)
+	case name == "DRUPAL_DEFAULT_ACCOUNT_PASSWORD":
+		return fmt.Sprintf("%s is only used when installing Drupal. Use drush user:password to change the password of an existing admin account", name)
+	}
+	return ""
+}
+
+// ServicesUsingSecret returns the compose services of running containers that mount a secret.
+func (d *DockerClient) ServicesUsingSecret(ctx context.Context, c *config.Context, name string) ([]string, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", "com.docker.compose.project="+c.ProjectName)
+	containers, err := d.CLI.ContainerList(ctx, dockercontainer.ListOptions{Filters: filterArgs})
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %v", err)
+	}
+
+	target := path.Join("/run/secrets", name)
+	services := []string{}
+	for _, container := range containers {
+		service := container.Labels["com.docker.compose.service"]
+		for _, mount := range container.Mounts {
+			if mount.Destination == target && !slices.Contains(services, service) {
+				services = append(services, service)
+			}
+		}
+	}
+	slices.Sort(services)
+
+	return services, nil
+}
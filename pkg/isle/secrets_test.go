@@ -0,0 +1,253 @@
+package isle
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+func TestValidateSecretName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "DB_ROOT_PASSWORD"},
+		{name: "my-secret.txt"},
+		{name: "", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: ".hidden", wantErr: true},
+		{name: "../etc/passwd", wantErr: true},
+		{name: "nested/SECRET", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSecretName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSecretName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	values, err := GenerateSecret("DRUPAL_DEFAULT_DB_PASSWORD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	password := values["DRUPAL_DEFAULT_DB_PASSWORD"]
+	if len(values) != 1 || len(password) != passwordLength {
+		t.Fatalf("expected one %d character password, got %v", passwordLength, values)
+	}
+	if strings.Trim(password, passwordChars) != "" {
+		t.Errorf("password %q has characters outside of %q", password, passwordChars)
+	}
+	again, _ := GenerateSecret("DRUPAL_DEFAULT_DB_PASSWORD")
+	if again["DRUPAL_DEFAULT_DB_PASSWORD"] == password {
+		t.Errorf("expected a new password each time")
+	}
+
+	values, err = GenerateSecret("DRUPAL_DEFAULT_SALT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values["DRUPAL_DEFAULT_SALT"]) != 74 {
+		t.Errorf("expected a 74 character salt, got %q", values["DRUPAL_DEFAULT_SALT"])
+	}
+
+	values, err = GenerateSecret("JWT_PUBLIC_KEY")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	privateBlock, _ := pem.Decode([]byte(values["JWT_PRIVATE_KEY"]))
+	publicBlock, _ := pem.Decode([]byte(values["JWT_PUBLIC_KEY"]))
+	if privateBlock == nil || publicBlock == nil {
+		t.Fatalf("expected a PEM encoded key pair, got %v", values)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(privateBlock.Bytes)
+	if err != nil {
+		t.Fatalf("unable to parse private key: %v", err)
+	}
+	public, err := x509.ParsePKIXPublicKey(publicBlock.Bytes)
+	if err != nil {
+		t.Fatalf("unable to parse public key: %v", err)
+	}
+	if !key.(*rsa.PrivateKey).PublicKey.Equal(public) {
+		t.Errorf("public key does not match private key")
+	}
+}
+
+func TestReadWriteSecret(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(projectDir, "secrets"), 0755); err != nil {
+		t.Fatalf("failed to create secrets dir: %v", err)
+	}
+	c := &config.Context{DockerHostType: config.ContextLocal, ProjectDir: projectDir}
+
+	if err := WriteSecret(c, "ACTIVEMQ_PASSWORD", "password"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WriteSecret(c, "../escape", "password"); err == nil {
+		t.Errorf("expected an error writing outside the secrets directory")
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "secrets", ".gitkeep"), nil, 0644); err != nil {
+		t.Fatalf("failed to write .gitkeep: %v", err)
+	}
+
+	names, err := SecretNames(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"ACTIVEMQ_PASSWORD"}) {
+		t.Errorf("unexpected secret names %v", names)
+	}
+
+	value, err := ReadSecret(c, "ACTIVEMQ_PASSWORD")
+	if err != nil || value != "password" {
+		t.Errorf("expected %q, got %q (%v)", "password", value, err)
+	}
+	if _, err := ReadSecret(c, "MISSING"); err == nil {
+		t.Errorf("expected an error reading a missing secret")
+	}
+
+	// backends take precedence, and a failing one falls back to the file
+	t.Setenv("TEST_ACTIVEMQ_PASSWORD", "from-env")
+	c.SecretBackends = []string{"env:TEST_"}
+	if value, err := ReadSecret(c, "ACTIVEMQ_PASSWORD"); err != nil || value != "from-env" {
+		t.Errorf("expected the backend's value, got %q (%v)", value, err)
+	}
+	c.SecretBackends = []string{"command:exit 1"}
+	if value, err := ReadSecret(c, "ACTIVEMQ_PASSWORD"); err != nil || value != "password" {
+		t.Errorf("expected the file's value when the backend fails, got %q (%v)", value, err)
+	}
+}
+
+func TestWriteSecretBackends(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(projectDir, "secrets"), 0755); err != nil {
+		t.Fatalf("failed to create secrets dir: %v", err)
+	}
+	vaultPath := filepath.Join(t.TempDir(), "prod.vault")
+	vault, err := config.OpenVault(vaultPath, "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error opening vault: %v", err)
+	}
+	vault.Secrets["DB_ROOT_PASSWORD"] = "old"
+	if err := vault.Save(); err != nil {
+		t.Fatalf("unexpected error saving vault: %v", err)
+	}
+	t.Setenv(config.VaultPassphraseEnv, "correct horse")
+	t.Setenv("WRITE_TEST_ACTIVEMQ_PASSWORD", "from-env")
+	c := &config.Context{
+		DockerHostType: config.ContextLocal,
+		ProjectDir:     projectDir,
+		SecretBackends: []string{"env:WRITE_TEST_", "vault:" + vaultPath},
+	}
+
+	// a secret only an env var holds can't be updated, so nothing is written
+	if err := WriteSecret(c, "ACTIVEMQ_PASSWORD", "new"); err == nil || !strings.Contains(err.Error(), "env:WRITE_TEST_") {
+		t.Errorf("expected an error naming the env backend, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "secrets", "ACTIVEMQ_PASSWORD")); !os.IsNotExist(err) {
+		t.Errorf("expected the secret file not to be written, got %v", err)
+	}
+	if err := CheckSecretsWritable(c, "DB_ROOT_PASSWORD", "ACTIVEMQ_PASSWORD"); err == nil {
+		t.Error("expected CheckSecretsWritable to refuse the env backend's secret")
+	}
+
+	// the vault is updated with the file, so the context reads the new value
+	if err := WriteSecret(c, "DB_ROOT_PASSWORD", "new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := ReadSecret(c, "DB_ROOT_PASSWORD"); err != nil || value != "new" {
+		t.Errorf("expected the new value, got %q (%v)", value, err)
+	}
+	reopened, err := config.OpenVault(vaultPath, "correct horse")
+	if err != nil || reopened.Secrets["DB_ROOT_PASSWORD"] != "new" {
+		t.Errorf("expected the vault to hold the new value, got %v (%v)", reopened.Secrets, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(projectDir, "secrets", "DB_ROOT_PASSWORD")); err != nil || string(data) != "new" {
+		t.Errorf("expected the secret file to hold the new value, got %q (%v)", data, err)
+	}
+
+	// secrets no backend has only change the file
+	if err := WriteSecret(c, "SOLR_PASSWORD", "new"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDiffSecrets(t *testing.T) {
+	source := map[string]string{
+		"ACTIVEMQ_PASSWORD": "same\n",
+		"DB_ROOT_PASSWORD":  "prod",
+		"JWT_PRIVATE_KEY":   "key",
+	}
+	target := map[string]string{
+		"ACTIVEMQ_PASSWORD": "same",
+		"DB_ROOT_PASSWORD":  "stage",
+		"SOLR_PASSWORD":     "solr",
+	}
+	diff := DiffSecrets(source, target)
+	expected := ConfigDiff{
+		Added:   []string{"JWT_PRIVATE_KEY"},
+		Changed: []string{"DB_ROOT_PASSWORD"},
+		Deleted: []string{"SOLR_PASSWORD"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected %+v, got %+v", expected, diff)
+	}
+}
+
+func TestStoredInDatabase(t *testing.T) {
+	for name, want := range map[string]bool{
+		"DB_ROOT_PASSWORD":           true,
+		"DRUPAL_DEFAULT_DB_PASSWORD": true,
+		"ACTIVEMQ_PASSWORD":          false,
+		"DB_ROOT_PASSWORD_OLD":       false,
+	} {
+		if got := StoredInDatabase(name); got != want {
+			t.Errorf("StoredInDatabase(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestServicesUsingSecret(t *testing.T) {
+	cli := &DockerClient{
+		CLI: &FakeDockerClient{
+			ListFunc: func(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error) {
+				return []dockercontainer.Summary{
+					{
+						Labels: map[string]string{"com.docker.compose.service": "mariadb"},
+						Mounts: []dockercontainer.MountPoint{{Destination: "/run/secrets/DB_ROOT_PASSWORD"}},
+					},
+					{
+						Labels: map[string]string{"com.docker.compose.service": "drupal"},
+						Mounts: []dockercontainer.MountPoint{
+							{Destination: "/run/secrets/DRUPAL_DEFAULT_SALT"},
+							{Destination: "/run/secrets/DB_ROOT_PASSWORD"},
+						},
+					},
+					{
+						Labels: map[string]string{"com.docker.compose.service": "solr"},
+					},
+				}, nil
+			},
+		},
+	}
+
+	services, err := cli.ServicesUsingSecret(context.Background(), &config.Context{ProjectName: "test"}, "DB_ROOT_PASSWORD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(services, []string{"drupal", "mariadb"}) {
+		t.Errorf("unexpected services %v", services)
+	}
+}
@@ -0,0 +1,169 @@
+package isle
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+	"github.com/spf13/pflag"
+)
+
+// DefaultSite is the Drupal site used when a context or command does not name one.
+const DefaultSite = "default"
+
+// Site is a Drupal multisite in the drupal container.
+type Site struct {
+	Name string
+	// Hosts are the sites.php keys that map to the site, in file order.
+	Hosts []string
+}
+
+// Database is the Drupal database the site uses in isle-site-template.
+func (s Site) Database() string {
+	return DatabaseName(s.Name)
+}
+
+// URI is the --uri to pass drush for the site. The context's UriMap takes precedence.
+// Otherwise it is empty for the default site, which uses DRUPAL_DRUSH_URI from the drupal container.
+func (s Site) URI(c *config.Context) string {
+	if uri, ok := c.SiteURI(s.Name); ok {
+		return uri
+	}
+	if s.Name == DefaultSite {
+		return ""
+	}
+	if len(s.Hosts) > 0 {
+		return "https://" + s.Hosts[0]
+	}
+	// drush finds sites/NAME for a bare site name
+	return s.Name
+}
+
+// sitesScript prints the site directories with a settings.php followed by sites.php
+const sitesScript = `cd /var/www/drupal/web/sites || exit 1
+for d in */; do [ -f "${d}settings.php" ] && echo "${d%/}"; done
+echo "` + sitesSeparator + `"
+cat sites.php 2>/dev/null || true`
+
+const sitesSeparator = "--- sites.php ---"
+
+var (
+	phpBlockCommentRe = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	phpLineCommentRe  = regexp.MustCompile(`(?m)(^|\s)(#|//).*$`)
+	sitesEntryRe      = regexp.MustCompile(`\$sites\[\s*['"]([^'"]+)['"]\s*\]\s*=\s*['"]([^'"]+)['"]`)
+)
+
+// ParseSitesPHP returns the hosts mapped to each site directory by a sites.php file.
+func ParseSitesPHP(src string) map[string][]string {
+	src = phpBlockCommentRe.ReplaceAllString(src, "")
+	src = phpLineCommentRe.ReplaceAllString(src, "")
+
+	hosts := map[string][]string{}
+	for _, match := range sitesEntryRe.FindAllStringSubmatch(src, -1) {
+		hosts[match[2]] = append(hosts[match[2]], match[1])
+	}
+	return hosts
+}
+
+// ParseSites builds the site list from the output of sitesScript.
+// Directories mapped in sites.php are included even without a settings.php.
+func ParseSites(output string) []Site {
+	dirOutput, sitesPHP, _ := strings.Cut(output, sitesSeparator)
+	hosts := ParseSitesPHP(sitesPHP)
+
+	names := []string{}
+	for _, line := range strings.Split(dirOutput, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	for name := range hosts {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if !slices.Contains(names, DefaultSite) {
+		names = append(names, DefaultSite)
+	}
+	// default first, then alphabetical
+	slices.SortFunc(names, func(a, b string) int {
+		if a == DefaultSite {
+			return -1
+		}
+		if b == DefaultSite {
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	sites := make([]Site, 0, len(names))
+	for _, name := range names {
+		sites = append(sites, Site{Name: name, Hosts: hosts[name]})
+	}
+	return sites
+}
+
+// ListSites discovers the Drupal multisites in the context's drupal container.
+func ListSites(c *config.Context) ([]Site, error) {
+	cli, err := GetDockerCli(c)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+	drupalContainer, err := cli.GetContainerName(c, "drupal", false)
+	if err != nil {
+		return nil, err
+	}
+	if drupalContainer == "" {
+		return nil, fmt.Errorf("no running drupal container found for context %q", c.Name)
+	}
+
+	cmd := exec.Command("docker", "exec", drupalContainer, "sh", "-c", sitesScript)
+	cmd.Dir = c.ProjectDir
+	output, err := c.CaptureCommand(cmd, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list sites: %v", err)
+	}
+
+	return ParseSites(output), nil
+}
+
+// CurrentSite returns the site named by the --site flag, or the context's site.
+func CurrentSite(f *pflag.FlagSet, c *config.Context) (Site, error) {
+	name, err := f.GetString("site")
+	if err != nil {
+		return Site{}, err
+	}
+	return FindSite(c, name)
+}
+
+// FindSite returns the named site. An empty name is the context's site.
+func FindSite(c *config.Context, name string) (Site, error) {
+	if name == "" {
+		name = c.Site
+	}
+	if name == "" {
+		name = DefaultSite
+	}
+	// sites with a URI in the context don't need to be looked up
+	if _, ok := c.SiteURI(name); ok || name == DefaultSite {
+		return Site{Name: name}, nil
+	}
+
+	sites, err := ListSites(c)
+	if err != nil {
+		return Site{}, err
+	}
+	names := []string{}
+	for _, site := range sites {
+		if site.Name == name {
+			return site, nil
+		}
+		names = append(names, site.Name)
+	}
+
+	return Site{}, fmt.Errorf("site %q not found on context %q. Valid sites are %s", name, c.Name, strings.Join(names, ", "))
+}
@@ -0,0 +1,88 @@
+package isle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+const testSitesPHP = `<?php
+
+/**
+ * Example from default.sites.php
+ * $sites['8080.www.drupal.org.mysite.test'] = 'example.com';
+ */
+# $sites['commented.example.com'] = 'commented';
+$sites['history.example.com'] = 'history';
+$sites["history.localhost"] = "history";
+$sites['art.example.com']   =  'art'; // the art collection
+`
+
+func TestParseSitesPHP(t *testing.T) {
+	expected := map[string][]string{
+		"history": {"history.example.com", "history.localhost"},
+		"art":     {"art.example.com"},
+	}
+	if got := ParseSitesPHP(testSitesPHP); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseSites(t *testing.T) {
+	output := "default\nhistory\nscience\n" + sitesSeparator + "\n" + testSitesPHP
+	expected := []Site{
+		{Name: "default"},
+		{Name: "art", Hosts: []string{"art.example.com"}},
+		{Name: "history", Hosts: []string{"history.example.com", "history.localhost"}},
+		{Name: "science"},
+	}
+	sites := ParseSites(output)
+	if !reflect.DeepEqual(sites, expected) {
+		t.Fatalf("expected %v, got %v", expected, sites)
+	}
+
+	c := &config.Context{}
+	uris := []string{}
+	for _, site := range sites {
+		uris = append(uris, site.URI(c))
+	}
+	expectedUris := []string{"", "https://art.example.com", "https://history.example.com", "science"}
+	if !reflect.DeepEqual(uris, expectedUris) {
+		t.Errorf("expected uris %v, got %v", expectedUris, uris)
+	}
+
+	// the context's UriMap wins over sites.php
+	c.UriMap = map[string]string{
+		"default": "https://islandora.dev",
+		"history": "https://history.islandora.dev",
+	}
+	uris = []string{}
+	for _, site := range sites {
+		uris = append(uris, site.URI(c))
+	}
+	expectedUris = []string{"https://islandora.dev", "https://art.example.com", "https://history.islandora.dev", "science"}
+	if !reflect.DeepEqual(uris, expectedUris) {
+		t.Errorf("expected uris %v, got %v", expectedUris, uris)
+	}
+
+	// a site without sites.php still has the default site
+	if got := ParseSites(sitesSeparator + "\n"); !reflect.DeepEqual(got, []Site{{Name: "default"}}) {
+		t.Errorf("expected only the default site, got %v", got)
+	}
+}
+
+func TestFindSiteFromUriMap(t *testing.T) {
+	c := &config.Context{
+		Site:   "history",
+		UriMap: map[string]string{"history": "https://history.islandora.dev"},
+	}
+	// the site is resolved without connecting to docker
+	site, err := FindSite(c, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if site.Name != "history" || site.URI(c) != "https://history.islandora.dev" {
+		t.Errorf("unexpected site %v with uri %q", site, site.URI(c))
+	}
+}
@@ -0,0 +1,259 @@
+package isle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/islandora-devops/islectl/internal/utils"
+)
+
+// SolrURL is the solr service, reached through a Tunnel's HTTP client.
+const SolrURL = "http://solr:8983/solr"
+
+// SolrCore is a Solr core and the size of its index.
+type SolrCore struct {
+	Name         string
+	DataDir      string
+	NumDocs      int64
+	SizeInBytes  int64
+	LastModified string
+}
+
+// SearchAPIIndex is a search_api index and its tracker counts.
+type SearchAPIIndex struct {
+	ID      string     `json:"id"`
+	Server  string     `json:"server"`
+	Core    string     `json:"core"`
+	Indexed drushCount `json:"indexed"`
+	Total   drushCount `json:"total"`
+}
+
+// SearchAPIStatusScript prints the search_api indexes with the Solr core of their server as JSON.
+// It is run with drush php:eval.
+const SearchAPIStatusScript = `$out = [];
+foreach (\Drupal::entityTypeManager()->getStorage('search_api_index')->loadMultiple() as $index) {
+  $tracker = $index->hasValidTracker() ? $index->getTrackerInstance() : NULL;
+  $server = $index->hasValidServer() ? $index->getServerInstance() : NULL;
+  $config = $server ? $server->getBackendConfig() : [];
+  $out[] = [
+    'id' => $index->id(),
+    'server' => $server ? $server->id() : '',
+    'core' => $config['connector_config']['core'] ?? '',
+    'indexed' => $tracker ? $tracker->getIndexedItemsCount() : 0,
+    'total' => $tracker ? $tracker->getTotalItemsCount() : 0,
+  ];
+}
+echo json_encode($out);`
+
+// ParseSearchAPIIndexes parses the output of SearchAPIStatusScript.
+func ParseSearchAPIIndexes(output string) ([]SearchAPIIndex, error) {
+	// the JSON is a single line, skip any warnings drush printed before it
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	indexes := []SearchAPIIndex{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &indexes); err != nil {
+		return nil, fmt.Errorf("unable to parse search_api indexes: %v", err)
+	}
+	return indexes, nil
+}
+
+// IndexHealth compares an index's tracker with the documents in its Solr core.
+// Indexes sharing a core are compared together.
+func IndexHealth(index SearchAPIIndex, indexes []SearchAPIIndex, cores []SolrCore) string {
+	if index.Indexed.Int() < index.Total.Int() {
+		return fmt.Sprintf("%d items to index", index.Total.Int()-index.Indexed.Int())
+	}
+	i := slices.IndexFunc(cores, func(core SolrCore) bool { return core.Name == index.Core })
+	if i < 0 {
+		return "core not found"
+	}
+	var indexed int64
+	for _, other := range indexes {
+		if other.Core == index.Core {
+			indexed += int64(other.Indexed.Int())
+		}
+	}
+	if cores[i].NumDocs < indexed {
+		return fmt.Sprintf("%d documents missing from solr", indexed-cores[i].NumDocs)
+	}
+	return "ok"
+}
+
+// SolrStatusHeader are the columns of SolrStatusRows.
+var SolrStatusHeader = []string{"INDEX", "CORE", "INDEXED", "TOTAL", "DOCUMENTS", "SIZE", "LAST MODIFIED", "STATUS"}
+
+// SolrStatusRows joins each search_api index with the Solr core it indexes to, so the status
+// is a single table in every output format. Cores no index uses get a row of their own.
+func SolrStatusRows(indexes []SearchAPIIndex, cores []SolrCore) [][]string {
+	coreColumns := func(name string) []string {
+		i := slices.IndexFunc(cores, func(core SolrCore) bool { return core.Name == name })
+		if i < 0 {
+			return []string{name, "", "", ""}
+		}
+		core := cores[i]
+		return []string{core.Name, fmt.Sprint(core.NumDocs), utils.FormatBytes(core.SizeInBytes), core.LastModified}
+	}
+
+	rows := [][]string{}
+	for _, index := range indexes {
+		core := coreColumns(index.Core)
+		rows = append(rows, []string{
+			index.ID, core[0], fmt.Sprint(index.Indexed.Int()), fmt.Sprint(index.Total.Int()),
+			core[1], core[2], core[3], IndexHealth(index, indexes, cores),
+		})
+	}
+	for _, core := range cores {
+		if slices.ContainsFunc(indexes, func(index SearchAPIIndex) bool { return index.Core == core.Name }) {
+			continue
+		}
+		columns := coreColumns(core.Name)
+		rows = append(rows, []string{"", columns[0], "", "", columns[1], columns[2], columns[3], "no search_api index"})
+	}
+	return rows
+}
+
+var searchAPIProgressRE = regexp.MustCompile(`Successfully indexed (\d+) items?`)
+
+// ParseIndexProgress returns the items indexed so far from a line of drush search-api:index output.
+func ParseIndexProgress(line string) (int, bool) {
+	match := searchAPIProgressRE.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(match[1])
+	return n, err == nil
+}
+
+// Solr talks to the solr service's admin APIs.
+type Solr struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewSolr returns a client for the context's solr service.
+func NewSolr(t *Tunnel) *Solr {
+	return &Solr{Client: t.HTTPClient(), BaseURL: SolrURL}
+}
+
+func (s *Solr) get(ctx context.Context, path string, params url.Values, out any) error {
+	params.Set("wt", "json")
+	// named lists as objects instead of flat arrays
+	params.Set("json.nl", "map")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach solr: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var solrErr struct {
+			Error struct {
+				Msg string `json:"msg"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &solrErr) == nil && solrErr.Error.Msg != "" {
+			return fmt.Errorf("solr returned %s: %s", resp.Status, solrErr.Error.Msg)
+		}
+		return fmt.Errorf("solr returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unable to parse solr response: %v", err)
+	}
+	return nil
+}
+
+// Cores returns the cores on the solr service sorted by name.
+func (s *Solr) Cores(ctx context.Context) ([]SolrCore, error) {
+	var out struct {
+		Status map[string]struct {
+			Name    string `json:"name"`
+			DataDir string `json:"dataDir"`
+			Index   struct {
+				NumDocs      int64  `json:"numDocs"`
+				SizeInBytes  int64  `json:"sizeInBytes"`
+				LastModified string `json:"lastModified"`
+			} `json:"index"`
+		} `json:"status"`
+	}
+	if err := s.get(ctx, "/admin/cores", url.Values{"action": {"STATUS"}}, &out); err != nil {
+		return nil, err
+	}
+
+	cores := []SolrCore{}
+	for name, status := range out.Status {
+		cores = append(cores, SolrCore{
+			Name:         name,
+			DataDir:      status.DataDir,
+			NumDocs:      status.Index.NumDocs,
+			SizeInBytes:  status.Index.SizeInBytes,
+			LastModified: status.Index.LastModified,
+		})
+	}
+	slices.SortFunc(cores, func(a, b SolrCore) int { return strings.Compare(a.Name, b.Name) })
+
+	return cores, nil
+}
+
+// Optimize merges a core's index segments, which can take a long time on big indexes.
+func (s *Solr) Optimize(ctx context.Context, core string) error {
+	params := url.Values{"optimize": {"true"}, "waitSearcher": {"true"}}
+	return s.get(ctx, "/"+url.PathEscape(core)+"/update", params, nil)
+}
+
+// Backup snapshots a core with the replication handler and waits for it to finish.
+// It returns the snapshot's directory in the solr container.
+func (s *Solr) Backup(ctx context.Context, core SolrCore, name string, poll time.Duration) (string, error) {
+	corePath := "/" + url.PathEscape(core.Name) + "/replication"
+	if err := s.get(ctx, corePath, url.Values{"command": {"backup"}, "name": {name}}, nil); err != nil {
+		return "", fmt.Errorf("unable to start backup of %s: %v", core.Name, err)
+	}
+
+	for {
+		var out struct {
+			Details struct {
+				Backup struct {
+					Status       string `json:"status"`
+					SnapshotName string `json:"snapshotName"`
+					Exception    string `json:"exception"`
+				} `json:"backup"`
+			} `json:"details"`
+		}
+		if err := s.get(ctx, corePath, url.Values{"command": {"details"}}, &out); err != nil {
+			return "", err
+		}
+		backup := out.Details.Backup
+		if backup.SnapshotName == name {
+			switch strings.ToLower(backup.Status) {
+			case "success":
+				return path.Join(core.DataDir, "snapshot."+name), nil
+			case "failed":
+				return "", fmt.Errorf("backup of %s failed: %s", core.Name, backup.Exception)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package isle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSearchAPIIndexes(t *testing.T) {
+	output := ` [warning] Some module is deprecated
+[{"id":"default_solr_index","server":"default_solr_server","core":"ISLANDORA","indexed":"120","total":125},{"id":"media","server":"default_solr_server","core":"ISLANDORA","indexed":30,"total":30}]`
+	indexes, err := ParseSearchAPIIndexes(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(indexes) != 2 || indexes[0].Indexed.Int() != 120 || indexes[0].Total.Int() != 125 || indexes[0].Core != "ISLANDORA" {
+		t.Fatalf("unexpected indexes %+v", indexes)
+	}
+
+	cores := []SolrCore{{Name: "ISLANDORA", NumDocs: 140}}
+	if got := IndexHealth(indexes[0], indexes, cores); got != "5 items to index" {
+		t.Errorf("unexpected health %q", got)
+	}
+	if got := IndexHealth(indexes[1], indexes, cores); got != "10 documents missing from solr" {
+		t.Errorf("unexpected health %q", got)
+	}
+	cores[0].NumDocs = 150
+	if got := IndexHealth(indexes[1], indexes, cores); got != "ok" {
+		t.Errorf("unexpected health %q", got)
+	}
+	if got := IndexHealth(indexes[1], indexes, nil); got != "core not found" {
+		t.Errorf("unexpected health %q", got)
+	}
+
+	if _, err := ParseSearchAPIIndexes("Command php:eval failed"); err == nil {
+		t.Error("expected an error for output without JSON")
+	}
+}
+
+func TestSolrStatusRows(t *testing.T) {
+	indexes, err := ParseSearchAPIIndexes(`[{"id":"default_solr_index","core":"ISLANDORA","indexed":120,"total":125}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cores := []SolrCore{
+		{Name: "ISLANDORA", NumDocs: 140, SizeInBytes: 2 << 20, LastModified: "2025-03-01T12:00:00Z"},
+		{Name: "spare", NumDocs: 0},
+	}
+
+	want := [][]string{
+		{"default_solr_index", "ISLANDORA", "120", "125", "140", "2.0 MiB", "2025-03-01T12:00:00Z", "5 items to index"},
+		{"", "spare", "", "", "0", "0 B", "", "no search_api index"},
+	}
+	if got := SolrStatusRows(indexes, cores); !reflect.DeepEqual(got, want) {
+		t.Errorf("SolrStatusRows() = %q, want %q", got, want)
+	}
+	for _, row := range want {
+		if len(row) != len(SolrStatusHeader) {
+			t.Errorf("row %q doesn't match the header %q", row, SolrStatusHeader)
+		}
+	}
+}
+
+func TestParseIndexProgress(t *testing.T) {
+	if n, ok := ParseIndexProgress(" [notice] Message: Successfully indexed 150 items."); !ok || n != 150 {
+		t.Errorf("expected 150, got %d %v", n, ok)
+	}
+	if n, ok := ParseIndexProgress("Successfully indexed 1 item."); !ok || n != 1 {
+		t.Errorf("expected 1, got %d %v", n, ok)
+	}
+	if _, ok := ParseIndexProgress("Found 300 items to index."); ok {
+		t.Error("expected other lines to be ignored")
+	}
+}
+
+func TestSolr(t *testing.T) {
+	detailsCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("wt") != "json" {
+			t.Errorf("expected JSON responses to be requested, got %s", r.URL)
+		}
+		switch {
+		case r.URL.Path == "/solr/admin/cores":
+			w.Write([]byte(`{"status": {"ISLANDORA": {"name": "ISLANDORA", "dataDir": "/opt/solr/server/solr/ISLANDORA/data/", "index": {"numDocs": 150, "sizeInBytes": 2048}}}}`))
+		case r.URL.Path == "/solr/ISLANDORA/update" && q.Get("optimize") == "true":
+			w.Write([]byte(`{"responseHeader": {"status": 0}}`))
+		case r.URL.Path == "/solr/ISLANDORA/replication" && q.Get("command") == "backup":
+			w.Write([]byte(`{"status": "OK"}`))
+		case r.URL.Path == "/solr/ISLANDORA/replication" && q.Get("command") == "details":
+			detailsCalls++
+			if detailsCalls == 1 {
+				w.Write([]byte(`{"details": {"backup": {"status": "In Progress", "snapshotName": "nightly"}}}`))
+				return
+			}
+			w.Write([]byte(`{"details": {"backup": {"status": "success", "snapshotName": "nightly"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"msg": "no such core"}}`))
+		}
+	}))
+	defer server.Close()
+
+	s := &Solr{Client: server.Client(), BaseURL: server.URL + "/solr"}
+	ctx := context.Background()
+
+	cores, err := s.Cores(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cores) != 1 || cores[0].Name != "ISLANDORA" || cores[0].NumDocs != 150 {
+		t.Fatalf("unexpected cores %+v", cores)
+	}
+	if err := s.Optimize(ctx, "ISLANDORA"); err != nil {
+		t.Errorf("unexpected error optimizing: %v", err)
+	}
+	if err := s.Optimize(ctx, "missing"); err == nil || err.Error() != "solr returned 404 Not Found: no such core" {
+		t.Errorf("expected solr's error message, got %v", err)
+	}
+
+	dir, err := s.Backup(ctx, cores[0], "nightly", time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir != "/opt/solr/server/solr/ISLANDORA/data/snapshot.nightly" || detailsCalls != 2 {
+		t.Errorf("unexpected backup %q after %d polls", dir, detailsCalls)
+	}
+}
@@ -0,0 +1,143 @@
+package isle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TriplestoreURL is the blazegraph service, reached through a Tunnel's HTTP client.
+const TriplestoreURL = "http://triplestore:8080/bigdata"
+
+// TriplestoreNamespace is the blazegraph namespace Islandora indexes into.
+const TriplestoreNamespace = "islandora"
+
+// TriplestoreIndexAction is the Drupal action that queues a node to be indexed in the triplestore.
+const TriplestoreIndexAction = "index_node_in_triplestore"
+
+// TriplestoreMediaIndexAction is the Drupal action that queues a media to be indexed in the triplestore.
+const TriplestoreMediaIndexAction = "index_media_in_triplestore"
+
+// TriplestoreTermIndexAction is the Drupal action that queues a taxonomy term to be indexed in the triplestore.
+const TriplestoreTermIndexAction = "index_taxonomy_term_in_triplestore"
+
+// SPARQLResult is the result of a SPARQL query. SELECT queries have Vars and Rows, ASK queries a Boolean
+// and CONSTRUCT or DESCRIBE queries the serialized Graph.
+type SPARQLResult struct {
+	Vars    []string
+	Rows    [][]string
+	Boolean *bool
+	Graph   string
+}
+
+// ParseSPARQLResults parses SPARQL JSON results. Bindings are rendered as their value,
+// with blank nodes prefixed by _: and language tags appended with @.
+func ParseSPARQLResults(data []byte) (SPARQLResult, error) {
+	var out struct {
+		Head struct {
+			Vars []string `json:"vars"`
+		} `json:"head"`
+		Boolean *bool `json:"boolean"`
+		Results struct {
+			Bindings []map[string]struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+				Lang  string `json:"xml:lang"`
+			} `json:"bindings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return SPARQLResult{}, fmt.Errorf("unable to parse SPARQL results: %v", err)
+	}
+
+	result := SPARQLResult{Vars: out.Head.Vars, Boolean: out.Boolean, Rows: [][]string{}}
+	for _, binding := range out.Results.Bindings {
+		row := make([]string, len(out.Head.Vars))
+		for i, name := range out.Head.Vars {
+			term, ok := binding[name]
+			if !ok {
+				continue
+			}
+			switch {
+			case term.Type == "bnode":
+				row[i] = "_:" + term.Value
+			case term.Lang != "":
+				row[i] = term.Value + "@" + term.Lang
+			default:
+				row[i] = term.Value
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// CountQuery counts the triples in the store, or the subjects of each rdf:type when byType is set.
+func CountQuery(byType bool) string {
+	if byType {
+		return `SELECT ?type (COUNT(DISTINCT ?s) AS ?count) WHERE { ?s a ?type } GROUP BY ?type ORDER BY DESC(?count)`
+	}
+	return `SELECT (COUNT(*) AS ?count) WHERE { ?s ?p ?o }`
+}
+
+// Triplestore talks to a blazegraph namespace's SPARQL endpoint.
+type Triplestore struct {
+	Client   *http.Client
+	Endpoint string
+}
+
+// NewTriplestore returns a client for a namespace of the context's triplestore service.
+func NewTriplestore(t *Tunnel, namespace string) *Triplestore {
+	return &Triplestore{
+		Client:   t.HTTPClient(),
+		Endpoint: TriplestoreURL + "/namespace/" + url.PathEscape(namespace) + "/sparql",
+	}
+}
+
+func (s *Triplestore) post(ctx context.Context, form url.Values, accept string) (string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", accept)
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to reach the triplestore: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// blazegraph puts the query and its stack trace in the body, the first line is the useful part
+		message, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+		return "", nil, fmt.Errorf("triplestore returned %s: %s", resp.Status, message)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType, data, nil
+}
+
+// Query runs a SPARQL query. Graph results are returned as N-Triples.
+func (s *Triplestore) Query(ctx context.Context, query string) (SPARQLResult, error) {
+	mediaType, data, err := s.post(ctx, url.Values{"query": {query}}, "application/sparql-results+json, application/n-triples;q=0.9")
+	if err != nil {
+		return SPARQLResult{}, err
+	}
+	if mediaType != "application/sparql-results+json" && mediaType != "application/json" {
+		return SPARQLResult{Graph: string(data)}, nil
+	}
+	return ParseSPARQLResults(data)
+}
+
+// Clear deletes every triple in the namespace.
+func (s *Triplestore) Clear(ctx context.Context) error {
+	_, _, err := s.post(ctx, url.Values{"update": {"CLEAR ALL"}}, "*/*")
+	return err
+}
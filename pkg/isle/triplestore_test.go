@@ -0,0 +1,89 @@
+package isle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSPARQLResults(t *testing.T) {
+	result, err := ParseSPARQLResults([]byte(`{
+  "head": {"vars": ["s", "title", "extra"]},
+  "results": {"bindings": [
+    {"s": {"type": "uri", "value": "http://example.com/node/1"}, "title": {"type": "literal", "value": "Pomme", "xml:lang": "fr"}},
+    {"s": {"type": "bnode", "value": "b0"}, "title": {"type": "literal", "value": "Untitled"}, "extra": {"type": "literal", "value": "1"}}
+  ]}
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][]string{
+		{"http://example.com/node/1", "Pomme@fr", ""},
+		{"_:b0", "Untitled", "1"},
+	}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("expected %d rows, got %v", len(want), result.Rows)
+	}
+	for i := range want {
+		if strings.Join(result.Rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %v, want %v", i, result.Rows[i], want[i])
+		}
+	}
+
+	result, err = ParseSPARQLResults([]byte(`{"head": {}, "boolean": true}`))
+	if err != nil || result.Boolean == nil || !*result.Boolean {
+		t.Errorf("expected an ASK result, got %+v %v", result, err)
+	}
+}
+
+func TestTriplestore(t *testing.T) {
+	updates := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bigdata/namespace/islandora/sparql" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Namespace not found\njava.lang.RuntimeException..."))
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if update := r.PostForm.Get("update"); update != "" {
+			updates = append(updates, update)
+			w.Write([]byte(`<data modified="10" milliseconds="3"/>`))
+			return
+		}
+		query := r.PostForm.Get("query")
+		switch {
+		case strings.HasPrefix(query, "CONSTRUCT"):
+			w.Header().Set("Content-Type", "application/n-triples")
+			w.Write([]byte("<http://example.com/a> <http://example.com/b> \"c\" .\n"))
+		case query == CountQuery(false):
+			w.Header().Set("Content-Type", "application/sparql-results+json; charset=UTF-8")
+			w.Write([]byte(`{"head": {"vars": ["count"]}, "results": {"bindings": [{"count": {"type": "literal", "value": "1234"}}]}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("MALFORMED QUERY: Encountered \" <EOF> \"\nat line 1"))
+		}
+	}))
+	defer server.Close()
+
+	s := &Triplestore{Client: server.Client(), Endpoint: server.URL + "/bigdata/namespace/islandora/sparql"}
+	ctx := context.Background()
+
+	if result, err := s.Query(ctx, CountQuery(false)); err != nil || !reflect.DeepEqual(result.Rows, [][]string{{"1234"}}) {
+		t.Errorf("expected a count of 1234, got %+v %v", result, err)
+	}
+	result, err := s.Query(ctx, "CONSTRUCT { ?s ?p ?o } WHERE { ?s ?p ?o }")
+	if err != nil || result.Vars != nil || !strings.Contains(result.Graph, "<http://example.com/a>") {
+		t.Errorf("expected N-Triples, got %+v %v", result, err)
+	}
+	if _, err := s.Query(ctx, "SELECT"); err == nil || err.Error() != `triplestore returned 400 Bad Request: MALFORMED QUERY: Encountered " <EOF> "` {
+		t.Errorf("expected the first line of blazegraph's error, got %v", err)
+	}
+	if err := s.Clear(ctx); err != nil || len(updates) != 1 || updates[0] != "CLEAR ALL" {
+		t.Errorf("Clear() = %v with updates %v", err, updates)
+	}
+}
@@ -0,0 +1,406 @@
+package isle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+const (
+	keepAliveRequest  = "keepalive@openssh.com"
+	keepAliveTimeout  = 10 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Tunnel dials docker compose services for a context.
+// For remote contexts connections are made through the context's SSH client.
+// When a dial fails the tunnel reconnects SSH (with backoff) if the link dropped
+// and re-resolves the service IP in case the container was recreated.
+type Tunnel struct {
+	Context *config.Context
+
+	mu  sync.Mutex
+	cli *DockerClient
+	ips map[string]string
+	// reconnectMu makes concurrent reconnects wait for the first one instead of replacing its client
+	reconnectMu sync.Mutex
+
+	// overridable for testing
+	connect        func(c *config.Context) (*DockerClient, error)
+	dial           func(d *DockerClient, network, addr string) (net.Conn, error)
+	reconnectDelay time.Duration
+	maxReconnects  int
+}
+
+// NewTunnel connects to the context's docker host.
+func NewTunnel(c *config.Context) (*Tunnel, error) {
+	t := &Tunnel{
+		Context:        c,
+		ips:            map[string]string{},
+		connect:        GetDockerCli,
+		dial:           (*DockerClient).Dial,
+		reconnectDelay: time.Second,
+		maxReconnects:  10,
+	}
+	if c.DockerHostType == config.ContextLocal && runtime.GOOS != "linux" {
+		// Docker Desktop runs containers in a VM so their IPs are not routable from the host
+		t.dial = func(d *DockerClient, network, addr string) (net.Conn, error) {
+			return d.DialRelay(context.Background(), c, addr)
+		}
+	}
+	cli, err := t.connect(c)
+	if err != nil {
+		return nil, err
+	}
+	t.cli = cli
+
+	return t, nil
+}
+
+// Client returns the docker client currently used by the tunnel.
+func (t *Tunnel) Client() *DockerClient {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cli
+}
+
+// Close closes the underlying docker and SSH clients.
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cli == nil {
+		return nil
+	}
+	return t.cli.Close()
+}
+
+// Dial connects to addr on the docker host's network.
+// Remote contexts dial through SSH, local contexts dial directly.
+func (d *DockerClient) Dial(network, addr string) (net.Conn, error) {
+	if d.SshCli != nil {
+		return d.SshCli.Dial(network, addr)
+	}
+	return net.DialTimeout(network, addr, 5*time.Second)
+}
+
+// ServiceIP returns the IP address for a compose service on the project network.
+// Lookups are cached until refresh is true.
+func (t *Tunnel) ServiceIP(ctx context.Context, service string, refresh bool) (string, error) {
+	t.mu.Lock()
+	ip, ok := t.ips[service]
+	cli := t.cli
+	t.mu.Unlock()
+	if ok && !refresh {
+		return ip, nil
+	}
+
+	// the docker calls can be slow over SSH, don't block other dials on them
+	containerName, err := containerName(cli, t.Context, service)
+	if err != nil {
+		return "", err
+	}
+	ip, err = cli.GetServiceIp(ctx, t.Context, containerName)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if previous, ok := t.ips[service]; ok && previous != ip {
+		slog.Info("Service IP changed", "service", service, "old", previous, "new", ip)
+	}
+	t.ips[service] = ip
+
+	return ip, nil
+}
+
+// ContainerName returns the name of the running container of a compose service.
+func (t *Tunnel) ContainerName(service string) (string, error) {
+	return containerName(t.Client(), t.Context, service)
+}
+
+func containerName(cli *DockerClient, c *config.Context, service string) (string, error) {
+	containerName, err := cli.GetContainerName(c, service, false)
+	if err != nil {
+		return "", err
+	}
+	if containerName == "" && c.Profile != "" {
+		// not every service has a profile specific variant e.g. ide
+		containerName, err = cli.GetContainerName(c, service, true)
+		if err != nil {
+			return "", err
+		}
+	}
+	if containerName == "" {
+		return "", fmt.Errorf("no running container found for service %q", service)
+	}
+	return containerName, nil
+}
+
+// HTTPClient returns a client sending requests for http://SERVICE:PORT to the compose service through the tunnel.
+// It has no timeout, callers bound requests with their context.
+func (t *Tunnel) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				service, portStr, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				port, err := strconv.Atoi(portStr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid port in %q: %v", addr, err)
+				}
+				return t.DialService(ctx, service, port)
+			},
+			MaxIdleConnsPerHost: 4,
+		},
+	}
+}
+
+// DialService connects to a port on a compose service.
+func (t *Tunnel) DialService(ctx context.Context, service string, port int) (net.Conn, error) {
+	ip, err := t.ServiceIP(ctx, service, false)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := t.dialAddr(fmt.Sprintf("%s:%d", ip, port))
+	if err == nil {
+		return conn, nil
+	}
+	slog.Warn("Unable to reach service, retrying", "service", service, "port", port, "err", err)
+
+	if err := t.ensureAlive(ctx); err != nil {
+		return nil, err
+	}
+
+	// the container may have been recreated with a new IP
+	ip, err = t.ServiceIP(ctx, service, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.dialAddr(fmt.Sprintf("%s:%d", ip, port))
+}
+
+func (t *Tunnel) dialAddr(addr string) (net.Conn, error) {
+	return t.dial(t.Client(), "tcp", addr)
+}
+
+// alive reports whether the client's SSH link is still usable.
+// Local contexts have no SSH link so they are always considered alive.
+func alive(cli *DockerClient) bool {
+	if cli == nil {
+		return false
+	}
+	if cli.SshCli == nil {
+		return true
+	}
+	// a silently dropped link never answers, so don't wait for it forever
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := cli.SshCli.SendRequest(keepAliveRequest, true, nil)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err == nil
+	case <-time.After(keepAliveTimeout):
+		return false
+	}
+}
+
+// ensureAlive reconnects when the SSH link has dropped.
+func (t *Tunnel) ensureAlive(ctx context.Context) error {
+	cli := t.Client()
+	if alive(cli) {
+		return nil
+	}
+	return t.reconnect(ctx, cli)
+}
+
+// Reconnect replaces the tunnel's docker client, retrying with exponential backoff.
+func (t *Tunnel) Reconnect(ctx context.Context) error {
+	return t.reconnect(ctx, t.Client())
+}
+
+// reconnect replaces the failed client. Callers that saw the same client fail wait for the first
+// reconnect and then use its client, rather than closing it and starting over.
+// The tunnel isn't locked while connecting, so Client and ServiceIP don't block during the backoff.
+func (t *Tunnel) reconnect(ctx context.Context, failed *DockerClient) error {
+	t.reconnectMu.Lock()
+	defer t.reconnectMu.Unlock()
+
+	t.mu.Lock()
+	replaced := t.cli != failed
+	t.mu.Unlock()
+	if replaced {
+		return nil
+	}
+	if failed != nil {
+		failed.Close()
+	}
+
+	delay := t.reconnectDelay
+	var err error
+	for attempt := 1; attempt <= t.maxReconnects; attempt++ {
+		var cli *DockerClient
+		cli, err = t.connect(t.Context)
+		if err == nil {
+			slog.Info("Reconnected", "context", t.Context.Name, "attempt", attempt)
+			t.mu.Lock()
+			t.cli = cli
+			t.ips = map[string]string{}
+			t.mu.Unlock()
+			return nil
+		}
+
+		slog.Warn("Reconnect failed", "context", t.Context.Name, "attempt", attempt, "retry", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+
+	return fmt.Errorf("unable to reconnect after %d attempts: %w", t.maxReconnects, err)
+}
+
+// KeepAlive periodically checks the SSH link and reconnects when it drops.
+// It blocks until ctx is cancelled.
+func (t *Tunnel) KeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cli := t.Client()
+			if alive(cli) {
+				continue
+			}
+			slog.Warn("SSH connection lost, reconnecting", "context", t.Context.Name)
+			if err := t.reconnect(ctx, cli); err != nil {
+				slog.Error("Unable to reconnect", "context", t.Context.Name, "err", err)
+			}
+		}
+	}
+}
+
+// ForwardStats counts the traffic going through a Forward.
+type ForwardStats struct {
+	Connections atomic.Int64
+	Active      atomic.Int64
+	Failures    atomic.Int64
+	BytesIn     atomic.Int64
+	BytesOut    atomic.Int64
+}
+
+// Forward accepts connections on a local listener and proxies them to a service port.
+type Forward struct {
+	Service    string
+	RemotePort int
+	LocalPort  int
+	Stats      ForwardStats
+
+	listener net.Listener
+}
+
+// NewForward creates a forward from listener to the service port.
+func NewForward(listener net.Listener, service string, remotePort int) *Forward {
+	return &Forward{
+		Service:    service,
+		RemotePort: remotePort,
+		LocalPort:  listener.Addr().(*net.TCPAddr).Port,
+		listener:   listener,
+	}
+}
+
+// Serve accepts connections until the listener is closed.
+func (f *Forward) Serve(ctx context.Context, t *Tunnel) {
+	for {
+		localConn, err := f.listener.Accept()
+		if err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				slog.Error("Error accepting connection", "port", f.LocalPort, "err", err)
+			}
+			return
+		}
+		go f.handle(ctx, t, localConn)
+	}
+}
+
+// Close stops accepting new connections.
+func (f *Forward) Close() error {
+	return f.listener.Close()
+}
+
+func (f *Forward) handle(ctx context.Context, t *Tunnel, localConn net.Conn) {
+	defer localConn.Close()
+	f.Stats.Connections.Add(1)
+
+	remoteConn, err := t.DialService(ctx, f.Service, f.RemotePort)
+	if err != nil {
+		f.Stats.Failures.Add(1)
+		slog.Error("Failed to reach service", "service", f.Service, "port", f.RemotePort, "err", err)
+		return
+	}
+	defer remoteConn.Close()
+
+	f.Stats.Active.Add(1)
+	defer f.Stats.Active.Add(-1)
+	Pipe(localConn, remoteConn, &f.Stats.BytesOut, &f.Stats.BytesIn)
+}
+
+// Pipe copies data in both directions between local and remote until either side closes.
+// Byte counts are added to sent and received when they are not nil.
+func Pipe(local, remote net.Conn, sent, received *atomic.Int64) {
+	done := make(chan struct{}, 2)
+	go func() {
+		n, err := io.Copy(remote, local)
+		if err != nil {
+			slog.Debug("error while copying local to remote", "err", err)
+		}
+		if sent != nil {
+			sent.Add(n)
+		}
+		closeWrite(remote)
+		done <- struct{}{}
+	}()
+	go func() {
+		n, err := io.Copy(local, remote)
+		if err != nil {
+			slog.Debug("error while copying remote to local", "err", err)
+		}
+		if received != nil {
+			received.Add(n)
+		}
+		closeWrite(local)
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
+
+// closeWrite half-closes a connection so the peer sees EOF
+// while data still flowing the other way can be read.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+		return
+	}
+	conn.Close()
+}
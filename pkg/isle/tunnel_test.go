@@ -0,0 +1,276 @@
+package isle
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/islandora-devops/islectl/pkg/config"
+)
+
+// fakeServiceClient returns a docker client where every service resolves to the IP returned by ip().
+func fakeServiceClient(ip func() string) *DockerClient {
+	return &DockerClient{
+		CLI: &FakeDockerClient{
+			ListFunc: func(ctx context.Context, options dockercontainer.ListOptions) ([]dockercontainer.Summary, error) {
+				return []dockercontainer.Summary{{Names: []string{"/test-solr-1"}}}, nil
+			},
+			InspectFunc: func(ctx context.Context, container string) (dockercontainer.InspectResponse, error) {
+				return dockercontainer.InspectResponse{
+					NetworkSettings: &dockercontainer.NetworkSettings{
+						Networks: map[string]*network.EndpointSettings{
+							"test_default": {IPAddress: ip()},
+						},
+					},
+				}, nil
+			},
+		},
+	}
+}
+
+func newTestTunnel(cli *DockerClient, dial func(d *DockerClient, network, addr string) (net.Conn, error)) *Tunnel {
+	return &Tunnel{
+		Context:        &config.Context{Name: "test", ProjectName: "test", DockerHostType: config.ContextLocal},
+		cli:            cli,
+		ips:            map[string]string{},
+		connect:        func(c *config.Context) (*DockerClient, error) { return cli, nil },
+		dial:           dial,
+		reconnectDelay: time.Millisecond,
+		maxReconnects:  3,
+	}
+}
+
+func TestTunnelDialServiceReResolvesIP(t *testing.T) {
+	currentIp := "10.0.0.1"
+	cli := fakeServiceClient(func() string { return currentIp })
+
+	dialed := []string{}
+	tunnel := newTestTunnel(cli, func(d *DockerClient, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr != "10.0.0.2:8983" {
+			return nil, fmt.Errorf("connection refused")
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+
+	ip, err := tunnel.ServiceIP(context.Background(), "solr", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ip != "10.0.0.1" {
+		t.Fatalf("expected %q, got %q", "10.0.0.1", ip)
+	}
+
+	// simulate the container being recreated with a new IP
+	currentIp = "10.0.0.2"
+	conn, err := tunnel.DialService(context.Background(), "solr", 8983)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+
+	expected := []string{"10.0.0.1:8983", "10.0.0.2:8983"}
+	if fmt.Sprint(dialed) != fmt.Sprint(expected) {
+		t.Errorf("expected dials %v, got %v", expected, dialed)
+	}
+}
+
+func TestTunnelServiceIPDoesNotBlock(t *testing.T) {
+	inspecting := make(chan struct{})
+	release := make(chan struct{})
+	cli := fakeServiceClient(func() string {
+		close(inspecting)
+		<-release
+		return "10.0.0.1"
+	})
+	tunnel := newTestTunnel(cli, nil)
+	tunnel.ips["fcrepo"] = "10.0.0.2"
+
+	done := make(chan error)
+	go func() {
+		_, err := tunnel.ServiceIP(context.Background(), "solr", false)
+		done <- err
+	}()
+	<-inspecting
+
+	// a slow docker lookup doesn't hold up cached ones
+	cached := make(chan string)
+	go func() {
+		ip, _ := tunnel.ServiceIP(context.Background(), "fcrepo", false)
+		cached <- ip
+	}()
+	select {
+	case ip := <-cached:
+		if ip != "10.0.0.2" {
+			t.Errorf("expected the cached IP, got %q", ip)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached lookup waited for the docker lookup")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ip, _ := tunnel.ServiceIP(context.Background(), "solr", false); ip != "10.0.0.1" {
+		t.Errorf("expected the looked up IP to be cached, got %q", ip)
+	}
+}
+
+func TestTunnelReconnectRetries(t *testing.T) {
+	cli := fakeServiceClient(func() string { return "10.0.0.1" })
+	tunnel := newTestTunnel(cli, nil)
+
+	attempts := 0
+	tunnel.connect = func(c *config.Context) (*DockerClient, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return cli, nil
+	}
+	if err := tunnel.Reconnect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = -10
+	if err := tunnel.Reconnect(context.Background()); err == nil {
+		t.Fatal("expected an error after exhausting reconnect attempts")
+	}
+}
+
+func TestTunnelReconnectSingleFlight(t *testing.T) {
+	failed := fakeServiceClient(func() string { return "10.0.0.1" })
+	replacement := fakeServiceClient(func() string { return "10.0.0.2" })
+	tunnel := newTestTunnel(failed, nil)
+	tunnel.reconnectDelay = 50 * time.Millisecond
+
+	var attempts atomic.Int32
+	tunnel.connect = func(c *config.Context) (*DockerClient, error) {
+		if attempts.Add(1) == 1 {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return replacement, nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- tunnel.reconnect(context.Background(), failed)
+		}()
+	}
+
+	// the backoff must not hold the lock every other caller needs
+	time.Sleep(10 * time.Millisecond)
+	got := make(chan *DockerClient)
+	go func() { got <- tunnel.Client() }()
+	select {
+	case <-got:
+	case <-time.After(25 * time.Millisecond):
+		t.Fatal("Client blocked while reconnecting")
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("expected one reconnect of 2 attempts, got %d attempts", attempts.Load())
+	}
+	if tunnel.Client() != replacement {
+		t.Error("expected the replacement client")
+	}
+}
+
+func TestForwardStats(t *testing.T) {
+	echo := startEcho(t)
+
+	cli := fakeServiceClient(func() string { return "10.0.0.1" })
+	tunnel := newTestTunnel(cli, func(d *DockerClient, network, addr string) (net.Conn, error) {
+		return net.Dial("tcp", echo.Addr().String())
+	})
+
+	listener, _, err := ListenLocal(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fwd := NewForward(listener, "solr", 8983)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go fwd.Serve(ctx, tunnel)
+	defer fwd.Close()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", fwd.LocalPort))
+	if err != nil {
+		t.Fatalf("unable to connect to forward: %v", err)
+	}
+	fmt.Fprint(conn, "hello\n")
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("unable to read reply: %v", err)
+	}
+	conn.Close()
+	if reply != "hello\n" {
+		t.Errorf("expected %q, got %q", "hello\n", reply)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for fwd.Stats.Active.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := fwd.Stats.Connections.Load(); got != 1 {
+		t.Errorf("expected 1 connection, got %d", got)
+	}
+	if got := fwd.Stats.BytesOut.Load(); got != 6 {
+		t.Errorf("expected 6 bytes sent, got %d", got)
+	}
+	if got := fwd.Stats.BytesIn.Load(); got != 6 {
+		t.Errorf("expected 6 bytes received, got %d", got)
+	}
+}
+
+func TestTunnelHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello from %s", r.Host)
+	}))
+	defer server.Close()
+
+	cli := fakeServiceClient(func() string { return "10.0.0.1" })
+	tunnel := newTestTunnel(cli, func(d *DockerClient, network, addr string) (net.Conn, error) {
+		if addr != "10.0.0.1:8983" {
+			return nil, fmt.Errorf("unexpected address %q", addr)
+		}
+		return net.Dial("tcp", server.Listener.Addr().String())
+	})
+
+	resp, err := tunnel.HTTPClient().Get("http://solr:8983/solr/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hello from solr:8983" {
+		t.Errorf("unexpected response %q", body)
+	}
+}
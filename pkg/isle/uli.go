@@ -0,0 +1,76 @@
+package isle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+var (
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b\][^\x07]*\x07`)
+	loginURLRe   = regexp.MustCompile(`https?://[^\s"'<>]+/user/reset/[^\s"'<>]+`)
+	anyURLRe     = regexp.MustCompile(`https?://[^\s"'<>]+`)
+)
+
+// UliArgs returns the drush user:login arguments. At most one of uid, name and mail is used,
+// with uid 0 meaning it was not set.
+func UliArgs(uri string, uid uint, name, mail string) ([]string, error) {
+	args := []string{"drush", "user:login", "--no-browser"}
+	if uri != "" {
+		args = append(args, "--uri="+uri)
+	}
+
+	set := 0
+	if uid != 0 {
+		set++
+		args = append(args, fmt.Sprintf("--uid=%d", uid))
+	}
+	if name != "" {
+		set++
+		args = append(args, "--name="+name)
+	}
+	if mail != "" {
+		set++
+		args = append(args, "--mail="+mail)
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of --uid, --name or --mail can be passed")
+	}
+	if set == 0 {
+		args = append(args, "--uid=1")
+	}
+
+	return args, nil
+}
+
+// UliCommand returns the drush user:login command to run with bash in the drupal container.
+// Without a uri, drush uses the container's DRUPAL_DRUSH_URI.
+func UliCommand(uri string, uid uint, name, mail string) (string, error) {
+	args, err := UliArgs(uri, uid, name, mail)
+	if err != nil {
+		return "", err
+	}
+	command := shellquote.Join(args...)
+	if uri == "" {
+		command += ` --uri="$DRUPAL_DRUSH_URI"`
+	}
+	return command, nil
+}
+
+// ExtractLoginURL finds the one-time login link in drush user:login output.
+// Terminal escape sequences and carriage returns added by PTYs are ignored.
+func ExtractLoginURL(output string) (string, error) {
+	output = ansiEscapeRe.ReplaceAllString(output, "")
+	output = strings.ReplaceAll(output, "\r", "")
+
+	if urls := loginURLRe.FindAllString(output, -1); len(urls) > 0 {
+		return urls[len(urls)-1], nil
+	}
+	if urls := anyURLRe.FindAllString(output, -1); len(urls) > 0 {
+		return urls[len(urls)-1], nil
+	}
+
+	return "", fmt.Errorf("no login URL found in drush output: %q", strings.TrimSpace(output))
+}
@@ -0,0 +1,90 @@
+package isle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractLoginURL(t *testing.T) {
+	const link = "https://islandora.dev/user/reset/1/1700000000/Yx3-abc_DEF/login"
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", output: link + "\n", want: link},
+		{name: "pty", output: "\x1b[?2004l\r" + link + "\r\n", want: link},
+		{name: "colors", output: "\x1b[32m" + link + "\x1b[0m", want: link},
+		{
+			name:   "warnings before the link",
+			output: " [warning] Could not connect to https://solr:8983/solr\n" + link + "\n",
+			want:   link,
+		},
+		{name: "no reset path", output: "http://localhost:8080/login\n", want: "http://localhost:8080/login"},
+		{name: "no url", output: "[error] Unable to load user by name admin2\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractLoginURL(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractLoginURL error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUliArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		uid     uint
+		user    string
+		mail    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "default admin", want: []string{"drush", "user:login", "--no-browser", "--uri=https://islandora.dev", "--uid=1"}},
+		{name: "uid", uid: 2, want: []string{"drush", "user:login", "--no-browser", "--uri=https://islandora.dev", "--uid=2"}},
+		{name: "name", user: "editor", want: []string{"drush", "user:login", "--no-browser", "--uri=https://islandora.dev", "--name=editor"}},
+		{name: "mail", mail: "a@example.com", want: []string{"drush", "user:login", "--no-browser", "--uri=https://islandora.dev", "--mail=a@example.com"}},
+		{name: "conflict", uid: 2, user: "editor", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UliArgs("https://islandora.dev", tt.uid, tt.user, tt.mail)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UliArgs error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUliCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		user string
+		want string
+	}{
+		{name: "site uri", uri: "https://islandora.dev", want: "drush user:login --no-browser --uri=https://islandora.dev --uid=1"},
+		{name: "container uri", want: `drush user:login --no-browser --uid=1 --uri="$DRUPAL_DRUSH_URI"`},
+		{name: "quoted name", user: "Jane Doe", want: `drush user:login --no-browser '--name=Jane Doe' --uri="$DRUPAL_DRUSH_URI"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UliCommand(tt.uri, 0, tt.user, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}